@@ -0,0 +1,405 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/enaml-ops/enaml"
+	"github.com/enaml-ops/omg-transform/cloudconfig"
+)
+
+// The kinds of flag values that can be completed from a manifest
+// or cloud config.
+const (
+	ValueInstanceGroup = "instance-group"
+	ValueNetwork       = "network"
+	ValueAZ            = "az"
+	ValueVMType        = "vm-type"
+	ValueVMExtension   = "vm-extension"
+)
+
+// The environment variables consulted for the manifest and cloud
+// config used to complete flag values.
+const (
+	ManifestEnv    = "OMG_TRANSFORM_MANIFEST"
+	CloudConfigEnv = "OMG_TRANSFORM_CLOUD_CONFIG"
+)
+
+// CompleteCommand is the hidden command the completion scripts use
+// to ask the program for candidates.
+const CompleteCommand = "__complete"
+
+// Completion implements the 'completion' command, which writes a
+// completion script for the named shell.
+func (p *Program) Completion(args []string, stdout, stderr io.Writer) int {
+	if len(args) != 1 {
+		fmt.Fprintf(stderr, "Usage: %s completion bash|zsh|fish\n", p.Name)
+		return 1
+	}
+
+	var script string
+	switch args[0] {
+	case "bash":
+		script = bashCompletion
+	case "zsh":
+		script = zshCompletion
+	case "fish":
+		script = fishCompletion
+	default:
+		fmt.Fprintf(stderr, "ERROR: unsupported shell %q, must be bash, zsh or fish\n", args[0])
+		return 1
+	}
+	r := strings.NewReplacer("{{prog}}", p.Name, "{{func}}", strings.Replace(p.Name, "-", "_", -1))
+	io.WriteString(stdout, r.Replace(script))
+	return 0
+}
+
+// Complete returns the candidates for the last of words, which are the
+// words on the command line after the program name.  The last word is
+// the (possibly empty) word being completed.  Global options and
+// redirections before the transform are skipped.
+//
+// Flag values are completed from the manifest redirected to standard
+// input with '<', or named by $OMG_TRANSFORM_MANIFEST, and from the
+// cloud config named by -cloud-config or $OMG_TRANSFORM_CLOUD_CONFIG.
+// When neither is available no values are suggested and the shell
+// falls back to completing files.
+func (p *Program) Complete(words []string) []string {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	all := words
+	cur := words[len(words)-1]
+
+	for len(words) > 1 {
+		n := p.globalWords(words[0])
+		if n == 0 {
+			break
+		}
+		if n >= len(words) {
+			// completing the value of a global option or the file
+			// redirected to standard input
+			return nil
+		}
+		words = words[n:]
+	}
+
+	if len(words) == 1 {
+		if strings.HasPrefix(cur, "-") {
+			return withPrefix(p.globalOptions(), cur)
+		}
+		var names []string
+		for _, t := range p.Transformations {
+			names = append(names, t.Name)
+		}
+		for _, c := range p.Commands {
+			names = append(names, c.Name)
+		}
+		return withPrefix(names, cur)
+	}
+
+	switch words[0] {
+	case "help":
+		var names []string
+		for _, t := range p.Transformations {
+			names = append(names, t.Name)
+		}
+		return withPrefix(names, cur)
+	case "completion":
+		if len(words) == 2 {
+			return withPrefix([]string{"bash", "zsh", "fish"}, cur)
+		}
+		return nil
+	}
+
+	t, ok := p.Lookup(words[0])
+	if !ok || t.Flags == nil {
+		return nil
+	}
+
+	prev := words[len(words)-2]
+	if name, ok := flagName(prev); ok {
+		if f := t.Flags.Lookup(name); f != nil && !isBoolFlag(f.Value) {
+			values := flagValues(t.Complete[name], all)
+			return completeList(values, cur)
+		}
+	}
+
+	if cur != "" && !strings.HasPrefix(cur, "-") {
+		return nil
+	}
+	used := make(map[string]bool)
+	for _, w := range words[1 : len(words)-1] {
+		if name, ok := flagName(w); ok {
+			used[name] = true
+		}
+	}
+	var flags []string
+	t.Flags.VisitAll(func(f *flag.Flag) {
+		if !used[f.Name] {
+			flags = append(flags, "-"+f.Name)
+		}
+	})
+	return withPrefix(flags, cur)
+}
+
+// globalOptions returns the names of the program's global options.
+func (p *Program) globalOptions() []string {
+	names := []string{"-json-errors"}
+	for _, o := range p.Options {
+		names = append(names, strings.Fields(o)[0])
+	}
+	return names
+}
+
+// globalWords returns the number of words taken by the global option
+// or redirection that starts with word, or 0 if word starts the
+// transform or command.
+func (p *Program) globalWords(word string) int {
+	if word == "<" {
+		return 2
+	}
+	if strings.HasPrefix(word, "<") || word == "-json-errors" {
+		return 1
+	}
+	for _, o := range p.Options {
+		if fields := strings.Fields(o); fields[0] == word {
+			return len(fields)
+		}
+	}
+	return 0
+}
+
+// WriteCompletions implements the hidden command used by the
+// completion scripts, writing one candidate per line.
+func (p *Program) WriteCompletions(w io.Writer, words []string) {
+	for _, c := range p.Complete(words) {
+		fmt.Fprintln(w, c)
+	}
+}
+
+// flagValues returns the possible values of the specified kind.
+func flagValues(kind string, words []string) []string {
+	var names []string
+	switch kind {
+	case ValueInstanceGroup:
+		dm := readManifest(manifestPath(words))
+		if dm == nil {
+			return nil
+		}
+		for _, ig := range dm.InstanceGroups {
+			names = append(names, ig.Name)
+		}
+	case ValueNetwork:
+		cc := readCloudConfig(cloudConfigPath(words))
+		if cc == nil {
+			return nil
+		}
+		networks, err := cloudconfig.Networks(cc)
+		if err != nil {
+			return nil
+		}
+		for _, n := range networks {
+			names = append(names, n.Name)
+		}
+	case ValueAZ:
+		cc := readCloudConfig(cloudConfigPath(words))
+		if cc == nil {
+			return nil
+		}
+		for _, az := range cc.AZs {
+			names = append(names, az.Name)
+		}
+	case ValueVMType:
+		cc := readCloudConfig(cloudConfigPath(words))
+		if cc == nil {
+			return nil
+		}
+		for _, vt := range cc.VMTypes {
+			names = append(names, vt.Name)
+		}
+	case ValueVMExtension:
+		cc := readCloudConfig(cloudConfigPath(words))
+		if cc == nil {
+			return nil
+		}
+		for _, ve := range cc.VMExtensions {
+			names = append(names, ve.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// manifestPath returns the file redirected to standard input,
+// or the value of $OMG_TRANSFORM_MANIFEST.
+func manifestPath(words []string) string {
+	if path := redirectedInput(words); path != "" {
+		return path
+	}
+	return os.Getenv(ManifestEnv)
+}
+
+// cloudConfigPath returns the value of the -cloud-config flag or of
+// $OMG_TRANSFORM_CLOUD_CONFIG.  Otherwise the file redirected to
+// standard input is used, which is the cloud config when completing
+// cloud config transformations.
+func cloudConfigPath(words []string) string {
+	for i := 0; i < len(words)-1; i++ {
+		if name, ok := flagName(words[i]); ok && name == "cloud-config" {
+			return words[i+1]
+		}
+	}
+	if path := os.Getenv(CloudConfigEnv); path != "" {
+		return path
+	}
+	return redirectedInput(words)
+}
+
+func redirectedInput(words []string) string {
+	for i := 0; i < len(words)-1; i++ {
+		if words[i] == "<" {
+			return words[i+1]
+		}
+		if strings.HasPrefix(words[i], "<") && len(words[i]) > 1 {
+			return words[i][1:]
+		}
+	}
+	return ""
+}
+
+func readManifest(path string) *enaml.DeploymentManifest {
+	if path == "" {
+		return nil
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	return enaml.NewDeploymentManifest(b)
+}
+
+func readCloudConfig(path string) *enaml.CloudConfigManifest {
+	if path == "" {
+		return nil
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	return enaml.NewCloudConfigManifest(b)
+}
+
+// completeList completes the last element of a comma separated list,
+// leaving out values that are already in the list.
+func completeList(values []string, cur string) []string {
+	i := strings.LastIndex(cur, ",")
+	if i < 0 {
+		return withPrefix(values, cur)
+	}
+	head, partial := cur[:i+1], cur[i+1:]
+	listed := make(map[string]bool)
+	for _, v := range strings.Split(head, ",") {
+		listed[v] = true
+	}
+	var result []string
+	for _, v := range withPrefix(values, partial) {
+		if !listed[v] {
+			result = append(result, head+v)
+		}
+	}
+	return result
+}
+
+func withPrefix(candidates []string, prefix string) []string {
+	var result []string
+	for _, c := range candidates {
+		if strings.HasPrefix(c, prefix) {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+func isBoolFlag(v flag.Value) bool {
+	b, ok := v.(interface {
+		IsBoolFlag() bool
+	})
+	return ok && b.IsBoolFlag()
+}
+
+// flagName returns the name of the flag in arg, which may be
+// written with one or two dashes.
+func flagName(arg string) (string, bool) {
+	if !strings.HasPrefix(arg, "-") || arg == "-" || arg == "--" {
+		return "", false
+	}
+	name := strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-")
+	if strings.Contains(name, "=") {
+		return "", false
+	}
+	return name, true
+}
+
+const bashCompletion = `# bash completion for {{prog}}
+#
+# Add the following to your ~/.bashrc:
+#
+#   source <({{prog}} completion bash)
+#
+_{{func}}() {
+	local IFS=$'\n'
+	COMPREPLY=($({{prog}} __complete "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null))
+	if [ ${#COMPREPLY[@]} -eq 0 ]; then
+		compopt -o default 2>/dev/null
+	fi
+}
+complete -F _{{func}} {{prog}}
+`
+
+const zshCompletion = `#compdef {{prog}}
+#
+# Add the following to your ~/.zshrc:
+#
+#   source <({{prog}} completion zsh)
+#
+_{{func}}() {
+	# $words leaves out redirections, so the words are split from the
+	# buffer instead, keeping the '<' that names the manifest
+	local -a args candidates
+	args=("${(@Q)${(z)LBUFFER}}")
+	args=("${(@)args[2,-1]}")
+	if [[ -z "$LBUFFER" || "$LBUFFER" == *[[:space:]] ]]; then
+		args+=("")
+	fi
+	candidates=("${(@f)$({{prog}} __complete "${(@)args}" 2>/dev/null)}")
+	if [[ -z "${candidates[1]}" ]]; then
+		_files
+		return
+	fi
+	compadd -- "${candidates[@]}"
+}
+compdef _{{func}} {{prog}}
+`
+
+const fishCompletion = `# fish completion for {{prog}}
+#
+# Save this to ~/.config/fish/completions/{{prog}}.fish
+#
+function __{{func}}_complete
+	set -l args (commandline -opc)
+	set -e args[1]
+	{{prog}} __complete $args (commandline -ct) 2>/dev/null
+end
+
+function __{{func}}_has_candidates
+	test (count (__{{func}}_complete)) -gt 0
+end
+
+complete -c {{prog}} -f -n __{{func}}_has_candidates -a '(__{{func}}_complete)'
+`
@@ -0,0 +1,119 @@
+package cli
+
+import (
+	"bytes"
+	"flag"
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("shell completion", func() {
+	var p *Program
+
+	BeforeEach(func() {
+		fs := flag.NewFlagSet("change-az", flag.ContinueOnError)
+		fs.String("instance-group", "", "name of the instance group")
+		fs.String("az", "", "a comma separated list of az names")
+		fs.String("vm-type", "", "the vm type to use")
+		fs.Bool("rebalance", false, "rebalance instances")
+		fs.String("cloud-config", "", "path to a cloud config")
+
+		p = testProgram()
+		p.Transformations = append(p.Transformations, Transformation{
+			Name:  "change-az",
+			Flags: fs,
+			Complete: map[string]string{
+				"instance-group": ValueInstanceGroup,
+				"az":             ValueAZ,
+				"vm-type":        ValueVMType,
+			},
+		})
+		os.Unsetenv(ManifestEnv)
+		os.Unsetenv(CloudConfigEnv)
+	})
+
+	It("completes transform and command names", func() {
+		Ω(p.Complete([]string{"s"})).Should(Equal([]string{"scale"}))
+		Ω(p.Complete(nil)).Should(ContainElement("help"))
+		Ω(p.Complete([]string{"help", "ch"})).Should(Equal([]string{"change-az"}))
+	})
+
+	It("completes the flags that haven't been used", func() {
+		Ω(p.Complete([]string{"scale", "-"})).Should(Equal([]string{"-instance-group", "-instances"}))
+		Ω(p.Complete([]string{"scale", "-instance-group", "router", ""})).Should(Equal([]string{"-instances"}))
+		Ω(p.Complete([]string{"change-az", "-rebalance", "-i"})).Should(Equal([]string{"-instance-group"}))
+	})
+
+	It("completes instance groups from the redirected manifest", func() {
+		words := []string{"change-az", "<", "fixtures/manifest.yml", "-instance-group", "diego"}
+		Ω(p.Complete(words)).Should(Equal([]string{"diego_brain", "diego_cell"}))
+	})
+
+	It("completes instance groups from the manifest in the environment", func() {
+		os.Setenv(ManifestEnv, "fixtures/manifest.yml")
+		defer os.Unsetenv(ManifestEnv)
+		Ω(p.Complete([]string{"change-az", "-instance-group", "r"})).Should(Equal([]string{"router"}))
+	})
+
+	It("completes comma separated AZs from the cloud config", func() {
+		words := []string{"change-az", "-cloud-config", "fixtures/cloud-config.yml", "-az", "z1,"}
+		Ω(p.Complete(words)).Should(Equal([]string{"z1,z2", "z1,z3"}))
+	})
+
+	It("completes vm types from the cloud config in the environment", func() {
+		os.Setenv(CloudConfigEnv, "fixtures/cloud-config.yml")
+		defer os.Unsetenv(CloudConfigEnv)
+		Ω(p.Complete([]string{"change-az", "-vm-type", "t"})).Should(Equal([]string{"t2.micro"}))
+	})
+
+	It("skips global options and redirections before the transform", func() {
+		p.Options = []string{"-journal file", "-annotate"}
+		Ω(p.Complete([]string{"-json-errors", "s"})).Should(Equal([]string{"scale"}))
+		Ω(p.Complete([]string{"-journal", "j.yml", "-annotate", "scale", "-"})).Should(Equal([]string{"-instance-group", "-instances"}))
+		words := []string{"-json-errors", "<", "fixtures/manifest.yml", "change-az", "-instance-group", "diego"}
+		Ω(p.Complete(words)).Should(Equal([]string{"diego_brain", "diego_cell"}))
+	})
+
+	It("completes global options", func() {
+		p.Options = []string{"-journal file", "-annotate"}
+		Ω(p.Complete([]string{"-j"})).Should(Equal([]string{"-json-errors", "-journal"}))
+		Ω(p.Complete([]string{"-json-errors", "-a"})).Should(Equal([]string{"-annotate"}))
+		Ω(p.Complete([]string{"-journal", ""})).Should(BeEmpty())
+	})
+
+	It("completes nothing when there is no manifest", func() {
+		Ω(p.Complete([]string{"change-az", "-instance-group", ""})).Should(BeEmpty())
+		Ω(p.Complete([]string{"scale", "-instances", ""})).Should(BeEmpty())
+	})
+
+	Context("the completion command", func() {
+		var stdout, stderr *bytes.Buffer
+
+		BeforeEach(func() {
+			stdout, stderr = &bytes.Buffer{}, &bytes.Buffer{}
+		})
+
+		for _, shell := range []string{"bash", "zsh", "fish"} {
+			shell := shell
+			It("writes a "+shell+" script", func() {
+				Ω(p.Completion([]string{shell}, stdout, stderr)).Should(Equal(0))
+				Ω(stdout.String()).Should(ContainSubstring("omg-transform __complete"))
+				Ω(stdout.String()).Should(ContainSubstring("_omg_transform"))
+				Ω(stdout.String()).ShouldNot(ContainSubstring("{{"))
+			})
+		}
+
+		It("passes redirections to the program from zsh", func() {
+			Ω(p.Completion([]string{"zsh"}, stdout, stderr)).Should(Equal(0))
+			Ω(stdout.String()).Should(ContainSubstring("${(z)LBUFFER}"))
+			Ω(stdout.String()).ShouldNot(ContainSubstring("words[2,$CURRENT]"))
+		})
+
+		It("rejects unsupported shells", func() {
+			Ω(p.Completion([]string{"tcsh"}, stdout, stderr)).Should(Equal(1))
+			Ω(stderr.String()).Should(ContainSubstring(`unsupported shell "tcsh"`))
+		})
+	})
+})
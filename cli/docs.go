@@ -0,0 +1,157 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// WriteMarkdown writes a Markdown reference for every transformation.
+func (p *Program) WriteMarkdown(w io.Writer) {
+	fmt.Fprintf(w, "# %s\n\n", p.Name)
+	fmt.Fprintf(w, "%s\n\n", p.Description)
+	fmt.Fprintf(w, "```\n%s <transform> [args...]\n```\n\n", p.Name)
+
+	fmt.Fprintf(w, "## Transformations\n\n")
+	for _, t := range p.Transformations {
+		fmt.Fprintf(w, " - [`%s`](#%s): %s\n", t.Name, t.Name, t.Description)
+	}
+
+	for _, t := range p.Transformations {
+		fmt.Fprintf(w, "\n### %s\n\n", t.Name)
+		fmt.Fprintf(w, "%s\n\n", sentence(t.Description))
+		fmt.Fprintf(w, "```\n%s %s %s\n```\n", p.Name, t.Name, t.Usage)
+
+		if hasFlags(t.Flags) {
+			fmt.Fprintf(w, "\n| Flag | Type | Default | Description |\n")
+			fmt.Fprintf(w, "|------|------|---------|-------------|\n")
+			t.Flags.VisitAll(func(f *flag.Flag) {
+				typ, usage := flag.UnquoteUsage(f)
+				fmt.Fprintf(w, "| `-%s` | %s | %s | %s |\n", f.Name, typ, markdownDefault(f), escapeTable(usage))
+			})
+		}
+
+		if len(t.Examples) > 0 {
+			fmt.Fprintf(w, "\nExamples:\n\n```sh\n")
+			for _, e := range t.Examples {
+				fmt.Fprintf(w, "%s %s\n", p.Name, e)
+			}
+			fmt.Fprintf(w, "```\n")
+		}
+	}
+}
+
+// WriteManPage writes a man page in roff format documenting the program
+// and every transformation.
+func (p *Program) WriteManPage(w io.Writer, date time.Time) {
+	title := strings.ToUpper(p.Name)
+	fmt.Fprintf(w, ".TH %s 1 %q %q %q\n", title, date.Format("January 2006"), p.Name+" "+p.Version, p.Name+" manual")
+	fmt.Fprintf(w, ".SH NAME\n%s \\- %s\n", roff(p.Name), roff(p.Description))
+	fmt.Fprintf(w, ".SH SYNOPSIS\n.B %s\n\\fItransform\\fR [\\fIargs\\fR...]\n", roff(p.Name))
+	fmt.Fprintf(w, ".SH DESCRIPTION\n")
+	fmt.Fprintf(w, "%s reads a manifest from standard input, applies the transformation and writes the result to standard output.\n", roff(p.Name))
+
+	fmt.Fprintf(w, ".SH TRANSFORMATIONS\n")
+	for _, t := range p.Transformations {
+		fmt.Fprintf(w, ".SS %s\n", roff(t.Name))
+		fmt.Fprintf(w, "%s\n", roff(sentence(t.Description)))
+		fmt.Fprintf(w, ".PP\n.B %s %s\n%s\n", roff(p.Name), roff(t.Name), roff(t.Usage))
+		if hasFlags(t.Flags) {
+			t.Flags.VisitAll(func(f *flag.Flag) {
+				typ, usage := flag.UnquoteUsage(f)
+				fmt.Fprintf(w, ".TP\n.B \\-%s", roff(f.Name))
+				if typ != "" {
+					fmt.Fprintf(w, " \\fI%s\\fR", roff(typ))
+				}
+				fmt.Fprintf(w, "\n%s", roff(usage))
+				if !isZeroDefault(f) {
+					fmt.Fprintf(w, " (default %s)", roff(f.DefValue))
+				}
+				fmt.Fprintln(w)
+			})
+		}
+		if len(t.Examples) > 0 {
+			fmt.Fprintf(w, ".PP\nExamples:\n.PP\n.nf\n.RS\n")
+			for _, e := range t.Examples {
+				fmt.Fprintf(w, "%s %s\n", roff(p.Name), roff(e))
+			}
+			fmt.Fprintf(w, ".RE\n.fi\n")
+		}
+	}
+}
+
+// Docs implements the 'docs' command, which generates reference
+// documentation in Markdown or man page format.
+func (p *Program) Docs(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("docs", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	format := fs.String("format", "markdown", "the documentation format (markdown or man)")
+	output := fs.String("output", "", "file to write the documentation to (defaults to stdout)")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+
+	var write func(io.Writer)
+	switch *format {
+	case "markdown":
+		write = p.WriteMarkdown
+	case "man":
+		write = func(w io.Writer) { p.WriteManPage(w, time.Now()) }
+	default:
+		fmt.Fprintf(stderr, "ERROR: invalid format %q, must be markdown or man\n", *format)
+		return 1
+	}
+
+	w := stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintf(stderr, "ERROR: %v\n", err)
+			return 1
+		}
+		defer f.Close()
+		w = f
+	}
+	write(w)
+	return 0
+}
+
+func isZeroDefault(f *flag.Flag) bool {
+	switch f.DefValue {
+	case "", "0", "false":
+		return true
+	}
+	return false
+}
+
+func markdownDefault(f *flag.Flag) string {
+	if isZeroDefault(f) {
+		return ""
+	}
+	return "`" + f.DefValue + "`"
+}
+
+func escapeTable(s string) string {
+	return strings.Replace(s, "|", "\\|", -1)
+}
+
+// roff escapes text for use in a man page.
+func roff(s string) string {
+	s = strings.Replace(s, "\\", "\\e", -1)
+	s = strings.Replace(s, "-", "\\-", -1)
+	if strings.HasPrefix(s, ".") || strings.HasPrefix(s, "'") {
+		s = "\\&" + s
+	}
+	return s
+}
+
+// sentence turns a description into a sentence.
+func sentence(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:] + "."
+}
@@ -0,0 +1,84 @@
+package cli
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("reference docs", func() {
+	var p *Program
+
+	BeforeEach(func() {
+		p = testProgram()
+	})
+
+	It("writes a Markdown reference", func() {
+		var buf bytes.Buffer
+		p.WriteMarkdown(&buf)
+		out := buf.String()
+		Ω(out).Should(HavePrefix("# omg-transform\n"))
+		Ω(out).Should(ContainSubstring(" - [`scale`](#scale): change the number of instances in an instance group"))
+		Ω(out).Should(ContainSubstring("### scale\n\nChange the number of instances in an instance group.\n"))
+		Ω(out).Should(ContainSubstring("| `-instance-group` | string |  | name of the instance group |"))
+		Ω(out).Should(ContainSubstring("| `-instances` | int | `-1` | number of instances |"))
+		Ω(out).Should(ContainSubstring("```sh\nomg-transform scale -instance-group router -instances 3\n```"))
+	})
+
+	It("writes a man page", func() {
+		var buf bytes.Buffer
+		p.WriteManPage(&buf, time.Date(2016, time.October, 1, 0, 0, 0, 0, time.UTC))
+		out := buf.String()
+		Ω(out).Should(HavePrefix(`.TH OMG-TRANSFORM 1 "October 2016" "omg-transform v1.2.3"`))
+		Ω(out).Should(ContainSubstring(".SS scale\n"))
+		Ω(out).Should(ContainSubstring(".B \\-instance\\-group \\fIstring\\fR\nname of the instance group\n"))
+		Ω(out).Should(ContainSubstring("number of instances (default \\-1)\n"))
+	})
+
+	Context("the docs command", func() {
+		var stdout, stderr *bytes.Buffer
+
+		BeforeEach(func() {
+			stdout, stderr = &bytes.Buffer{}, &bytes.Buffer{}
+		})
+
+		It("defaults to Markdown", func() {
+			Ω(p.Docs(nil, stdout, stderr)).Should(Equal(0))
+			Ω(stdout.String()).Should(HavePrefix("# omg-transform\n"))
+		})
+
+		It("writes to a file", func() {
+			dir, err := ioutil.TempDir("", "omg-transform-docs")
+			Ω(err).ShouldNot(HaveOccurred())
+			defer os.RemoveAll(dir)
+
+			path := filepath.Join(dir, "omg-transform.1")
+			Ω(p.Docs([]string{"-format", "man", "-output", path}, stdout, stderr)).Should(Equal(0))
+			Ω(stdout.Len()).Should(Equal(0))
+			b, err := ioutil.ReadFile(path)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(string(b)).Should(HavePrefix(".TH OMG-TRANSFORM 1"))
+		})
+
+		It("rejects unknown formats", func() {
+			Ω(p.Docs([]string{"-format", "html"}, stdout, stderr)).Should(Equal(1))
+			Ω(stderr.String()).Should(ContainSubstring(`invalid format "html"`))
+		})
+
+		It("doesn't create the output file for an unknown format", func() {
+			dir, err := ioutil.TempDir("", "omg-transform-docs")
+			Ω(err).ShouldNot(HaveOccurred())
+			defer os.RemoveAll(dir)
+
+			path := filepath.Join(dir, "omg-transform.html")
+			Ω(p.Docs([]string{"-format", "html", "-output", path}, stdout, stderr)).Should(Equal(1))
+			_, err = os.Stat(path)
+			Ω(os.IsNotExist(err)).Should(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,136 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/enaml-ops/omg-transform/errs"
+	"github.com/enaml-ops/omg-transform/suggest"
+)
+
+// Exit codes used by the omg-transform programs.
+const (
+	ExitFailure      = 1 // the transformation failed
+	ExitUsage        = 2 // invalid arguments or an unknown transform
+	ExitNotFound     = 3 // something the transformation refers to doesn't exist
+	ExitPrecondition = 4 // the input can't be transformed as requested
+)
+
+// The error kinds reported by the transformation packages.
+const (
+	KindNotFound     = errs.KindNotFound
+	KindArgument     = errs.KindArgument
+	KindPrecondition = errs.KindPrecondition
+	KindFailure      = errs.KindFailure
+)
+
+// kinded is implemented by the errors returned by transformations.
+type kinded interface {
+	ErrorKind() string
+}
+
+// ErrorKind returns the kind of err, or of the first error it wraps
+// that has a kind, or KindFailure if it isn't known.
+func ErrorKind(err error) string {
+	var k kinded
+	if errors.As(err, &k) {
+		return k.ErrorKind()
+	}
+	return KindFailure
+}
+
+// ExitCode returns the exit code for err.
+func ExitCode(err error) int {
+	switch ErrorKind(err) {
+	case KindArgument:
+		return ExitUsage
+	case KindNotFound:
+		return ExitNotFound
+	case KindPrecondition:
+		return ExitPrecondition
+	}
+	return ExitFailure
+}
+
+// UsageError is an error in the way a program was invoked, such as an
+// unknown transform or a flag that couldn't be parsed.
+type UsageError struct {
+	Message string `json:"message"`
+}
+
+func (e *UsageError) Error() string { return e.Message }
+
+func (e *UsageError) ErrorKind() string { return KindArgument }
+
+// UnknownTransform returns the error for a transform that doesn't
+// exist, suggesting transforms and commands with similar names.
+func (p *Program) UnknownTransform(name string) error {
+	var names []string
+	for _, t := range p.Transformations {
+		names = append(names, t.Name)
+	}
+	for _, c := range p.Commands {
+		names = append(names, c.Name)
+	}
+	return &UsageError{Message: fmt.Sprintf("unknown transform %q%s", name, suggest.DidYouMean(suggest.Similar(name, names)))}
+}
+
+// ArgumentError returns err as an argument error, unless it already
+// has a kind.  It is used for errors returned while building a
+// transformation, which are all caused by its arguments.
+func ArgumentError(err error) error {
+	if _, ok := err.(kinded); ok {
+		return err
+	}
+	return &UsageError{Message: err.Error()}
+}
+
+// ErrorReport is the JSON written for an error when JSONErrors is set.
+type ErrorReport struct {
+	Error     string      `json:"error"`
+	Kind      string      `json:"kind"`
+	ExitCode  int         `json:"exit_code"`
+	Transform string      `json:"transform,omitempty"`
+	Details   interface{} `json:"details,omitempty"`
+}
+
+// NewErrorReport returns the report for an error returned by the named
+// transform.
+func NewErrorReport(transform string, err error) ErrorReport {
+	report := ErrorReport{
+		Error:     err.Error(),
+		Kind:      ErrorKind(err),
+		ExitCode:  ExitCode(err),
+		Transform: transform,
+	}
+	if _, ok := err.(kinded); ok {
+		report.Details = err
+	}
+	return report
+}
+
+// ReportError writes err to w and returns the exit code to use.  The
+// error is written as JSON if JSONErrors is set, otherwise as a line of
+// text followed, for usage errors, by a pointer to the transform's help.
+func (p *Program) ReportError(w io.Writer, transform string, err error) int {
+	code := ExitCode(err)
+	if p.JSONErrors {
+		b, jsonErr := json.Marshal(NewErrorReport(transform, err))
+		if jsonErr == nil {
+			fmt.Fprintf(w, "%s\n", b)
+			return code
+		}
+	}
+
+	fmt.Fprintf(w, "ERROR: %v\n", err)
+	if code == ExitUsage {
+		if _, ok := p.Lookup(transform); ok {
+			fmt.Fprintf(w, "Run '%s help %s' for usage.\n", p.Name, transform)
+		} else {
+			fmt.Fprintf(w, "Run '%s help' for a list of transforms.\n", p.Name)
+		}
+	}
+	return code
+}
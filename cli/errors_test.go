@@ -0,0 +1,93 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type notFoundError struct {
+	Name string `json:"name"`
+}
+
+func (e *notFoundError) Error() string     { return "couldn't find instance group " + e.Name }
+func (e *notFoundError) ErrorKind() string { return KindNotFound }
+
+var _ = Describe("errors", func() {
+	It("maps error kinds to exit codes", func() {
+		Ω(ExitCode(errors.New("boom"))).Should(Equal(ExitFailure))
+		Ω(ExitCode(&UsageError{Message: "bad flag"})).Should(Equal(ExitUsage))
+		Ω(ExitCode(&notFoundError{Name: "router"})).Should(Equal(ExitNotFound))
+	})
+
+	It("uses the kind of wrapped errors", func() {
+		err := fmt.Errorf("step 2 (scale): %w", &notFoundError{Name: "router"})
+		Ω(ErrorKind(err)).Should(Equal(KindNotFound))
+		Ω(ExitCode(err)).Should(Equal(ExitNotFound))
+	})
+
+	It("treats errors from building a transformation as argument errors", func() {
+		err := ArgumentError(errors.New("flag provided but not defined: -bogus"))
+		Ω(ErrorKind(err)).Should(Equal(KindArgument))
+		Ω(err).Should(MatchError("flag provided but not defined: -bogus"))
+
+		nf := &notFoundError{Name: "router"}
+		Ω(ArgumentError(nf)).Should(BeIdenticalTo(nf))
+	})
+
+	Context("reporting errors", func() {
+		var (
+			p   *Program
+			buf *bytes.Buffer
+		)
+
+		BeforeEach(func() {
+			p = testProgram()
+			buf = &bytes.Buffer{}
+		})
+
+		It("writes text with a pointer to the transform's help", func() {
+			code := p.ReportError(buf, "scale", &UsageError{Message: "missing required flag -instances"})
+			Ω(code).Should(Equal(ExitUsage))
+			Ω(buf.String()).Should(Equal("ERROR: missing required flag -instances\nRun 'omg-transform help scale' for usage.\n"))
+		})
+
+		It("writes JSON", func() {
+			p.JSONErrors = true
+			code := p.ReportError(buf, "scale", &notFoundError{Name: "router"})
+			Ω(code).Should(Equal(ExitNotFound))
+
+			var report map[string]interface{}
+			Ω(json.Unmarshal(buf.Bytes(), &report)).Should(Succeed())
+			Ω(report).Should(Equal(map[string]interface{}{
+				"error":     "couldn't find instance group router",
+				"kind":      "not_found",
+				"exit_code": float64(ExitNotFound),
+				"transform": "scale",
+				"details":   map[string]interface{}{"name": "router"},
+			}))
+		})
+
+		It("omits details for errors without a kind", func() {
+			p.JSONErrors = true
+			Ω(p.ReportError(buf, "scale", errors.New("boom"))).Should(Equal(ExitFailure))
+			Ω(buf.String()).Should(Equal(`{"error":"boom","kind":"failure","exit_code":1,"transform":"scale"}` + "\n"))
+		})
+	})
+})
+
+var _ = Describe("unknown transforms", func() {
+	It("suggests transforms with similar names", func() {
+		err := testProgram().UnknownTransform("scael")
+		Ω(ErrorKind(err)).Should(Equal(KindArgument))
+		Ω(err).Should(MatchError(`unknown transform "scael", did you mean scale?`))
+	})
+
+	It("doesn't suggest anything for unrelated names", func() {
+		Ω(testProgram().UnknownTransform("merge")).Should(MatchError(`unknown transform "merge"`))
+	})
+})
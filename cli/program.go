@@ -0,0 +1,134 @@
+// Package cli contains the command line support shared by the
+// omg-transform programs.
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// Transformation describes a transformation independently of the
+// kind of manifest it applies to.
+type Transformation struct {
+	Name        string
+	Description string
+	Usage       string
+	Examples    []string
+	Flags       *flag.FlagSet // may be nil
+
+	// Complete maps flag names to the kind of value they take,
+	// for example ValueInstanceGroup.
+	Complete map[string]string
+}
+
+// Command is a built-in command that isn't a transformation.
+type Command struct {
+	Name        string
+	Description string
+}
+
+// Program describes one of the omg-transform programs.
+type Program struct {
+	Name            string // the name of the executable
+	Description     string // what the program does, in one line
+	Version         string
+	Transformations []Transformation
+	Commands        []Command
+
+	// Options lists global options other than -json-errors, as they
+	// appear in the usage, for example "-journal file".
+	Options []string
+
+	// JSONErrors makes ReportError write errors as JSON.
+	JSONErrors bool
+}
+
+// Lookup returns the named transformation.
+func (p *Program) Lookup(name string) (Transformation, bool) {
+	for _, t := range p.Transformations {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return Transformation{}, false
+}
+
+// WriteUsage writes the program's usage along with a list
+// of transformations and commands.
+func (p *Program) WriteUsage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: %s [-json-errors]", p.Name)
+	for _, o := range p.Options {
+		fmt.Fprintf(w, " [%s]", o)
+	}
+	fmt.Fprintf(w, " <transform> [args...]\n")
+	fmt.Fprintf(w, "Transforms:\n")
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	for _, t := range p.Transformations {
+		fmt.Fprintf(tw, "  %s\t%s\n", t.Name, t.Description)
+	}
+	tw.Flush()
+
+	if len(p.Commands) > 0 {
+		fmt.Fprintf(w, "Commands:\n")
+		tw = tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		for _, c := range p.Commands {
+			fmt.Fprintf(tw, "  %s\t%s\n", c.Name, c.Description)
+		}
+		tw.Flush()
+	}
+	fmt.Fprintf(w, "Run '%s help <transform>' for more information on a transform.\n", p.Name)
+}
+
+// WriteHelp writes the description, usage, flags and examples of the
+// named transformation.
+func (p *Program) WriteHelp(w io.Writer, name string) error {
+	t, ok := p.Lookup(name)
+	if !ok {
+		return fmt.Errorf("unknown transform %q", name)
+	}
+
+	fmt.Fprintf(w, "%s - %s\n\n", t.Name, t.Description)
+	fmt.Fprintf(w, "Usage:\n  %s %s %s\n", p.Name, t.Name, t.Usage)
+	if hasFlags(t.Flags) {
+		fmt.Fprintf(w, "\nFlags:\n")
+		t.Flags.SetOutput(w)
+		t.Flags.PrintDefaults()
+	}
+	if len(t.Examples) > 0 {
+		fmt.Fprintf(w, "\nExamples:\n")
+		for _, e := range t.Examples {
+			fmt.Fprintf(w, "  %s %s\n", p.Name, e)
+		}
+	}
+	return nil
+}
+
+// Help implements the 'help' command.  With no arguments it writes the
+// program's usage, otherwise it writes help for each named transform.
+func (p *Program) Help(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		p.WriteUsage(stdout)
+		return 0
+	}
+	for i, name := range args {
+		if i > 0 {
+			fmt.Fprintln(stdout)
+		}
+		if err := p.WriteHelp(stdout, name); err != nil {
+			fmt.Fprintf(stderr, "ERROR: %v\n", err)
+			return 1
+		}
+	}
+	return 0
+}
+
+func hasFlags(fs *flag.FlagSet) bool {
+	if fs == nil {
+		return false
+	}
+	n := 0
+	fs.VisitAll(func(*flag.Flag) { n++ })
+	return n > 0
+}
@@ -0,0 +1,96 @@
+package cli
+
+import (
+	"bytes"
+	"flag"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// testProgram returns a program with a transformation that takes
+// flags and one that doesn't.
+func testProgram() *Program {
+	fs := flag.NewFlagSet("scale", flag.ContinueOnError)
+	fs.String("instance-group", "", "name of the instance group")
+	fs.Int("instances", -1, "number of instances")
+
+	return &Program{
+		Name:        "omg-transform",
+		Description: "apply transformations to bosh deployment manifests",
+		Version:     "v1.2.3",
+		Transformations: []Transformation{
+			{
+				Name:        "scale",
+				Description: "change the number of instances in an instance group",
+				Usage:       "-instance-group <name> -instances <n>",
+				Examples:    []string{"scale -instance-group router -instances 3"},
+				Flags:       fs,
+			},
+			{
+				Name:        "noop",
+				Description: "do nothing",
+			},
+		},
+		Commands: []Command{
+			{Name: "help", Description: "show help for a transform"},
+		},
+	}
+}
+
+var _ = Describe("Program", func() {
+	var (
+		p              *Program
+		stdout, stderr *bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		p = testProgram()
+		stdout, stderr = &bytes.Buffer{}, &bytes.Buffer{}
+	})
+
+	It("lists transformations and commands in its usage", func() {
+		p.WriteUsage(stdout)
+		Ω(stdout.String()).Should(ContainSubstring("Usage: omg-transform [-json-errors] <transform> [args...]"))
+		Ω(stdout.String()).Should(MatchRegexp(`scale\s+change the number of instances`))
+		Ω(stdout.String()).Should(MatchRegexp(`help\s+show help for a transform`))
+	})
+
+	It("lists global options in its usage", func() {
+		p.Options = []string{"-journal file", "-annotate"}
+		p.WriteUsage(stdout)
+		Ω(stdout.String()).Should(ContainSubstring("Usage: omg-transform [-json-errors] [-journal file] [-annotate] <transform> [args...]"))
+	})
+
+	It("writes the usage, flags and examples of a transformation", func() {
+		Ω(p.WriteHelp(stdout, "scale")).Should(Succeed())
+		out := stdout.String()
+		Ω(out).Should(HavePrefix("scale - change the number of instances in an instance group\n"))
+		Ω(out).Should(ContainSubstring("omg-transform scale -instance-group <name> -instances <n>"))
+		Ω(out).Should(ContainSubstring("-instance-group string"))
+		Ω(out).Should(ContainSubstring("(default -1)"))
+		Ω(out).Should(ContainSubstring("  omg-transform scale -instance-group router -instances 3"))
+	})
+
+	It("omits the flags section for transformations without flags", func() {
+		Ω(p.WriteHelp(stdout, "noop")).Should(Succeed())
+		Ω(stdout.String()).ShouldNot(ContainSubstring("Flags:"))
+	})
+
+	Context("the help command", func() {
+		It("writes the usage with no arguments", func() {
+			Ω(p.Help(nil, stdout, stderr)).Should(Equal(0))
+			Ω(stdout.String()).Should(ContainSubstring("Transforms:"))
+		})
+
+		It("writes help for the named transformation", func() {
+			Ω(p.Help([]string{"scale"}, stdout, stderr)).Should(Equal(0))
+			Ω(stdout.String()).Should(ContainSubstring("scale - "))
+		})
+
+		It("fails on an unknown transformation", func() {
+			Ω(p.Help([]string{"bogus"}, stdout, stderr)).Should(Equal(1))
+			Ω(stderr.String()).Should(ContainSubstring(`unknown transform "bogus"`))
+		})
+	})
+})
@@ -0,0 +1,48 @@
+package cloudconfig
+
+import (
+	"flag"
+
+	"github.com/enaml-ops/omg-transform/registry"
+)
+
+func init() {
+	RegisterTransformation(TransformationInfo{
+		Info: registry.Info{
+			Name:        "add-subnet",
+			Description: "add a subnet to a network, with a gateway, reserved and static addresses computed from its range",
+			Usage:       "-network name -range cidr -az name [-reserved n] [-static n] [-dns ips] [-create]",
+			Examples: []string{
+				"add-subnet -network cf -range 10.0.8.0/22 -az us-west-1b -static 32",
+				"add-subnet -network services -range 10.0.16.0/24 -az z1 -dns 10.0.0.2 -create",
+			},
+			Flags: func() *flag.FlagSet { return new(SubnetAdder).flagSet() },
+		},
+		Builder: AddSubnetTransformation,
+	})
+	RegisterTransformation(TransformationInfo{
+		Info: registry.Info{
+			Name:        "resize-static",
+			Description: "grow or shrink the static addresses of a subnet",
+			Usage:       "-network name [-az name] [-range cidr] -size n",
+			Examples: []string{
+				"resize-static -network cf -az us-west-1b -size 50",
+			},
+			Flags: func() *flag.FlagSet { return new(StaticResizer).flagSet() },
+		},
+		Builder: ResizeStaticTransformation,
+	})
+	RegisterTransformation(TransformationInfo{
+		Info: registry.Info{
+			Name:        "script",
+			Description: "edit the cloud config with a Starlark script",
+			Usage:       "-file script.star | -e script [-max-steps n]",
+			Examples: []string{
+				"script -file add-vm-types.star",
+				`script -e 'vm_type("default")["cloud_properties"]["instance_type"] = "m4.large"'`,
+			},
+			Flags: func() *flag.FlagSet { return new(Script).flagSet() },
+		},
+		Builder: ScriptTransformation,
+	})
+}
@@ -0,0 +1,142 @@
+package cloudconfig
+
+import (
+	"fmt"
+
+	"github.com/enaml-ops/enaml"
+	"github.com/enaml-ops/omg-transform/errs"
+	"github.com/enaml-ops/omg-transform/suggest"
+)
+
+// ErrNetworkNotFound is returned when a network isn't defined in the
+// cloud config.
+type ErrNetworkNotFound struct {
+	Name        string   `json:"name"`
+	Suggestions []string `json:"suggestions,omitempty"` // similar networks
+}
+
+func (e *ErrNetworkNotFound) Error() string {
+	return fmt.Sprintf("network %s is not defined in the cloud config%s", e.Name, suggest.DidYouMean(e.Suggestions))
+}
+
+func (e *ErrNetworkNotFound) ErrorKind() string { return errs.KindNotFound }
+
+// ErrAZNotFound is returned when an AZ isn't defined in the cloud config.
+type ErrAZNotFound struct {
+	Name        string   `json:"name"`
+	Suggestions []string `json:"suggestions,omitempty"` // similar AZs
+}
+
+func (e *ErrAZNotFound) Error() string {
+	return fmt.Sprintf("az %s is not defined in the cloud config%s", e.Name, suggest.DidYouMean(e.Suggestions))
+}
+
+func (e *ErrAZNotFound) ErrorKind() string { return errs.KindNotFound }
+
+// ErrVMTypeNotFound is returned when a vm type isn't defined in the
+// cloud config.
+type ErrVMTypeNotFound struct {
+	Name        string   `json:"name"`
+	Suggestions []string `json:"suggestions,omitempty"` // similar vm types
+}
+
+func (e *ErrVMTypeNotFound) Error() string {
+	return fmt.Sprintf("vm type %s is not defined in the cloud config%s", e.Name, suggest.DidYouMean(e.Suggestions))
+}
+
+func (e *ErrVMTypeNotFound) ErrorKind() string { return errs.KindNotFound }
+
+// ErrVMExtensionNotFound is returned when a vm extension isn't defined in
+// the cloud config.
+type ErrVMExtensionNotFound struct {
+	Name        string   `json:"name"`
+	Suggestions []string `json:"suggestions,omitempty"` // similar vm extensions
+}
+
+func (e *ErrVMExtensionNotFound) Error() string {
+	return fmt.Sprintf("vm extension %s is not defined in the cloud config%s", e.Name, suggest.DidYouMean(e.Suggestions))
+}
+
+func (e *ErrVMExtensionNotFound) ErrorKind() string { return errs.KindNotFound }
+
+// ErrDiskTypeNotFound is returned when a disk type isn't defined in the
+// cloud config.
+type ErrDiskTypeNotFound struct {
+	Name        string   `json:"name"`
+	Suggestions []string `json:"suggestions,omitempty"` // similar disk types
+}
+
+func (e *ErrDiskTypeNotFound) Error() string {
+	return fmt.Sprintf("disk type %s is not defined in the cloud config%s", e.Name, suggest.DidYouMean(e.Suggestions))
+}
+
+func (e *ErrDiskTypeNotFound) ErrorKind() string { return errs.KindNotFound }
+
+// CheckNetwork returns an ErrNetworkNotFound if the named network isn't
+// defined in the cloud config.
+func CheckNetwork(cc *enaml.CloudConfigManifest, name string) error {
+	networks, err := Networks(cc)
+	if err != nil {
+		return err
+	}
+	var names []string
+	for _, n := range networks {
+		if n.Name == name {
+			return nil
+		}
+		names = append(names, n.Name)
+	}
+	return &ErrNetworkNotFound{Name: name, Suggestions: suggest.Similar(name, names)}
+}
+
+// CheckAZ returns an ErrAZNotFound if the named AZ isn't defined in the
+// cloud config.
+func CheckAZ(cc *enaml.CloudConfigManifest, name string) error {
+	var names []string
+	for _, az := range cc.AZs {
+		if az.Name == name {
+			return nil
+		}
+		names = append(names, az.Name)
+	}
+	return &ErrAZNotFound{Name: name, Suggestions: suggest.Similar(name, names)}
+}
+
+// CheckVMType returns an ErrVMTypeNotFound if the named vm type isn't
+// defined in the cloud config.
+func CheckVMType(cc *enaml.CloudConfigManifest, name string) error {
+	var names []string
+	for _, vt := range cc.VMTypes {
+		if vt.Name == name {
+			return nil
+		}
+		names = append(names, vt.Name)
+	}
+	return &ErrVMTypeNotFound{Name: name, Suggestions: suggest.Similar(name, names)}
+}
+
+// CheckVMExtension returns an ErrVMExtensionNotFound if the named vm
+// extension isn't defined in the cloud config.
+func CheckVMExtension(cc *enaml.CloudConfigManifest, name string) error {
+	var names []string
+	for _, e := range cc.VMExtensions {
+		if e.Name == name {
+			return nil
+		}
+		names = append(names, e.Name)
+	}
+	return &ErrVMExtensionNotFound{Name: name, Suggestions: suggest.Similar(name, names)}
+}
+
+// CheckDiskType returns an ErrDiskTypeNotFound if the named disk type
+// isn't defined in the cloud config.
+func CheckDiskType(cc *enaml.CloudConfigManifest, name string) error {
+	var names []string
+	for _, dt := range cc.DiskTypes {
+		if dt.Name == name {
+			return nil
+		}
+		names = append(names, dt.Name)
+	}
+	return &ErrDiskTypeNotFound{Name: name, Suggestions: suggest.Similar(name, names)}
+}
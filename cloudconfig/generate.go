@@ -0,0 +1,399 @@
+package cloudconfig
+
+import (
+	"net"
+	"sort"
+	"strconv"
+
+	"github.com/enaml-ops/enaml"
+	"github.com/enaml-ops/omg-transform/errs"
+)
+
+// Placeholder is the value of the cloud properties of a generated cloud
+// config that must be filled in by hand.
+const Placeholder = "REPLACE_ME"
+
+// DefaultDiskSize is the size, in MB, of generated disk types whose
+// names aren't sizes.
+const DefaultDiskSize = 10240
+
+// IaaSProfile fills in the IaaS specific cloud properties of a generated
+// cloud config.
+type IaaSProfile struct {
+	Name     string
+	AZ       func(az string) interface{}
+	VMType   func(name string) interface{}
+	DiskType func(name string) interface{}
+	Subnet   func(network, az string) interface{}
+}
+
+type props map[string]interface{}
+
+// iaasProfiles are the built-in IaaS profiles, by name.
+var iaasProfiles = map[string]*IaaSProfile{
+	"aws": {
+		Name:     "aws",
+		AZ:       func(az string) interface{} { return props{"availability_zone": az} },
+		VMType:   func(name string) interface{} { return props{"instance_type": name} },
+		DiskType: func(string) interface{} { return props{"type": "gp2"} },
+		Subnet:   func(string, string) interface{} { return props{"subnet": Placeholder} },
+	},
+	"azure": {
+		Name:     "azure",
+		AZ:       func(az string) interface{} { return props{"availability_zone": az} },
+		VMType:   func(name string) interface{} { return props{"instance_type": name} },
+		DiskType: func(string) interface{} { return props{"storage_account_type": "Standard_LRS"} },
+		Subnet: func(string, string) interface{} {
+			return props{"virtual_network_name": Placeholder, "subnet_name": Placeholder}
+		},
+	},
+	"gcp": {
+		Name:     "gcp",
+		AZ:       func(az string) interface{} { return props{"zone": az} },
+		VMType:   func(name string) interface{} { return props{"machine_type": name} },
+		DiskType: func(string) interface{} { return props{"type": "pd-ssd"} },
+		Subnet: func(string, string) interface{} {
+			return props{"network_name": Placeholder, "subnetwork_name": Placeholder}
+		},
+	},
+	"openstack": {
+		Name:     "openstack",
+		AZ:       func(az string) interface{} { return props{"availability_zone": az} },
+		VMType:   func(name string) interface{} { return props{"instance_type": name} },
+		DiskType: func(string) interface{} { return props{"type": Placeholder} },
+		Subnet:   func(string, string) interface{} { return props{"net_id": Placeholder} },
+	},
+	"vsphere": {
+		Name: "vsphere",
+		AZ: func(string) interface{} {
+			return props{"datacenters": []interface{}{
+				props{"name": Placeholder, "clusters": []interface{}{props{Placeholder: props{}}}},
+			}}
+		},
+		VMType:   func(string) interface{} { return props{"cpu": 2, "ram": 4096, "disk": 16384} },
+		DiskType: func(string) interface{} { return props{"type": "thin"} },
+		Subnet:   func(string, string) interface{} { return props{"name": Placeholder} },
+	},
+}
+
+// IaaSProfiles returns the names of the built-in IaaS profiles.
+func IaaSProfiles() []string {
+	var names []string
+	for name := range iaasProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LookupIaaSProfile returns the built-in IaaS profile with the
+// specified name.
+func LookupIaaSProfile(name string) (*IaaSProfile, bool) {
+	p, ok := iaasProfiles[name]
+	return p, ok
+}
+
+// GenerateOptions control how a cloud config is generated.
+type GenerateOptions struct {
+	// CIDR is the address space that subnets are allocated from,
+	// and defaults to 10.0.0.0/16.  The subnets of AZs that have
+	// static IPs are placed around those IPs instead.
+	CIDR *net.IPNet
+
+	// Workers is the number of compilation workers, and defaults to 4.
+	Workers int
+}
+
+// Generate returns a cloud config skeleton with every AZ, network,
+// vm type, vm extension and disk type used by dm, and a compilation
+// block.  Networks are manual networks with a subnet per AZ, sized for
+// the instances placed in that AZ, and with static ranges that hold the
+// static IPs already used by dm.
+func Generate(dm *enaml.DeploymentManifest, profile *IaaSProfile, opts GenerateOptions) (*enaml.CloudConfigManifest, error) {
+	if opts.CIDR == nil {
+		_, opts.CIDR, _ = net.ParseCIDR("10.0.0.0/16")
+	}
+	if opts.Workers == 0 {
+		opts.Workers = 4
+	}
+
+	var azs, vmTypes, extensions, diskTypes, networks []string
+	for _, ig := range dm.InstanceGroups {
+		azs = appendNew(azs, ig.AZs...)
+		vmTypes = appendNew(vmTypes, ig.VMType)
+		extensions = appendNew(extensions, ig.VMExtensions...)
+		diskTypes = appendNew(diskTypes, ig.PersistentDiskType)
+		for _, n := range ig.Networks {
+			networks = appendNew(networks, n.Name)
+		}
+	}
+	if len(azs) == 0 {
+		return nil, errs.Preconditionf("the manifest's instance groups have no AZs")
+	}
+	if len(networks) == 0 {
+		return nil, errs.Preconditionf("the manifest's instance groups have no networks")
+	}
+	if len(vmTypes) == 0 {
+		return nil, errs.Preconditionf("the manifest's instance groups have no vm types")
+	}
+
+	cc := &enaml.CloudConfigManifest{}
+	for _, az := range azs {
+		cc.AZs = append(cc.AZs, enaml.AZ{Name: az, CloudProperties: profile.AZ(az)})
+	}
+	for _, name := range vmTypes {
+		cc.VMTypes = append(cc.VMTypes, enaml.VMType{Name: name, CloudProperties: profile.VMType(name)})
+	}
+	for _, name := range extensions {
+		cc.VMExtensions = append(cc.VMExtensions, enaml.VMExtension{Name: name})
+	}
+	for _, name := range diskTypes {
+		size, err := strconv.Atoi(name)
+		if err != nil || size <= 0 {
+			size = DefaultDiskSize
+		}
+		cc.DiskTypes = append(cc.DiskTypes, enaml.DiskType{Name: name, DiskSize: size, CloudProperties: profile.DiskType(name)})
+	}
+
+	needs, err := subnetNeeds(dm)
+	if err != nil {
+		return nil, err
+	}
+	// the compilation workers run in the first AZ of the first network
+	compilation := subnetKey{networks[0], azs[0]}
+	if needs[compilation] == nil {
+		needs[compilation] = &need{}
+	}
+	needs[compilation].dynamic += opts.Workers
+
+	subnets, err := placeSubnets(networks, azs, needs, opts.CIDR)
+	if err != nil {
+		return nil, err
+	}
+	var nets []Network
+	for _, name := range networks {
+		n := Network{Name: name, Type: "manual"}
+		for _, az := range azs {
+			s, ok := subnets[subnetKey{name, az}]
+			if !ok {
+				continue
+			}
+			s.AZ = az
+			s.CloudProperties = profile.Subnet(name, az)
+			n.Subnets = append(n.Subnets, s)
+		}
+		nets = append(nets, n)
+	}
+	SetNetworks(cc, nets)
+
+	cc.Compilation = &enaml.Compilation{
+		Workers:             opts.Workers,
+		ReuseCompilationVMs: true,
+		AZ:                  azs[0],
+		VMType:              vmTypes[0],
+		Network:             networks[0],
+	}
+	return cc, nil
+}
+
+// appendNew appends the values that are neither empty nor already in
+// list.
+func appendNew(list []string, values ...string) []string {
+	for _, v := range values {
+		if v == "" {
+			continue
+		}
+		found := false
+		for _, e := range list {
+			if e == v {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+type subnetKey struct {
+	network, az string
+}
+
+// need is what a subnet must hold: the static IPs of the instances
+// placed in its AZ, and the number of instances that get dynamic IPs.
+type need struct {
+	static  []net.IP
+	dynamic int
+}
+
+// subnetNeeds returns what the subnet of each network in each AZ must
+// hold.  Instances are spread across the AZs of their instance group in
+// order, as BOSH places them, and static IPs are assigned to them in
+// the order they are listed.
+func subnetNeeds(dm *enaml.DeploymentManifest) (map[subnetKey]*need, error) {
+	needs := make(map[subnetKey]*need)
+	get := func(network, az string) *need {
+		k := subnetKey{network, az}
+		if needs[k] == nil {
+			needs[k] = &need{}
+		}
+		return needs[k]
+	}
+	for _, ig := range dm.InstanceGroups {
+		for _, n := range ig.Networks {
+			ips, err := ExpandIPs(n.StaticIPs)
+			if err != nil {
+				return nil, err
+			}
+			for i, az := range ig.AZs {
+				count := ig.Instances / len(ig.AZs)
+				if i < ig.Instances%len(ig.AZs) {
+					count++
+				}
+				nd := get(n.Name, az)
+				if len(ips) == 0 {
+					nd.dynamic += count
+					continue
+				}
+				if count > len(ips) {
+					count = len(ips)
+				}
+				nd.static = append(nd.static, ips[:count]...)
+				ips = ips[count:]
+			}
+		}
+	}
+	return needs, nil
+}
+
+// size returns the prefix length of the smallest subnet, no smaller
+// than a /24, with room for the gateway, four reserved addresses and
+// twice the addresses the subnet needs.
+func (n *need) size() int {
+	hosts := 2 * (len(n.static) + n.dynamic + 7)
+	prefix := 24
+	for prefix > 8 && 1<<uint(32-prefix) < hosts {
+		prefix--
+	}
+	return prefix
+}
+
+// placeSubnets returns the subnet of each network in each AZ that
+// instances are placed in.  Subnets with static IPs are the smallest
+// subnets of their size that hold those IPs, other subnets are
+// allocated from cidr.
+func placeSubnets(networks, azs []string, needs map[subnetKey]*need, cidr *net.IPNet) (map[subnetKey]Subnet, error) {
+	subnets := make(map[subnetKey]Subnet)
+	var used []IPRange
+
+	for _, network := range networks {
+		for _, az := range azs {
+			nd := needs[subnetKey{network, az}]
+			if nd == nil || len(nd.static) == 0 {
+				continue
+			}
+			sort.Slice(nd.static, func(i, j int) bool { return ipToInt(nd.static[i]) < ipToInt(nd.static[j]) })
+			prefix := nd.size()
+			block := blockOf(nd.static[0], prefix)
+			for !block.Contains(nd.static[len(nd.static)-1]) {
+				prefix--
+				block = blockOf(nd.static[0], prefix)
+			}
+			for _, u := range used {
+				if u.Overlaps(block) {
+					return nil, errs.Preconditionf("the static IPs of network %s in AZ %s overlap another subnet, %s", network, az, u)
+				}
+			}
+			s, ok := subnet(block, prefix, nd.static)
+			if !ok {
+				return nil, errs.Preconditionf("the static IPs of network %s in AZ %s leave no address in %s for the gateway", network, az, block)
+			}
+			used = append(used, block)
+			subnets[subnetKey{network, az}] = s
+		}
+	}
+
+	for _, network := range networks {
+		for _, az := range azs {
+			nd := needs[subnetKey{network, az}]
+			if nd == nil || len(nd.static) > 0 {
+				continue
+			}
+			prefix := nd.size()
+			block, ok := allocate(cidr, prefix, used)
+			if !ok {
+				return nil, errs.Preconditionf("there is no room for a /%d subnet for network %s in AZ %s in %s", prefix, network, az, cidr)
+			}
+			used = append(used, block)
+			subnets[subnetKey{network, az}], _ = subnet(block, prefix, nil)
+		}
+	}
+	return subnets, nil
+}
+
+// allocate returns the first subnet with the specified prefix length in
+// cidr that doesn't overlap any of the used ranges.
+func allocate(cidr *net.IPNet, prefix int, used []IPRange) (IPRange, bool) {
+	ones, _ := cidr.Mask.Size()
+	if prefix < ones {
+		return IPRange{}, false
+	}
+	space := blockOf(cidr.IP, ones)
+	step := uint32(1) << uint(32-prefix)
+	for start := ipToInt(space.First); start <= ipToInt(space.Last); start += step {
+		block := blockOf(intToIP(start), prefix)
+		free := true
+		for _, u := range used {
+			if u.Overlaps(block) {
+				free = false
+				break
+			}
+		}
+		if free {
+			return block, true
+		}
+		if start+step < start {
+			break
+		}
+	}
+	return IPRange{}, false
+}
+
+// subnet returns a subnet for block, with the first address after the
+// network address that isn't a static IP as its gateway, up to the next
+// four addresses reserved, and the static IPs, which must be sorted, as
+// its static ranges.  It returns false if the static IPs leave no
+// address for the gateway.
+func subnet(block IPRange, prefix int, static []net.IP) (Subnet, bool) {
+	gateway := IPAdd(block.First, 1)
+	for _, ip := range static {
+		if ip.Equal(gateway) {
+			gateway = IPAdd(gateway, 1)
+		}
+	}
+	if ipToInt(gateway) >= ipToInt(block.Last) {
+		return Subnet{}, false
+	}
+
+	s := Subnet{
+		Range:   block.First.String() + "/" + strconv.Itoa(prefix),
+		Gateway: gateway.String(),
+	}
+	reserved := IPRange{First: IPAdd(gateway, 1), Last: IPAdd(gateway, 4)}
+	if ipToInt(reserved.Last) >= ipToInt(block.Last) {
+		reserved.Last = IPAdd(block.Last, -1)
+	}
+	for _, ip := range static {
+		if ipToInt(ip) > ipToInt(gateway) && ipToInt(ip) <= ipToInt(reserved.Last) {
+			reserved.Last = IPAdd(ip, -1)
+			break
+		}
+	}
+	if ipToInt(reserved.First) <= ipToInt(reserved.Last) {
+		s.Reserved = []string{reserved.String()}
+	}
+	s.Static = collapse(static)
+	return s, true
+}
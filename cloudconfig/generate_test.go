@@ -0,0 +1,155 @@
+package cloudconfig
+
+import (
+	"io/ioutil"
+	"net"
+	"path/filepath"
+
+	"github.com/enaml-ops/enaml"
+	"github.com/enaml-ops/omg-transform/errs"
+	"github.com/enaml-ops/omg-transform/golden"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const generateManifest = `name: cf
+instance_groups:
+- name: router
+  instances: 3
+  azs: [z1, z2]
+  vm_type: small
+  vm_extensions: [public-lbs]
+  networks:
+  - name: cf
+    static_ips: [10.0.16.10-10.0.16.11, 10.0.20.10]
+- name: diego_cell
+  instances: 2
+  azs: [z1, z2]
+  vm_type: large
+  persistent_disk_type: "10240"
+  networks:
+  - name: cf
+  - name: isolated
+- name: mysql
+  instances: 1
+  azs: [z2]
+  vm_type: large
+  persistent_disk_type: fast
+  networks:
+  - name: cf
+`
+
+var _ = Describe("generating a cloud config", func() {
+	var dm *enaml.DeploymentManifest
+
+	BeforeEach(func() {
+		dm = enaml.NewDeploymentManifest([]byte(generateManifest))
+	})
+
+	generate := func(iaas string, opts GenerateOptions) *enaml.CloudConfigManifest {
+		profile, ok := LookupIaaSProfile(iaas)
+		Ω(ok).Should(BeTrue())
+		cc, err := Generate(dm, profile, opts)
+		Ω(err).ShouldNot(HaveOccurred())
+		return cc
+	}
+
+	It("includes everything the manifest refers to", func() {
+		cc := generate("aws", GenerateOptions{})
+		Ω(cc.AZs).Should(Equal([]enaml.AZ{
+			{Name: "z1", CloudProperties: props{"availability_zone": "z1"}},
+			{Name: "z2", CloudProperties: props{"availability_zone": "z2"}},
+		}))
+		Ω(cc.VMTypes).Should(HaveLen(2))
+		Ω(cc.VMTypes[1]).Should(Equal(enaml.VMType{Name: "large", CloudProperties: props{"instance_type": "large"}}))
+		Ω(cc.VMExtensions).Should(Equal([]enaml.VMExtension{{Name: "public-lbs"}}))
+		Ω(cc.DiskTypes).Should(Equal([]enaml.DiskType{
+			{Name: "10240", DiskSize: 10240, CloudProperties: props{"type": "gp2"}},
+			{Name: "fast", DiskSize: DefaultDiskSize, CloudProperties: props{"type": "gp2"}},
+		}))
+		Ω(*cc.Compilation).Should(Equal(enaml.Compilation{Workers: 4, ReuseCompilationVMs: true, AZ: "z1", VMType: "small", Network: "cf"}))
+	})
+
+	It("places subnets around static IPs and allocates the others", func() {
+		_, cidr, _ := net.ParseCIDR("10.1.0.0/16")
+		cc := generate("gcp", GenerateOptions{CIDR: cidr, Workers: 2})
+		networks, err := Networks(cc)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(networks).Should(HaveLen(2))
+
+		cf := networks[0]
+		Ω(cf.Type).Should(Equal("manual"))
+		Ω(cf.Subnets).Should(HaveLen(2))
+		Ω(cf.Subnets[0]).Should(Equal(Subnet{
+			Range:           "10.0.16.0/24",
+			Gateway:         "10.0.16.1",
+			Reserved:        []string{"10.0.16.2-10.0.16.5"},
+			Static:          []string{"10.0.16.10-10.0.16.11"},
+			AZ:              "z1",
+			CloudProperties: map[interface{}]interface{}{"network_name": Placeholder, "subnetwork_name": Placeholder},
+		}))
+		Ω(cf.Subnets[1].Range).Should(Equal("10.0.20.0/24"))
+		Ω(cf.Subnets[1].Static).Should(Equal([]string{"10.0.20.10"}))
+
+		isolated := networks[1]
+		Ω(isolated.Subnets).Should(HaveLen(2))
+		Ω(isolated.Subnets[0].Range).Should(Equal("10.1.0.0/24"))
+		Ω(isolated.Subnets[0].Static).Should(BeEmpty())
+		Ω(isolated.Subnets[1].Range).Should(Equal("10.1.1.0/24"))
+		Ω(isolated.Subnets[1].AZ).Should(Equal("z2"))
+	})
+
+	It("doesn't use a static IP as the gateway", func() {
+		dm.InstanceGroups[0].Networks[0].StaticIPs = []string{"10.0.16.1-10.0.16.2", "10.0.20.10"}
+		cc := generate("aws", GenerateOptions{})
+		networks, err := Networks(cc)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(networks[0].Subnets[0].Gateway).Should(Equal("10.0.16.3"))
+		Ω(networks[0].Subnets[0].Reserved).Should(Equal([]string{"10.0.16.4-10.0.16.7"}))
+		Ω(networks[0].Subnets[0].Static).Should(Equal([]string{"10.0.16.1-10.0.16.2"}))
+	})
+
+	It("doesn't place a subnet whose static IPs leave no room for the gateway", func() {
+		block := IPRange{First: net.ParseIP("10.0.16.0").To4(), Last: net.ParseIP("10.0.16.3").To4()}
+		_, ok := subnet(block, 30, []net.IP{net.ParseIP("10.0.16.1").To4(), net.ParseIP("10.0.16.2").To4()})
+		Ω(ok).Should(BeFalse())
+	})
+
+	It("sizes subnets for the instances placed in them", func() {
+		dm.InstanceGroups[1].Instances = 400
+		cc := generate("openstack", GenerateOptions{})
+		networks, err := Networks(cc)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(networks[1].Subnets[0].Range).Should(Equal("10.0.0.0/23"))
+		Ω(networks[1].Subnets[1].Range).Should(Equal("10.0.2.0/23"))
+	})
+
+	It("returns an error if subnets don't fit", func() {
+		_, cidr, _ := net.ParseCIDR("10.1.0.0/24")
+		profile, _ := LookupIaaSProfile("aws")
+		_, err := Generate(dm, profile, GenerateOptions{CIDR: cidr})
+		Ω(err).Should(MatchError("there is no room for a /24 subnet for network isolated in AZ z2 in 10.1.0.0/24"))
+		Ω(err.(*errs.PreconditionError).ErrorKind()).Should(Equal(errs.KindPrecondition))
+	})
+
+	It("returns an error for manifests without AZs", func() {
+		profile, _ := LookupIaaSProfile("aws")
+		_, err := Generate(&enaml.DeploymentManifest{}, profile, GenerateOptions{})
+		Ω(err).Should(MatchError("the manifest's instance groups have no AZs"))
+	})
+
+	It("lists the IaaS profiles", func() {
+		Ω(IaaSProfiles()).Should(Equal([]string{"aws", "azure", "gcp", "openstack", "vsphere"}))
+	})
+
+	It("generates a cloud config for the PCF manifest", func() {
+		b, err := ioutil.ReadFile("../manifest/fixtures/pcf-aws-1.8.00-build.373.yml")
+		Ω(err).ShouldNot(HaveOccurred())
+		dm = enaml.NewDeploymentManifest(b)
+		cc := generate("vsphere", GenerateOptions{})
+		out, err := yaml.Marshal(cc)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(golden.Check(filepath.Join("fixtures", "golden", "generate-pcf-vsphere.yml"), out, *update)).Should(Succeed())
+	})
+})
@@ -0,0 +1,95 @@
+package cloudconfig
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/enaml-ops/enaml"
+	"github.com/enaml-ops/omg-transform/golden"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	yaml "gopkg.in/yaml.v2"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in fixtures/golden")
+
+const awsCloudConfig = "fixtures/cloud-config-aws.yml"
+
+// goldenCase applies a transformation to an input cloud config and
+// compares the result with a golden file in fixtures/golden.  Golden
+// files ending in .diff hold the structural diff of the input and
+// output, any other golden file holds the output cloud config.
+type goldenCase struct {
+	Input     string   // the input cloud config, defaults to the AWS fixture
+	Transform string   // the name of a registered transformation
+	Args      []string // the transformation's arguments
+	Golden    string   // the golden file, relative to fixtures/golden
+
+	// Transformation is applied instead of a registered
+	// transformation when Transform is empty.
+	Transformation Transformation
+}
+
+func expectGolden(c goldenCase) {
+	input := c.Input
+	if input == "" {
+		input = awsCloudConfig
+	}
+	before, err := ioutil.ReadFile(input)
+	Ω(err).ShouldNot(HaveOccurred())
+
+	t := c.Transformation
+	if c.Transform != "" {
+		info, ok := LookupTransformation(c.Transform)
+		Ω(ok).Should(BeTrue(), "unknown transformation %q", c.Transform)
+		t, err = info.Builder(c.Args)
+		Ω(err).ShouldNot(HaveOccurred())
+	}
+
+	cc := enaml.NewCloudConfigManifest(before)
+	Ω(t.Apply(cc)).Should(Succeed())
+	after, err := yaml.Marshal(cc)
+	Ω(err).ShouldNot(HaveOccurred())
+
+	got := after
+	if strings.HasSuffix(c.Golden, ".diff") {
+		// diff against the round-tripped input so that only the
+		// transformation's changes show up
+		orig, err := yaml.Marshal(enaml.NewCloudConfigManifest(before))
+		Ω(err).ShouldNot(HaveOccurred())
+		lines, err := golden.Diff(orig, after)
+		Ω(err).ShouldNot(HaveOccurred())
+		got = []byte(strings.Join(append(lines, ""), "\n"))
+	}
+	Ω(golden.Check(filepath.Join("fixtures", "golden", c.Golden), got, *update)).Should(Succeed())
+}
+
+// transformationFunc adapts a function to the Transformation interface.
+type transformationFunc func(*enaml.CloudConfigManifest) error
+
+func (f transformationFunc) Apply(cc *enaml.CloudConfigManifest) error { return f(cc) }
+
+var _ = Describe("golden files", func() {
+	It("compares the structural diff of a transformation", func() {
+		expectGolden(goldenCase{
+			Transformation: transformationFunc(func(cc *enaml.CloudConfigManifest) error {
+				cc.VMTypes = cc.VMTypes[1:]
+				cc.AZs[0].Name = "us-west-1a"
+				return nil
+			}),
+			Golden: "example.diff",
+		})
+	})
+
+	It("compares the full output of a transformation", func() {
+		expectGolden(goldenCase{
+			Transformation: transformationFunc(func(cc *enaml.CloudConfigManifest) error {
+				cc.DiskTypes = nil
+				return nil
+			}),
+			Golden: "example.yml",
+		})
+	})
+})
@@ -0,0 +1,154 @@
+package cloudconfig
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// IPRange is an inclusive range of IPv4 addresses, as used in the
+// static and reserved sections of a subnet.
+type IPRange struct {
+	First net.IP
+	Last  net.IP
+}
+
+// ParseIPRange parses a single address ("10.0.0.5") or a range of
+// addresses ("10.0.0.5-10.0.0.10" or "10.0.0.5 - 10.0.0.10").
+func ParseIPRange(s string) (IPRange, error) {
+	parts := strings.Split(s, "-")
+	if len(parts) > 2 {
+		return IPRange{}, fmt.Errorf("invalid IP range %q", s)
+	}
+	var ips []net.IP
+	for _, p := range parts {
+		ip := net.ParseIP(strings.TrimSpace(p)).To4()
+		if ip == nil {
+			return IPRange{}, fmt.Errorf("%q is not a valid IPv4 address", strings.TrimSpace(p))
+		}
+		ips = append(ips, ip)
+	}
+	r := IPRange{First: ips[0], Last: ips[len(ips)-1]}
+	if ipToInt(r.First) > ipToInt(r.Last) {
+		return IPRange{}, fmt.Errorf("invalid IP range %q, first address is after last", s)
+	}
+	return r, nil
+}
+
+// ParseIPRanges parses each of the specified ranges.
+func ParseIPRanges(ranges []string) ([]IPRange, error) {
+	result := make([]IPRange, 0, len(ranges))
+	for _, s := range ranges {
+		r, err := ParseIPRange(s)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, r)
+	}
+	return result, nil
+}
+
+// Contains returns true if ip is part of the range.
+func (r IPRange) Contains(ip net.IP) bool {
+	ip = ip.To4()
+	if ip == nil {
+		return false
+	}
+	i := ipToInt(ip)
+	return i >= ipToInt(r.First) && i <= ipToInt(r.Last)
+}
+
+// Overlaps returns true if the two ranges have any addresses in common.
+func (r IPRange) Overlaps(other IPRange) bool {
+	return ipToInt(r.First) <= ipToInt(other.Last) && ipToInt(other.First) <= ipToInt(r.Last)
+}
+
+// Len returns the number of addresses in the range.
+func (r IPRange) Len() int {
+	return int(ipToInt(r.Last)-ipToInt(r.First)) + 1
+}
+
+// IPs returns every address in the range.
+func (r IPRange) IPs() []net.IP {
+	var ips []net.IP
+	for i := ipToInt(r.First); i <= ipToInt(r.Last); i++ {
+		ips = append(ips, intToIP(i))
+		if i == ^uint32(0) {
+			break
+		}
+	}
+	return ips
+}
+
+func (r IPRange) String() string {
+	if r.First.Equal(r.Last) {
+		return r.First.String()
+	}
+	return r.First.String() + "-" + r.Last.String()
+}
+
+// ExpandIPs returns every address in the specified ranges.
+func ExpandIPs(ranges []string) ([]net.IP, error) {
+	parsed, err := ParseIPRanges(ranges)
+	if err != nil {
+		return nil, err
+	}
+	var ips []net.IP
+	for _, r := range parsed {
+		ips = append(ips, r.IPs()...)
+	}
+	return ips, nil
+}
+
+// ParseCIDR parses a subnet range such as "10.0.16.0/24", which must
+// be an IPv4 network address, and returns its addresses and prefix
+// length.
+func ParseCIDR(s string) (IPRange, int, error) {
+	ip, network, err := net.ParseCIDR(s)
+	if err != nil || ip.To4() == nil {
+		return IPRange{}, 0, fmt.Errorf("%q is not a valid IPv4 range", s)
+	}
+	prefix, _ := network.Mask.Size()
+	if !ip.Equal(network.IP) {
+		return IPRange{}, 0, fmt.Errorf("%q is not a network address, did you mean %s/%d?", s, network.IP, prefix)
+	}
+	return blockOf(network.IP, prefix), prefix, nil
+}
+
+// blockOf returns the addresses of the subnet with the specified prefix
+// length that ip is part of.
+func blockOf(ip net.IP, prefix int) IPRange {
+	mask := ^uint32(0) << uint(32-prefix)
+	first := ipToInt(ip) & mask
+	return IPRange{First: intToIP(first), Last: intToIP(first | ^mask)}
+}
+
+// collapse returns sorted addresses as the fewest ranges.
+func collapse(ips []net.IP) []string {
+	var ranges []string
+	for i := 0; i < len(ips); {
+		j := i
+		for j+1 < len(ips) && ipToInt(ips[j+1]) == ipToInt(ips[j])+1 {
+			j++
+		}
+		ranges = append(ranges, IPRange{First: ips[i], Last: ips[j]}.String())
+		i = j + 1
+	}
+	return ranges
+}
+
+// IPAdd returns the address n addresses after ip.
+func IPAdd(ip net.IP, n int) net.IP {
+	return intToIP(uint32(int64(ipToInt(ip.To4())) + int64(n)))
+}
+
+func ipToInt(ip net.IP) uint32 {
+	return binary.BigEndian.Uint32(ip.To4())
+}
+
+func intToIP(i uint32) net.IP {
+	ip := make(net.IP, net.IPv4len)
+	binary.BigEndian.PutUint32(ip, i)
+	return ip
+}
@@ -0,0 +1,66 @@
+package cloudconfig
+
+import (
+	"net"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("IP ranges", func() {
+	It("parses a single address", func() {
+		r, err := ParseIPRange("10.0.0.5")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(r.Len()).Should(Equal(1))
+		Ω(r.String()).Should(Equal("10.0.0.5"))
+	})
+
+	It("parses a range of addresses", func() {
+		r, err := ParseIPRange("10.0.0.5 - 10.0.0.10")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(r.Len()).Should(Equal(6))
+		Ω(r.String()).Should(Equal("10.0.0.5-10.0.0.10"))
+		Ω(r.Contains(net.ParseIP("10.0.0.7"))).Should(BeTrue())
+		Ω(r.Contains(net.ParseIP("10.0.0.11"))).Should(BeFalse())
+	})
+
+	It("returns an error for invalid ranges", func() {
+		_, err := ParseIPRange("10.0.0.5-10.0.0.10-10.0.0.11")
+		Ω(err).Should(HaveOccurred())
+
+		_, err = ParseIPRange("10.0.0.X")
+		Ω(err).Should(HaveOccurred())
+
+		_, err = ParseIPRange("10.0.0.10-10.0.0.5")
+		Ω(err).Should(HaveOccurred())
+	})
+
+	It("detects overlapping ranges", func() {
+		a, _ := ParseIPRange("10.0.0.5-10.0.0.10")
+		b, _ := ParseIPRange("10.0.0.10-10.0.0.20")
+		c, _ := ParseIPRange("10.0.0.21")
+		Ω(a.Overlaps(b)).Should(BeTrue())
+		Ω(b.Overlaps(c)).Should(BeFalse())
+	})
+
+	It("expands ranges into addresses", func() {
+		ips, err := ExpandIPs([]string{"10.0.0.254-10.0.1.1", "10.0.2.1"})
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(ips).Should(HaveLen(5))
+		Ω(ips[2].String()).Should(Equal("10.0.1.0"))
+		Ω(IPAdd(ips[4], 2).String()).Should(Equal("10.0.2.3"))
+	})
+
+	It("parses subnet ranges", func() {
+		r, prefix, err := ParseCIDR("10.0.16.0/22")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(prefix).Should(Equal(22))
+		Ω(r.String()).Should(Equal("10.0.16.0-10.0.19.255"))
+
+		_, _, err = ParseCIDR("10.0.17.0/22")
+		Ω(err).Should(MatchError(`"10.0.17.0/22" is not a network address, did you mean 10.0.16.0/22?`))
+
+		_, _, err = ParseCIDR("fd00::/64")
+		Ω(err).Should(HaveOccurred())
+	})
+})
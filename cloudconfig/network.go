@@ -0,0 +1,82 @@
+package cloudconfig
+
+import (
+	"github.com/enaml-ops/enaml"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Network is a network defined in a cloud config.
+//
+// Network and Subnet keep the keys they don't model, such as a dynamic
+// network's dns or a subnet's name, in Extra, so that transformations
+// don't drop them.
+type Network struct {
+	Name            string                 `yaml:"name"`
+	Type            string                 `yaml:"type,omitempty"`
+	Subnets         []Subnet               `yaml:"subnets,omitempty"`
+	CloudProperties interface{}            `yaml:"cloud_properties,omitempty"`
+	Extra           map[string]interface{} `yaml:",inline"`
+}
+
+// Subnet is a subnet of a manual or dynamic network.
+type Subnet struct {
+	Range           string                 `yaml:"range,omitempty"`
+	Gateway         string                 `yaml:"gateway,omitempty"`
+	DNS             []string               `yaml:"dns,omitempty"`
+	Reserved        []string               `yaml:"reserved,omitempty"`
+	Static          []string               `yaml:"static,omitempty"`
+	AZ              string                 `yaml:"az,omitempty"`
+	AZs             []string               `yaml:"azs,omitempty"`
+	CloudProperties interface{}            `yaml:"cloud_properties,omitempty"`
+	Extra           map[string]interface{} `yaml:",inline"`
+}
+
+// InAZ returns true if the subnet is placed in the specified AZ.
+func (s *Subnet) InAZ(az string) bool {
+	if s.AZ == az {
+		return true
+	}
+	for _, a := range s.AZs {
+		if a == az {
+			return true
+		}
+	}
+	return false
+}
+
+// Networks returns the networks defined in a cloud config.
+//
+// enaml stores networks as untyped values, so they are converted
+// to Networks here.
+func Networks(cc *enaml.CloudConfigManifest) ([]Network, error) {
+	b, err := yaml.Marshal(cc.Networks)
+	if err != nil {
+		return nil, err
+	}
+	var networks []Network
+	err = yaml.Unmarshal(b, &networks)
+	return networks, err
+}
+
+// GetNetworkByName returns the network with the specified name,
+// or nil if the cloud config doesn't define it.
+func GetNetworkByName(cc *enaml.CloudConfigManifest, name string) (*Network, error) {
+	networks, err := Networks(cc)
+	if err != nil {
+		return nil, err
+	}
+	for i := range networks {
+		if networks[i].Name == name {
+			return &networks[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// SetNetworks replaces the networks defined in a cloud config.
+func SetNetworks(cc *enaml.CloudConfigManifest, networks []Network) {
+	cc.Networks = make([]interface{}, len(networks))
+	for i := range networks {
+		cc.Networks[i] = networks[i]
+	}
+}
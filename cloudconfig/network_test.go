@@ -0,0 +1,79 @@
+package cloudconfig
+
+import (
+	"io/ioutil"
+
+	"github.com/enaml-ops/enaml"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	yaml "gopkg.in/yaml.v2"
+)
+
+var _ = Describe("networks", func() {
+	var cc *enaml.CloudConfigManifest
+
+	BeforeEach(func() {
+		b, err := ioutil.ReadFile("fixtures/cloud-config-aws.yml")
+		Ω(err).ShouldNot(HaveOccurred())
+		cc = enaml.NewCloudConfigManifest(b)
+		Ω(cc).ShouldNot(BeNil())
+	})
+
+	It("reads the networks from a cloud config", func() {
+		networks, err := Networks(cc)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(networks).Should(HaveLen(1))
+		Ω(networks[0].Name).Should(Equal("cf"))
+		Ω(networks[0].Subnets).Should(HaveLen(2))
+		Ω(networks[0].Subnets[1].InAZ("us-west-1c")).Should(BeTrue())
+		Ω(networks[0].Subnets[1].Static).Should(Equal([]string{"10.0.4.6-10.0.4.30"}))
+	})
+
+	It("looks up networks by name", func() {
+		n, err := GetNetworkByName(cc, "cf")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(n).ShouldNot(BeNil())
+
+		n, err = GetNetworkByName(cc, "unknown")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(n).Should(BeNil())
+	})
+
+	It("replaces the networks in a cloud config", func() {
+		networks, err := Networks(cc)
+		Ω(err).ShouldNot(HaveOccurred())
+		networks[0].Name = "default"
+		SetNetworks(cc, networks)
+
+		n, err := GetNetworkByName(cc, "default")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(n).ShouldNot(BeNil())
+		Ω(n.Subnets).Should(HaveLen(2))
+	})
+
+	It("keeps the keys it doesn't model", func() {
+		const networks = `networks:
+- name: default
+  type: dynamic
+  dns: [8.8.8.8]
+  subnet: subnet-a1b2c3d4
+- name: cf
+  type: manual
+  subnets:
+  - name: cf-1
+    range: 10.0.0.0/22
+    gateway: 10.0.0.1
+    managed: true
+    az: us-west-1b
+`
+		cc := enaml.NewCloudConfigManifest([]byte(networks))
+		Ω(cc).ShouldNot(BeNil())
+		n, err := Networks(cc)
+		Ω(err).ShouldNot(HaveOccurred())
+		SetNetworks(cc, n)
+
+		b, err := yaml.Marshal(cc)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(b).Should(MatchYAML(networks))
+	})
+})
@@ -0,0 +1,16 @@
+package cloudconfig
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("transformation registry", func() {
+	It("looks up builders of cloud config transformations", func() {
+		info, ok := LookupTransformation("add-subnet")
+		Ω(ok).Should(BeTrue())
+		t, err := info.Builder([]string{"-network", "cf", "-range", "10.0.8.0/22", "-az", "z3"})
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(t).Should(BeAssignableToTypeOf(&SubnetAdder{}))
+	})
+})
@@ -0,0 +1,107 @@
+package cloudconfig
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+
+	"github.com/enaml-ops/enaml"
+	"github.com/enaml-ops/omg-transform/errs"
+	"github.com/enaml-ops/omg-transform/registry"
+	"github.com/enaml-ops/omg-transform/script"
+	"go.starlark.net/starlark"
+)
+
+// scriptGlobals are the values that cloud config scripts can use,
+// besides the Starlark built-ins.
+//
+//	cloud_config   the cloud config, as a dict
+//	network(name)  the network dict with that name
+//	az(name)       the AZ dict with that name
+//	vm_type(name)  the vm type dict with that name
+var scriptGlobals = []string{"cloud_config", "network", "az", "vm_type"}
+
+// Script is a transformation that runs a Starlark script, which edits
+// the cloud config in place.
+type Script struct {
+	File     string // the script file
+	Source   string // the script, if File isn't set
+	MaxSteps uint64 // the execution steps after which the script is stopped
+	prog     *script.Program
+}
+
+func (s *Script) Apply(cc *enaml.CloudConfigManifest) error {
+	data, err := script.Data(cc)
+	if err != nil {
+		return err
+	}
+	err = s.prog.Run(starlark.StringDict{
+		"cloud_config": data,
+		"network": script.Lookup("network", data, "networks", func(name string) error {
+			return CheckNetwork(cc, name)
+		}),
+		"az": script.Lookup("az", data, "azs", func(name string) error {
+			return CheckAZ(cc, name)
+		}),
+		"vm_type": script.Lookup("vm_type", data, "vm_types", func(name string) error {
+			return CheckVMType(cc, name)
+		}),
+	}, script.Options{MaxSteps: s.MaxSteps, Print: os.Stderr})
+	if err != nil {
+		return err
+	}
+
+	b, err := script.Marshal(data)
+	if err != nil {
+		return errs.Preconditionf("the script left an invalid cloud config: %v", err)
+	}
+	out := enaml.NewCloudConfigManifest(b)
+	if out == nil {
+		return errs.Preconditionf("the script left an invalid cloud config")
+	}
+	*cc = *out
+	return nil
+}
+
+func (s *Script) flagSet() *flag.FlagSet {
+	fs := registry.NewFlagSet("script")
+	fs.StringVar(&s.File, "file", "", "the Starlark script to run")
+	fs.StringVar(&s.Source, "e", "", "the script to run, instead of a file")
+	fs.Uint64Var(&s.MaxSteps, "max-steps", script.DefaultMaxSteps, "stop the script after this many execution steps")
+	return fs
+}
+
+func ScriptTransformation(args []string) (Transformation, error) {
+	s := &Script{}
+	fs := s.flagSet()
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, errs.InvalidArgs("unexpected arguments %v", fs.Args())
+	}
+
+	src, filename, err := scriptSource(s.File, s.Source)
+	if err != nil {
+		return nil, err
+	}
+	if s.prog, err = script.Compile(filename, src, scriptGlobals); err != nil {
+		return nil, errs.InvalidArgs("%v", err)
+	}
+	return s, nil
+}
+
+// scriptSource returns the script given with -file or -e, and the name
+// used for it in errors.
+func scriptSource(file, source string) ([]byte, string, error) {
+	switch {
+	case file != "" && source != "":
+		return nil, "", errs.InvalidArgs("only one of -file and -e can be given")
+	case file != "":
+		src, err := ioutil.ReadFile(file)
+		return src, file, err
+	case source != "":
+		return []byte(source), "<script>", nil
+	}
+	return nil, "", errs.InvalidFlag("file", "missing required flag -file or -e")
+}
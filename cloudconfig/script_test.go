@@ -0,0 +1,46 @@
+package cloudconfig
+
+import (
+	"errors"
+	"io/ioutil"
+
+	"github.com/enaml-ops/enaml"
+	"github.com/enaml-ops/omg-transform/errs"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("script", func() {
+	It("requires a script", func() {
+		_, err := ScriptTransformation(nil)
+		Ω(err).Should(MatchError("missing required flag -file or -e"))
+	})
+
+	It("edits the cloud config", func() {
+		expectGolden(goldenCase{
+			Transform: "script",
+			Args:      []string{"-file", "fixtures/scripts/add-vm-types.star"},
+			Golden:    "script-add-vm-types.diff",
+		})
+	})
+
+	It("reports unknown names at the line that looked them up", func() {
+		b, err := ioutil.ReadFile(awsCloudConfig)
+		Ω(err).ShouldNot(HaveOccurred())
+		cc := enaml.NewCloudConfigManifest(b)
+
+		for src, want := range map[string]string{
+			`network("cf2")`:     "<script>:2:8: network cf2 is not defined in the cloud config, did you mean cf?",
+			`az("us-west-1d")`:   "<script>:2:3: az us-west-1d is not defined in the cloud config",
+			`vm_type("t2.mini")`: "<script>:2:8: vm type t2.mini is not defined in the cloud config",
+		} {
+			t, err := ScriptTransformation([]string{"-e", "x = 1\n" + src})
+			Ω(err).ShouldNot(HaveOccurred())
+			err = t.Apply(cc)
+			Ω(err).Should(MatchError(HavePrefix(want)), src)
+			var k interface{ ErrorKind() string }
+			Ω(errors.As(err, &k)).Should(BeTrue(), src)
+			Ω(k.ErrorKind()).Should(Equal(errs.KindNotFound))
+		}
+	})
+})
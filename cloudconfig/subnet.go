@@ -0,0 +1,343 @@
+package cloudconfig
+
+import (
+	"flag"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/enaml-ops/enaml"
+	"github.com/enaml-ops/omg-transform/errs"
+	"github.com/enaml-ops/omg-transform/registry"
+)
+
+// DefaultDNS are the DNS servers of added subnets whose network has no
+// other subnet to copy them from.
+var DefaultDNS = []string{"8.8.8.8"}
+
+// DefaultReserved is the number of addresses reserved after the gateway
+// of added subnets, for the IaaS's own infrastructure.
+const DefaultReserved = 4
+
+// SubnetAdder is a transformation that adds a subnet to a manual network.
+// The first address of the range after the network address is its
+// gateway, the next Reserved addresses are reserved, and the Static
+// addresses after those are static.
+type SubnetAdder struct {
+	Network  string
+	Range    string
+	AZ       string
+	Reserved int
+	Static   int
+
+	// DNS defaults to the DNS servers of the network's other subnets,
+	// or to DefaultDNS.
+	DNS []string
+
+	// Create adds the network if the cloud config doesn't define it.
+	Create bool
+
+	dnsFlag string
+}
+
+func (a *SubnetAdder) Apply(cc *enaml.CloudConfigManifest) error {
+	if err := CheckAZ(cc, a.AZ); err != nil {
+		return err
+	}
+	block, prefix, err := ParseCIDR(a.Range)
+	if err != nil {
+		return errs.InvalidFlag("range", "%v", err)
+	}
+
+	networks, err := Networks(cc)
+	if err != nil {
+		return err
+	}
+	n := networkIndex(networks, a.Network)
+	if n < 0 {
+		if !a.Create {
+			return CheckNetwork(cc, a.Network)
+		}
+		networks = append(networks, Network{Name: a.Network, Type: "manual"})
+		n = len(networks) - 1
+	}
+	if t := networks[n].Type; t != "" && t != "manual" {
+		return errs.Preconditionf("network %s is a %s network, subnets can only be added to manual networks", a.Network, t)
+	}
+	if err := checkOverlap(networks, a.Range, block, -1, -1); err != nil {
+		return err
+	}
+
+	gateway := IPAdd(block.First, 1)
+	reserved := IPRange{First: IPAdd(gateway, 1), Last: IPAdd(gateway, a.Reserved)}
+	static := IPRange{First: IPAdd(reserved.Last, 1), Last: IPAdd(reserved.Last, a.Static)}
+	if room := block.Len() - 3 - a.Reserved; a.Static > room {
+		if room < 0 {
+			room = 0
+		}
+		return errs.Preconditionf("a /%d subnet has room for %d static IPs after %d reserved IPs, not %d", prefix, room, a.Reserved, a.Static)
+	}
+
+	s := Subnet{
+		Range:   a.Range,
+		Gateway: gateway.String(),
+		DNS:     a.DNS,
+		AZ:      a.AZ,
+	}
+	if a.Reserved > 0 {
+		s.Reserved = []string{reserved.String()}
+	}
+	if a.Static > 0 {
+		s.Static = []string{static.String()}
+	}
+	if s.DNS == nil {
+		s.DNS = DefaultDNS
+		for _, other := range networks[n].Subnets {
+			if len(other.DNS) > 0 {
+				s.DNS = other.DNS
+				break
+			}
+		}
+	}
+	networks[n].Subnets = append(networks[n].Subnets, s)
+	SetNetworks(cc, networks)
+	return nil
+}
+
+func (a *SubnetAdder) flagSet() *flag.FlagSet {
+	fs := registry.NewFlagSet("add-subnet")
+	fs.StringVar(&a.Network, "network", "", "the network to add the subnet to")
+	fs.StringVar(&a.Range, "range", "", "the subnet's range, such as 10.0.16.0/24")
+	fs.StringVar(&a.AZ, "az", "", "the AZ the subnet is placed in")
+	fs.IntVar(&a.Reserved, "reserved", DefaultReserved, "the number of addresses reserved after the gateway")
+	fs.IntVar(&a.Static, "static", 0, "the number of static addresses after the reserved ones")
+	fs.StringVar(&a.dnsFlag, "dns", "", "a comma separated list of DNS servers, defaults to those of the network's other subnets")
+	fs.BoolVar(&a.Create, "create", false, "create the network if it doesn't exist")
+	return fs
+}
+
+// AddSubnetTransformation is a TransformationBuilder that builds the
+// 'add-subnet' transformation.
+func AddSubnetTransformation(args []string) (Transformation, error) {
+	a := &SubnetAdder{}
+	fs := a.flagSet()
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, errs.InvalidArgs("unexpected arguments %v", fs.Args())
+	}
+
+	for _, f := range []struct{ name, value string }{
+		{"network", a.Network}, {"range", a.Range}, {"az", a.AZ},
+	} {
+		if f.value == "" {
+			return nil, errs.MissingFlag(f.name)
+		}
+	}
+	if _, _, err := ParseCIDR(a.Range); err != nil {
+		return nil, errs.InvalidFlag("range", "%v", err)
+	}
+	if a.Reserved < 0 {
+		return nil, errs.InvalidFlag("reserved", "invalid number of reserved addresses %d, must be 0 or more", a.Reserved)
+	}
+	if a.Static < 0 {
+		return nil, errs.InvalidFlag("static", "invalid number of static addresses %d, must be 0 or more", a.Static)
+	}
+	if a.dnsFlag != "" {
+		for _, s := range strings.Split(a.dnsFlag, ",") {
+			if net.ParseIP(s) == nil {
+				return nil, errs.InvalidFlag("dns", "%q is not a valid IP address", s)
+			}
+			a.DNS = append(a.DNS, s)
+		}
+	}
+	return a, nil
+}
+
+// StaticResizer is a transformation that grows or shrinks the static
+// addresses of a subnet to Size addresses.  Addresses are added after
+// the last static address, skipping the gateway and reserved addresses,
+// and removed from the end.
+type StaticResizer struct {
+	Network string
+
+	// AZ and Range select the network's subnet, and can be left empty
+	// if only one subnet matches.
+	AZ    string
+	Range string
+
+	Size int
+}
+
+func (r *StaticResizer) Apply(cc *enaml.CloudConfigManifest) error {
+	networks, err := Networks(cc)
+	if err != nil {
+		return err
+	}
+	n := networkIndex(networks, r.Network)
+	if n < 0 {
+		return CheckNetwork(cc, r.Network)
+	}
+	i, err := r.subnet(networks[n])
+	if err != nil {
+		return err
+	}
+	s := &networks[n].Subnets[i]
+	block, _, err := ParseCIDR(s.Range)
+	if err != nil {
+		return errs.Preconditionf("subnet %s of network %s has an invalid range: %v", s.Range, r.Network, err)
+	}
+	if err := checkOverlap(networks, s.Range, block, n, i); err != nil {
+		return err
+	}
+	reserved, err := ParseIPRanges(s.Reserved)
+	if err != nil {
+		return errs.Preconditionf("subnet %s of network %s has invalid reserved IPs: %v", s.Range, r.Network, err)
+	}
+	ips, err := ExpandIPs(s.Static)
+	if err != nil {
+		return errs.Preconditionf("subnet %s of network %s has invalid static IPs: %v", s.Range, r.Network, err)
+	}
+	sort.Slice(ips, func(i, j int) bool { return ipToInt(ips[i]) < ipToInt(ips[j]) })
+
+	// unavailable returns why ip can't be a static address, or "" if
+	// it can.
+	unavailable := func(ip net.IP) string {
+		switch {
+		case !block.Contains(ip):
+			return "outside the range"
+		case ip.Equal(block.First):
+			return "the network address"
+		case ip.Equal(block.Last):
+			return "the broadcast address"
+		case ip.Equal(net.ParseIP(s.Gateway)):
+			return "the gateway"
+		}
+		for _, res := range reserved {
+			if res.Contains(ip) {
+				return "reserved"
+			}
+		}
+		return ""
+	}
+
+	if r.Size < len(ips) {
+		ips = ips[:r.Size]
+	} else {
+		next := IPAdd(block.First, 1)
+		if len(ips) > 0 {
+			next = IPAdd(ips[len(ips)-1], 1)
+		}
+		for len(ips) < r.Size {
+			if !block.Contains(next) || next.Equal(block.Last) {
+				return errs.Preconditionf("subnet %s of network %s has room for %d static IPs, not %d", s.Range, r.Network, len(ips), r.Size)
+			}
+			if unavailable(next) == "" {
+				ips = append(ips, next)
+			}
+			next = IPAdd(next, 1)
+		}
+	}
+	for _, ip := range ips {
+		if why := unavailable(ip); why != "" {
+			return errs.Preconditionf("static IP %s of subnet %s of network %s is %s", ip, s.Range, r.Network, why)
+		}
+	}
+
+	s.Static = collapse(ips)
+	SetNetworks(cc, networks)
+	return nil
+}
+
+// subnet returns the index of the subnet of n selected by the AZ and
+// range.
+func (r *StaticResizer) subnet(n Network) (int, error) {
+	var matches []int
+	for i := range n.Subnets {
+		s := &n.Subnets[i]
+		if (r.AZ == "" || s.InAZ(r.AZ)) && (r.Range == "" || s.Range == r.Range) {
+			matches = append(matches, i)
+		}
+	}
+
+	var where []string
+	if r.AZ != "" {
+		where = append(where, "in AZ "+r.AZ)
+	}
+	if r.Range != "" {
+		where = append(where, "with range "+r.Range)
+	}
+	switch {
+	case len(matches) == 0:
+		return 0, errs.Preconditionf("network %s has no subnet %s", n.Name, strings.Join(where, " "))
+	case len(matches) > 1 && r.AZ == "":
+		return 0, errs.InvalidArgs("network %s has %d subnets, select one with -az or -range", n.Name, len(matches))
+	case len(matches) > 1:
+		return 0, errs.InvalidFlag("range", "network %s has %d subnets %s, select one with -range", n.Name, len(matches), strings.Join(where, " "))
+	}
+	return matches[0], nil
+}
+
+func (r *StaticResizer) flagSet() *flag.FlagSet {
+	fs := registry.NewFlagSet("resize-static")
+	fs.StringVar(&r.Network, "network", "", "the network of the subnet")
+	fs.StringVar(&r.AZ, "az", "", "the AZ of the subnet")
+	fs.StringVar(&r.Range, "range", "", "the range of the subnet, if the network has several in the AZ")
+	fs.IntVar(&r.Size, "size", -1, "the number of static addresses")
+	return fs
+}
+
+// ResizeStaticTransformation is a TransformationBuilder that builds the
+// 'resize-static' transformation.
+func ResizeStaticTransformation(args []string) (Transformation, error) {
+	r := &StaticResizer{}
+	fs := r.flagSet()
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, errs.InvalidArgs("unexpected arguments %v", fs.Args())
+	}
+
+	if r.Network == "" {
+		return nil, errs.MissingFlag("network")
+	}
+	if r.Size == -1 {
+		return nil, errs.MissingFlag("size")
+	}
+	if r.Size < 0 {
+		return nil, errs.InvalidFlag("size", "invalid number of static addresses %d, must be 0 or more", r.Size)
+	}
+	return r, nil
+}
+
+// networkIndex returns the index of the named network, or -1.
+func networkIndex(networks []Network, name string) int {
+	for i := range networks {
+		if networks[i].Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// checkOverlap returns an error if rng, whose addresses are block,
+// overlaps the range of any subnet other than subnet i of network n.
+func checkOverlap(networks []Network, rng string, block IPRange, n, i int) error {
+	for ni, network := range networks {
+		for si, s := range network.Subnets {
+			if (ni == n && si == i) || s.Range == "" {
+				continue
+			}
+			other, _, err := ParseCIDR(s.Range)
+			if err != nil {
+				return errs.Preconditionf("subnet %s of network %s has an invalid range: %v", s.Range, network.Name, err)
+			}
+			if block.Overlaps(other) {
+				return errs.Preconditionf("range %s overlaps subnet %s of network %s", rng, s.Range, network.Name)
+			}
+		}
+	}
+	return nil
+}
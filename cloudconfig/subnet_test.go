@@ -0,0 +1,177 @@
+package cloudconfig
+
+import (
+	"io/ioutil"
+
+	"github.com/enaml-ops/enaml"
+	"github.com/enaml-ops/omg-transform/errs"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("subnets", func() {
+	var cc *enaml.CloudConfigManifest
+
+	BeforeEach(func() {
+		b, err := ioutil.ReadFile(awsCloudConfig)
+		Ω(err).ShouldNot(HaveOccurred())
+		cc = enaml.NewCloudConfigManifest(b)
+	})
+
+	subnets := func(network string) []Subnet {
+		n, err := GetNetworkByName(cc, network)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(n).ShouldNot(BeNil())
+		return n.Subnets
+	}
+
+	Describe("add-subnet", func() {
+		apply := func(args ...string) error {
+			t, err := AddSubnetTransformation(args)
+			Ω(err).ShouldNot(HaveOccurred())
+			return t.Apply(cc)
+		}
+
+		It("adds a subnet", func() {
+			expectGolden(goldenCase{
+				Transform: "add-subnet",
+				Args:      []string{"-network", "cf", "-range", "10.0.8.0/22", "-az", "us-west-1b", "-static", "32"},
+				Golden:    "add-subnet.diff",
+			})
+		})
+
+		It("computes the gateway, reserved and static addresses", func() {
+			Ω(apply("-network", "cf", "-range", "10.0.16.0/28", "-az", "us-west-1c", "-reserved", "2", "-static", "11", "-dns", "10.0.0.2,10.0.0.3")).Should(Succeed())
+			s := subnets("cf")
+			Ω(s).Should(HaveLen(3))
+			Ω(s[2]).Should(Equal(Subnet{
+				Range:    "10.0.16.0/28",
+				Gateway:  "10.0.16.1",
+				DNS:      []string{"10.0.0.2", "10.0.0.3"},
+				Reserved: []string{"10.0.16.2-10.0.16.3"},
+				Static:   []string{"10.0.16.4-10.0.16.14"},
+				AZ:       "us-west-1c",
+			}))
+		})
+
+		It("creates the network", func() {
+			Ω(apply("-network", "services", "-range", "10.0.16.0/24", "-az", "us-west-1c", "-create")).Should(Succeed())
+			n, err := GetNetworkByName(cc, "services")
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(n.Type).Should(Equal("manual"))
+			Ω(n.Subnets).Should(Equal([]Subnet{{
+				Range:    "10.0.16.0/24",
+				Gateway:  "10.0.16.1",
+				DNS:      DefaultDNS,
+				Reserved: []string{"10.0.16.2-10.0.16.5"},
+				AZ:       "us-west-1c",
+			}}))
+		})
+
+		It("returns an error if the network doesn't exist", func() {
+			err := apply("-network", "services", "-range", "10.0.16.0/24", "-az", "us-west-1c")
+			Ω(err).Should(BeAssignableToTypeOf(&ErrNetworkNotFound{}))
+		})
+
+		It("returns an error if the AZ doesn't exist", func() {
+			err := apply("-network", "cf", "-range", "10.0.16.0/24", "-az", "us-west-1d")
+			Ω(err).Should(BeAssignableToTypeOf(&ErrAZNotFound{}))
+		})
+
+		It("returns an error if the range overlaps another subnet", func() {
+			err := apply("-network", "cf", "-range", "10.0.0.0/16", "-az", "us-west-1b")
+			Ω(err).Should(MatchError("range 10.0.0.0/16 overlaps subnet 10.0.0.0/22 of network cf"))
+			Ω(err.(*errs.PreconditionError).ErrorKind()).Should(Equal(errs.KindPrecondition))
+		})
+
+		It("returns an error if the static addresses don't fit", func() {
+			err := apply("-network", "cf", "-range", "10.0.16.0/28", "-az", "us-west-1b", "-static", "10")
+			Ω(err).Should(MatchError("a /28 subnet has room for 9 static IPs after 4 reserved IPs, not 10"))
+		})
+
+		It("validates its flags", func() {
+			for want, args := range map[string][]string{
+				"missing required flag -range":                                        {"-network", "cf", "-az", "z1"},
+				`"10.0.16.1/24" is not a network address, did you mean 10.0.16.0/24?`: {"-network", "cf", "-az", "z1", "-range", "10.0.16.1/24"},
+				"invalid number of static addresses -1, must be 0 or more":            {"-network", "cf", "-az", "z1", "-range", "10.0.16.0/24", "-static", "-1"},
+				`"dns" is not a valid IP address`:                                     {"-network", "cf", "-az", "z1", "-range", "10.0.16.0/24", "-dns", "dns"},
+			} {
+				_, err := AddSubnetTransformation(args)
+				Ω(err).Should(MatchError(want))
+				Ω(err.(*errs.ArgumentError).ErrorKind()).Should(Equal(errs.KindArgument))
+			}
+		})
+	})
+
+	Describe("resize-static", func() {
+		apply := func(args ...string) error {
+			t, err := ResizeStaticTransformation(args)
+			Ω(err).ShouldNot(HaveOccurred())
+			return t.Apply(cc)
+		}
+
+		It("grows the static range", func() {
+			expectGolden(goldenCase{
+				Transform: "resize-static",
+				Args:      []string{"-network", "cf", "-az", "us-west-1c", "-size", "50"},
+				Golden:    "resize-static-grow.diff",
+			})
+		})
+
+		It("shrinks the static range", func() {
+			Ω(apply("-network", "cf", "-az", "us-west-1b", "-size", "5")).Should(Succeed())
+			Ω(subnets("cf")[0].Static).Should(Equal([]string{"10.0.0.6-10.0.0.10"}))
+			Ω(subnets("cf")[1].Static).Should(Equal([]string{"10.0.4.6-10.0.4.30"}))
+
+			Ω(apply("-network", "cf", "-range", "10.0.4.0/22", "-size", "0")).Should(Succeed())
+			Ω(subnets("cf")[1].Static).Should(BeEmpty())
+		})
+
+		It("skips the gateway and reserved addresses", func() {
+			n, _ := GetNetworkByName(cc, "cf")
+			n.Subnets[0].Static = nil
+			n.Subnets[0].Reserved = []string{"10.0.0.2-10.0.0.3", "10.0.0.5"}
+			SetNetworks(cc, []Network{*n})
+
+			Ω(apply("-network", "cf", "-az", "us-west-1b", "-size", "4")).Should(Succeed())
+			Ω(subnets("cf")[0].Static).Should(Equal([]string{"10.0.0.4", "10.0.0.6-10.0.0.8"}))
+		})
+
+		It("returns an error if the static range doesn't fit", func() {
+			err := apply("-network", "cf", "-az", "us-west-1b", "-size", "1020")
+			Ω(err).Should(MatchError("subnet 10.0.0.0/22 of network cf has room for 1017 static IPs, not 1020"))
+			Ω(err.(*errs.PreconditionError).ErrorKind()).Should(Equal(errs.KindPrecondition))
+		})
+
+		It("returns an error if static addresses are reserved", func() {
+			n, _ := GetNetworkByName(cc, "cf")
+			n.Subnets[0].Reserved = []string{"10.0.0.1-10.0.0.10"}
+			SetNetworks(cc, []Network{*n})
+
+			err := apply("-network", "cf", "-az", "us-west-1b", "-size", "30")
+			Ω(err).Should(MatchError("static IP 10.0.0.6 of subnet 10.0.0.0/22 of network cf is reserved"))
+		})
+
+		It("returns an error if the subnet overlaps another", func() {
+			n, _ := GetNetworkByName(cc, "cf")
+			n.Subnets[1].Range = "10.0.0.0/24"
+			SetNetworks(cc, []Network{*n})
+
+			err := apply("-network", "cf", "-az", "us-west-1b", "-size", "30")
+			Ω(err).Should(MatchError("range 10.0.0.0/22 overlaps subnet 10.0.0.0/24 of network cf"))
+		})
+
+		It("requires the subnet to be selected", func() {
+			err := apply("-network", "cf", "-size", "30")
+			Ω(err).Should(MatchError("network cf has 2 subnets, select one with -az or -range"))
+
+			err = apply("-network", "cf", "-az", "us-west-1c", "-range", "10.0.0.0/22", "-size", "30")
+			Ω(err).Should(MatchError("network cf has no subnet in AZ us-west-1c with range 10.0.0.0/22"))
+		})
+
+		It("requires a size", func() {
+			_, err := ResizeStaticTransformation([]string{"-network", "cf"})
+			Ω(err).Should(MatchError("missing required flag -size"))
+		})
+	})
+})
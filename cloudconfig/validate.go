@@ -0,0 +1,193 @@
+package cloudconfig
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/enaml-ops/enaml"
+)
+
+// Problem is an inconsistency found in a cloud config by Validate.
+type Problem struct {
+	// Path is the YAML path of the value at fault, such as
+	// networks[name=cf].subnets[0].gateway.  Named entries are
+	// identified by name when it is unique, and by index otherwise.
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (p Problem) String() string {
+	return p.Path + ": " + p.Message
+}
+
+// Validate checks that a cloud config is consistent: names are unique
+// within each section, subnets don't overlap, gateways, static and
+// reserved ranges are inside their subnet's range, and the AZs, networks
+// and vm types that subnets and the compilation block refer to are
+// defined.  Problems are returned in the order they appear in the cloud
+// config.
+func Validate(cc *enaml.CloudConfigManifest) ([]Problem, error) {
+	networks, err := Networks(cc)
+	if err != nil {
+		return nil, err
+	}
+	v := &validator{}
+
+	var names []string
+	for _, az := range cc.AZs {
+		names = append(names, az.Name)
+	}
+	v.names("azs", "az", names)
+	names = nil
+	for _, vt := range cc.VMTypes {
+		names = append(names, vt.Name)
+	}
+	v.names("vm_types", "vm type", names)
+	names = nil
+	for _, e := range cc.VMExtensions {
+		names = append(names, e.Name)
+	}
+	v.names("vm_extensions", "vm extension", names)
+	names = nil
+	for _, dt := range cc.DiskTypes {
+		names = append(names, dt.Name)
+	}
+	v.names("disk_types", "disk type", names)
+	names = nil
+	for _, n := range networks {
+		names = append(names, n.Name)
+	}
+	networkPaths := v.names("networks", "network", names)
+
+	// the ranges of the subnets seen so far, to find overlaps
+	type subnetRange struct {
+		path  string
+		rng   string
+		block IPRange
+	}
+	var ranges []subnetRange
+
+	for i, n := range networks {
+		for j, s := range n.Subnets {
+			path := fmt.Sprintf("%s.subnets[%d]", networkPaths[i], j)
+			if s.AZ != "" {
+				v.check(path+".az", CheckAZ(cc, s.AZ))
+			}
+			for k, az := range s.AZs {
+				v.check(fmt.Sprintf("%s.azs[%d]", path, k), CheckAZ(cc, az))
+			}
+			if s.Range == "" {
+				continue
+			}
+
+			block, _, err := ParseCIDR(s.Range)
+			if err != nil {
+				v.add(path+".range", "%v", err)
+				continue
+			}
+			for _, other := range ranges {
+				if block.Overlaps(other.block) {
+					v.add(path+".range", "range %s overlaps %s (%s)", s.Range, other.path, other.rng)
+				}
+			}
+			ranges = append(ranges, subnetRange{path, s.Range, block})
+
+			if s.Gateway != "" {
+				gateway := net.ParseIP(s.Gateway)
+				switch {
+				case gateway == nil || gateway.To4() == nil:
+					v.add(path+".gateway", "%q is not a valid IPv4 address", s.Gateway)
+				case !block.Contains(gateway):
+					v.add(path+".gateway", "gateway %s is outside the range %s", s.Gateway, s.Range)
+				}
+			}
+
+			var reserved []IPRange
+			for k, res := range s.Reserved {
+				if r, ok := v.inRange(fmt.Sprintf("%s.reserved[%d]", path, k), res, s.Range, block); ok {
+					reserved = append(reserved, r)
+				}
+			}
+			for k, static := range s.Static {
+				staticPath := fmt.Sprintf("%s.static[%d]", path, k)
+				r, ok := v.inRange(staticPath, static, s.Range, block)
+				if !ok {
+					continue
+				}
+				for _, res := range reserved {
+					if r.Overlaps(res) {
+						v.add(staticPath, "static range %s overlaps reserved range %s", static, res)
+					}
+				}
+			}
+		}
+	}
+
+	if c := cc.Compilation; c != nil {
+		if c.AZ != "" {
+			v.check("compilation.az", CheckAZ(cc, c.AZ))
+		}
+		if c.Network != "" {
+			v.check("compilation.network", CheckNetwork(cc, c.Network))
+		}
+		if c.VMType != "" {
+			v.check("compilation.vm_type", CheckVMType(cc, c.VMType))
+		}
+	}
+	return v.problems, nil
+}
+
+type validator struct {
+	problems []Problem
+}
+
+func (v *validator) add(path, format string, args ...interface{}) {
+	v.problems = append(v.problems, Problem{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) check(path string, err error) {
+	if err != nil {
+		v.add(path, "%v", err)
+	}
+}
+
+// names reports the duplicate names in a section, and returns the path
+// of each entry.
+func (v *validator) names(section, kind string, names []string) []string {
+	count := make(map[string]int, len(names))
+	for _, name := range names {
+		count[name]++
+	}
+	first := make(map[string]string, len(names))
+	paths := make([]string, len(names))
+	for i, name := range names {
+		paths[i] = fmt.Sprintf("%s[%d]", section, i)
+		if name != "" && count[name] == 1 {
+			paths[i] = fmt.Sprintf("%s[name=%s]", section, name)
+		}
+		switch {
+		case name == "":
+			v.add(paths[i], "%s has no name", kind)
+		case first[name] != "":
+			v.add(paths[i]+".name", "duplicate %s %s, also defined at %s", kind, name, first[name])
+		default:
+			first[name] = paths[i]
+		}
+	}
+	return paths
+}
+
+// inRange reports a static or reserved range that is invalid or not in
+// the subnet's range.
+func (v *validator) inRange(path, s, rng string, block IPRange) (IPRange, bool) {
+	r, err := ParseIPRange(s)
+	if err != nil {
+		v.add(path, "%v", err)
+		return IPRange{}, false
+	}
+	if !block.Contains(r.First) || !block.Contains(r.Last) {
+		v.add(path, "%s is outside the range %s", s, rng)
+		return IPRange{}, false
+	}
+	return r, true
+}
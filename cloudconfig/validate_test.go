@@ -0,0 +1,55 @@
+package cloudconfig
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/enaml-ops/enaml"
+	"github.com/enaml-ops/omg-transform/golden"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("validating a cloud config", func() {
+	validate := func(path string) []Problem {
+		b, err := ioutil.ReadFile(path)
+		Ω(err).ShouldNot(HaveOccurred())
+		problems, err := Validate(enaml.NewCloudConfigManifest(b))
+		Ω(err).ShouldNot(HaveOccurred())
+		return problems
+	}
+
+	It("finds no problems in a valid cloud config", func() {
+		Ω(validate(awsCloudConfig)).Should(BeEmpty())
+	})
+
+	It("reports problems with their paths", func() {
+		var lines []string
+		for _, p := range validate("fixtures/cloud-config-invalid.yml") {
+			lines = append(lines, p.String())
+		}
+		out := []byte(strings.Join(append(lines, ""), "\n"))
+		Ω(golden.Check(filepath.Join("fixtures", "golden", "validate-invalid.txt"), out, *update)).Should(Succeed())
+	})
+
+	It("finds overlapping subnets across networks", func() {
+		b, err := ioutil.ReadFile(awsCloudConfig)
+		Ω(err).ShouldNot(HaveOccurred())
+		cc := enaml.NewCloudConfigManifest(b)
+		networks, err := Networks(cc)
+		Ω(err).ShouldNot(HaveOccurred())
+		SetNetworks(cc, append(networks, Network{
+			Name:    "services",
+			Type:    "manual",
+			Subnets: []Subnet{{Range: "10.0.6.0/24", AZ: "us-west-1c"}},
+		}))
+
+		problems, err := Validate(cc)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(problems).Should(Equal([]Problem{{
+			Path:    "networks[name=services].subnets[0].range",
+			Message: "range 10.0.6.0/24 overlaps networks[name=cf].subnets[1] (10.0.4.0/22)",
+		}}))
+	})
+})
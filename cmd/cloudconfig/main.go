@@ -3,14 +3,15 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
-	"io"
 	"io/ioutil"
 	"os"
 	"strings"
 
 	"github.com/enaml-ops/enaml"
+	"github.com/enaml-ops/omg-transform/cli"
 	"github.com/enaml-ops/omg-transform/cloudconfig"
 	yaml "gopkg.in/yaml.v2"
 )
@@ -18,10 +19,6 @@ import (
 // Version is the version of omg-transform.
 var Version = "v0.0.0-localcompile"
 
-func init() {
-	//	manifest.RegisterTransformationBuilder("change-network", manifest.ChangeNetworkTransformation)
-}
-
 func main() {
 
 	if len(os.Args) == 2 && strings.HasSuffix(os.Args[1], "version") {
@@ -29,78 +26,98 @@ func main() {
 		os.Exit(0)
 	}
 
-	if len(os.Args) <= 1 {
-		fmt.Fprintf(os.Stderr, "Usage: %s <transform> [args...]\n", os.Args[0])
-		writeTransforms(os.Stderr)
-		os.Exit(1)
+	prog := program()
+	args := os.Args[1:]
+	if len(args) > 0 && args[0] == "-json-errors" {
+		prog.JSONErrors = true
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		prog.WriteUsage(os.Stderr)
+		os.Exit(cli.ExitUsage)
 	}
 
-	name := os.Args[1]
-	builder, ok := transformationBuilders[name]
+	name := args[0]
+	switch name {
+	case "help":
+		os.Exit(prog.Help(args[1:], os.Stdout, os.Stderr))
+	case "docs":
+		os.Exit(prog.Docs(args[1:], os.Stdout, os.Stderr))
+	case "completion":
+		os.Exit(prog.Completion(args[1:], os.Stdout, os.Stderr))
+	case "validate":
+		os.Exit(validateCommand(prog, args[1:], os.Stdin, os.Stdout, os.Stderr))
+	case cli.CompleteCommand:
+		prog.WriteCompletions(os.Stdout, args[1:])
+		os.Exit(0)
+	}
+
+	info, ok := cloudconfig.LookupTransformation(name)
 	if !ok {
-		fmt.Fprintf(os.Stderr, "Usage: %s <transform> [args...]\n", os.Args[0])
-		fmt.Fprintf(os.Stderr, "ERROR: unknown transform %q\n", name)
-		writeTransforms(os.Stderr)
-		os.Exit(1)
+		os.Exit(prog.ReportError(os.Stderr, name, prog.UnknownTransform(name)))
 	}
 
 	// create the transform based on the arg passed in by the user
-	transform, err := builder(os.Args[2:])
+	transform, err := info.Builder(args[1:])
 	if err == flag.ErrHelp {
-		// help message was printed, so just exit
+		prog.WriteHelp(os.Stderr, name)
 		os.Exit(1)
 	}
 
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
-		os.Exit(1)
+		os.Exit(prog.ReportError(os.Stderr, name, cli.ArgumentError(err)))
 	}
 
-	// read manifest from stdin
+	// read cloud config from stdin
 	b, err := ioutil.ReadAll(os.Stdin)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
-		os.Exit(1)
+		os.Exit(prog.ReportError(os.Stderr, name, err))
 	}
 
 	cloudconfigManifest := enaml.NewCloudConfigManifest(b)
 	if cloudconfigManifest == nil {
-		fmt.Fprintln(os.Stderr, "ERROR: invalid input cloud config")
-		os.Exit(1)
+		os.Exit(prog.ReportError(os.Stderr, name, errors.New("invalid input cloud config")))
 	}
 
 	// apply the transformation
 	err = transform.Apply(cloudconfigManifest)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
-		os.Exit(1)
+		os.Exit(prog.ReportError(os.Stderr, name, err))
 	}
 
 	// write the transformed manifest back to stdout
 	b, err = yaml.Marshal(cloudconfigManifest)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
-		os.Exit(1)
+		os.Exit(prog.ReportError(os.Stderr, name, err))
 	}
 	os.Stdout.Write(b)
 }
 
-func writeTransforms(w io.Writer) {
-	fmt.Fprintf(w, "Transforms:\n")
-	for t := range transformationBuilders {
-		fmt.Fprintf(w, "  %s\n", t)
-	}
-}
-
-var transformationBuilders map[string]cloudconfig.TransformationBuilder
-
-// RegisterTransformationBuilder registers a transformation builder with the specified name.
-func RegisterTransformationBuilder(name string, tb cloudconfig.TransformationBuilder) {
-	if transformationBuilders == nil {
-		transformationBuilders = make(map[string]cloudconfig.TransformationBuilder)
+// program describes omg-transform-cloudconfig and its transformations.
+func program() *cli.Program {
+	p := &cli.Program{
+		Name:        "omg-transform-cloudconfig",
+		Description: "apply transformations to bosh cloud configs",
+		Version:     Version,
+		Commands: []cli.Command{
+			{Name: "help", Description: "show help for a transform"},
+			{Name: "docs", Description: "generate Markdown or man page reference docs"},
+			{Name: "validate", Description: "check a cloud config for overlapping subnets, undefined references and duplicate names"},
+			{Name: "completion", Description: "generate a bash, zsh or fish completion script"},
+		},
 	}
-	if _, ok := transformationBuilders[name]; ok {
-		panic(fmt.Errorf("duplicate transformation %q\n\nThis is a development error and should be reported at https://github.com/enaml-ops/omg-transform/issues", name))
+	for _, t := range cloudconfig.Transformations() {
+		ct := cli.Transformation{
+			Name:        t.Name,
+			Description: t.Description,
+			Usage:       t.Usage,
+			Examples:    t.Examples,
+			Complete:    t.Complete,
+		}
+		if t.Flags != nil {
+			ct.Flags = t.Flags()
+		}
+		p.Transformations = append(p.Transformations, ct)
 	}
-	transformationBuilders[name] = tb
+	return p
 }
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/enaml-ops/enaml"
+	"github.com/enaml-ops/omg-transform/cli"
+	"github.com/enaml-ops/omg-transform/cloudconfig"
+)
+
+// validateCommand implements the 'validate' command, which reports the
+// problems in the cloud config read from stdin with their YAML paths,
+// and fails if there are any.
+func validateCommand(prog *cli.Program, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	format := fs.String("format", "text", "the output format (text or json)")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: omg-transform-cloudconfig validate [-format text|json] < cloud-config.yml")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return cli.ExitUsage
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return cli.ExitUsage
+	}
+	if *format != "text" && *format != "json" {
+		return prog.ReportError(stderr, "validate", cli.ArgumentError(fmt.Errorf("invalid format %q, must be text or json", *format)))
+	}
+
+	b, err := ioutil.ReadAll(stdin)
+	if err != nil {
+		return prog.ReportError(stderr, "validate", err)
+	}
+	cc := enaml.NewCloudConfigManifest(b)
+	if cc == nil {
+		return prog.ReportError(stderr, "validate", errors.New("invalid input cloud config"))
+	}
+	problems, err := cloudconfig.Validate(cc)
+	if err != nil {
+		return prog.ReportError(stderr, "validate", err)
+	}
+
+	if *format == "json" {
+		if problems == nil {
+			problems = []cloudconfig.Problem{}
+		}
+		b, err := json.MarshalIndent(problems, "", "  ")
+		if err != nil {
+			return prog.ReportError(stderr, "validate", err)
+		}
+		fmt.Fprintln(stdout, string(b))
+	} else {
+		for _, p := range problems {
+			fmt.Fprintln(stdout, p)
+		}
+		fmt.Fprintf(stdout, "%d problems\n", len(problems))
+	}
+
+	if len(problems) > 0 {
+		return cli.ExitPrecondition
+	}
+	return 0
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+
+	"github.com/enaml-ops/omg-transform/cli"
+	"github.com/enaml-ops/omg-transform/cloudconfig"
+)
+
+// generateCloudConfigCommand implements the 'generate-cloudconfig'
+// command, which writes a cloud config skeleton for the manifest read
+// from stdin.
+func generateCloudConfigCommand(prog *cli.Program, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("generate-cloudconfig", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	iaas := fs.String("iaas", "", "the IaaS whose cloud properties are filled in ("+strings.Join(cloudconfig.IaaSProfiles(), ", ")+")")
+	cidr := fs.String("cidr", "10.0.0.0/16", "the address space subnets without static IPs are allocated from")
+	workers := fs.Int("workers", 4, "the number of compilation workers")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: omg-transform generate-cloudconfig -iaas name [-cidr range] [-workers n] < manifest.yml")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return cli.ExitUsage
+	}
+	if *iaas == "" || fs.NArg() > 0 {
+		fs.Usage()
+		return cli.ExitUsage
+	}
+
+	profile, ok := cloudconfig.LookupIaaSProfile(*iaas)
+	if !ok {
+		err := fmt.Errorf("unknown IaaS %q, must be one of %s", *iaas, strings.Join(cloudconfig.IaaSProfiles(), ", "))
+		return prog.ReportError(stderr, "generate-cloudconfig", cli.ArgumentError(err))
+	}
+	_, space, err := net.ParseCIDR(*cidr)
+	if err != nil || space.IP.To4() == nil {
+		err = fmt.Errorf("invalid CIDR %q, must be an IPv4 range such as 10.0.0.0/16", *cidr)
+		return prog.ReportError(stderr, "generate-cloudconfig", cli.ArgumentError(err))
+	}
+	if *workers < 1 {
+		err = fmt.Errorf("invalid number of workers %d, must be at least 1", *workers)
+		return prog.ReportError(stderr, "generate-cloudconfig", cli.ArgumentError(err))
+	}
+
+	dm, err := readManifest(stdin)
+	if err != nil {
+		return prog.ReportError(stderr, "generate-cloudconfig", err)
+	}
+	cc, err := cloudconfig.Generate(dm, profile, cloudconfig.GenerateOptions{CIDR: space, Workers: *workers})
+	if err != nil {
+		return prog.ReportError(stderr, "generate-cloudconfig", err)
+	}
+	return writeManifest(prog, stdout, stderr, "generate-cloudconfig", cc)
+}
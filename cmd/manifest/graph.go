@@ -0,0 +1,62 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/enaml-ops/omg-transform/manifest"
+)
+
+// graphCommand implements the 'graph' command, which exports the
+// dependency graph of the manifest read from stdin.
+func graphCommand(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("graph", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	format := fs.String("format", "dot", "the output format (dot, mermaid or json)")
+	ig := fs.String("instance-group", "", "only include the neighbourhood of this instance group")
+	depth := fs.Int("depth", 1, "how many dependencies away from -instance-group to include")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: omg-transform graph [-format dot|mermaid|json] [-instance-group name [-depth n]] < manifest.yml")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return 1
+	}
+
+	var write func(*manifest.Graph, io.Writer) error
+	switch *format {
+	case "dot":
+		write = (*manifest.Graph).WriteDOT
+	case "mermaid":
+		write = (*manifest.Graph).WriteMermaid
+	case "json":
+		write = (*manifest.Graph).WriteJSON
+	default:
+		fmt.Fprintf(stderr, "ERROR: invalid format %q, must be dot, mermaid or json\n", *format)
+		return 1
+	}
+
+	dm, err := readManifest(stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "ERROR: %v\n", err)
+		return 1
+	}
+	g := manifest.NewGraph(dm)
+	if *ig != "" {
+		g, err = g.Neighbourhood(*ig, *depth)
+		if err != nil {
+			fmt.Fprintf(stderr, "ERROR: %v\n", err)
+			return 1
+		}
+	}
+	if err = write(g, stdout); err != nil {
+		fmt.Fprintf(stderr, "ERROR: %v\n", err)
+		return 1
+	}
+	return 0
+}
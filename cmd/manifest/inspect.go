@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/enaml-ops/enaml"
+	"github.com/enaml-ops/omg-transform/manifest"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// inspectCommand implements the 'inspect' command, which describes
+// the manifest read from stdin.
+func inspectCommand(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) > 0 {
+		fmt.Fprintln(stderr, "Usage: omg-transform inspect < manifest.yml")
+		return 1
+	}
+	dm, err := readManifest(stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "ERROR: %v\n", err)
+		return 1
+	}
+	if err = manifest.Inspect(stdout, dm); err != nil {
+		fmt.Fprintf(stderr, "ERROR: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// queryCommand implements the 'query' command, which prints the values
+// in the manifest read from stdin that match an expression.  Each value
+// is written as its own YAML document, or as a line of JSON.
+func queryCommand(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("query", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	format := fs.String("format", "yaml", "the output format (yaml or json)")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: omg-transform query [-format yaml|json] <expression> < manifest.yml")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 1
+	}
+	if *format != "yaml" && *format != "json" {
+		fmt.Fprintf(stderr, "ERROR: invalid format %q, must be yaml or json\n", *format)
+		return 1
+	}
+
+	dm, err := readManifest(stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "ERROR: %v\n", err)
+		return 1
+	}
+	values, err := manifest.Query(dm, fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "ERROR: %v\n", err)
+		return 1
+	}
+
+	for i, v := range values {
+		var b []byte
+		if *format == "json" {
+			b, err = json.Marshal(v)
+			b = append(b, '\n')
+		} else {
+			b, err = yaml.Marshal(v)
+			if i > 0 {
+				fmt.Fprintln(stdout, "---")
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "ERROR: %v\n", err)
+			return 1
+		}
+		stdout.Write(b)
+	}
+	return 0
+}
+
+func readManifest(r io.Reader) (*enaml.DeploymentManifest, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	dm := enaml.NewDeploymentManifest(b)
+	if dm == nil {
+		return nil, errors.New("invalid input manifest")
+	}
+	return dm, nil
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"text/tabwriter"
+	"time"
+
+	"github.com/enaml-ops/enaml"
+	"github.com/enaml-ops/omg-transform/cli"
+	"github.com/enaml-ops/omg-transform/manifest"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// provenance records the transformations applied by this invocation
+// into the journal file given with -journal, and into the manifest's
+// annotation when -annotate is set.
+type provenance struct {
+	journalFile string
+	annotate    bool
+	journal     manifest.Journal
+}
+
+func (p *provenance) enabled() bool {
+	return p.journalFile != "" || p.annotate
+}
+
+// options returns the journal to record steps in when running a
+// pipeline, or nil if provenance isn't being recorded.
+func (p *provenance) options() *manifest.Journal {
+	if !p.enabled() {
+		return nil
+	}
+	p.journal.Version = Version
+	return &p.journal
+}
+
+// record adds a step that is about to be applied to dm.
+func (p *provenance) record(step manifest.Step, dm *enaml.DeploymentManifest) error {
+	if !p.enabled() {
+		return nil
+	}
+	input, err := manifest.Checksum(dm)
+	if err != nil {
+		return err
+	}
+	p.options().Record(step, input)
+	return nil
+}
+
+// finish appends the recorded steps to the journal file, and returns the
+// output manifest with the steps added to the annotation carried over
+// from the input manifest.
+func (p *provenance) finish(input, output []byte) ([]byte, error) {
+	if p.journalFile != "" {
+		j, err := manifest.ReadJournal(p.journalFile)
+		if err != nil {
+			return nil, err
+		}
+		j.Entries = append(j.Entries, p.journal.Entries...)
+		if err = j.WriteFile(p.journalFile); err != nil {
+			return nil, err
+		}
+	}
+	if !p.annotate {
+		return output, nil
+	}
+	j, err := manifest.ReadAnnotation(input)
+	if err != nil {
+		return nil, err
+	}
+	j.Entries = append(j.Entries, p.journal.Entries...)
+	return manifest.Annotate(output, j)
+}
+
+// historyCommand implements the 'history' command, which lists the
+// entries of a journal, or replays them against the manifest read from
+// stdin.
+func historyCommand(prog *cli.Program, prov *provenance, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("history", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	journalFile := fs.String("journal", "", "the journal file to read")
+	annotated := fs.String("manifest", "", "read the journal annotation of this manifest")
+	replay := fs.Bool("replay", false, "apply the journal's steps to the manifest read from stdin")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: omg-transform history [-journal file | -manifest annotated.yml] [-replay] [< manifest.yml]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return cli.ExitUsage
+	}
+	if fs.NArg() > 0 || (*journalFile != "" && *annotated != "") || (*replay && *journalFile == "" && *annotated == "") {
+		fs.Usage()
+		return cli.ExitUsage
+	}
+
+	var (
+		j   *manifest.Journal
+		err error
+	)
+	switch {
+	case *journalFile != "":
+		j, err = manifest.ReadJournal(*journalFile)
+	case *annotated != "":
+		j, err = readAnnotation(*annotated)
+	default:
+		var b []byte
+		if b, err = ioutil.ReadAll(stdin); err == nil {
+			j, err = manifest.ReadAnnotation(b)
+		}
+	}
+	if err != nil {
+		return prog.ReportError(stderr, "history", err)
+	}
+
+	if !*replay {
+		writeJournal(stdout, j)
+		return 0
+	}
+	return replayJournal(prog, prov, j, stdin, stdout, stderr)
+}
+
+func readAnnotation(path string) (*manifest.Journal, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return manifest.ReadAnnotation(b)
+}
+
+// writeJournal lists the entries of a journal.
+func writeJournal(w io.Writer, j *manifest.Journal) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "#\tTIME\tVERSION\tINPUT\tSTEP")
+	for i, e := range j.Entries {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n", i+1, e.Time.Format(time.RFC3339), e.Version, shortChecksum(e.Input), e.Step)
+	}
+	tw.Flush()
+}
+
+// shortChecksum abbreviates a sha256:hex checksum for display.
+func shortChecksum(c string) string {
+	const n = len("sha256:") + 12
+	if len(c) > n {
+		return c[:n]
+	}
+	return c
+}
+
+// replayJournal applies the steps of a journal to the manifest read from
+// stdin.  A fresh manifest generated by omg-cli won't usually match the
+// one the journal was recorded against, so a different checksum is only
+// a warning.
+func replayJournal(prog *cli.Program, prov *provenance, j *manifest.Journal, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(j.Entries) == 0 {
+		return prog.ReportError(stderr, "history", errors.New("the journal has no entries to replay"))
+	}
+	b, err := ioutil.ReadAll(stdin)
+	if err != nil {
+		return prog.ReportError(stderr, "history", err)
+	}
+	dm := enaml.NewDeploymentManifest(b)
+	if dm == nil {
+		return prog.ReportError(stderr, "history", errors.New("invalid input manifest"))
+	}
+	if input, err := manifest.Checksum(dm); err == nil && input != j.Entries[0].Input {
+		fmt.Fprintf(stderr, "warning: the manifest isn't the one the journal was recorded against (%s, not %s)\n",
+			shortChecksum(input), shortChecksum(j.Entries[0].Input))
+	}
+
+	log, err := j.Pipeline().Run(dm, manifest.RunOptions{Journal: prov.options()})
+	if err != nil {
+		return prog.ReportError(stderr, "history", err)
+	}
+	writeSummary(stderr, log)
+
+	out, err := yaml.Marshal(dm)
+	if err != nil {
+		return prog.ReportError(stderr, "history", err)
+	}
+	if out, err = prov.finish(b, out); err != nil {
+		return prog.ReportError(stderr, "history", err)
+	}
+	stdout.Write(out)
+	return 0
+}
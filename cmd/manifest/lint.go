@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/enaml-ops/omg-transform/cli"
+	"github.com/enaml-ops/omg-transform/manifest"
+)
+
+// lintCommand implements the 'lint' command, which checks the manifest
+// read from stdin against the built-in rules and those in a rules file,
+// and fails if there are findings at or above a severity.
+func lintCommand(prog *cli.Program, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("lint", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	rulesFile := fs.String("rules", "", "a YAML file of rules, which configures the built-in rules and adds others")
+	failOn := fs.String("fail-on", manifest.SeverityError, "fail if there are findings of this severity or above (info, warning or error)")
+	format := fs.String("format", "text", "the output format (text or json)")
+	list := fs.Bool("list", false, "list the built-in rules")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: omg-transform lint [-rules rules.yml] [-fail-on severity] [-format text|json] < manifest.yml")
+		fmt.Fprintln(stderr, "       omg-transform lint -list")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return cli.ExitUsage
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return cli.ExitUsage
+	}
+	if *list {
+		writeLintRules(stdout)
+		return 0
+	}
+	if *format != "text" && *format != "json" {
+		return prog.ReportError(stderr, "lint", cli.ArgumentError(fmt.Errorf("invalid format %q, must be text or json", *format)))
+	}
+
+	lint, err := lintOptions(*rulesFile, *failOn)
+	if err != nil {
+		return prog.ReportError(stderr, "lint", err)
+	}
+	dm, err := readManifest(stdin)
+	if err != nil {
+		return prog.ReportError(stderr, "lint", err)
+	}
+	findings, err := manifest.Lint(dm, lint.Rules)
+	if err != nil {
+		return prog.ReportError(stderr, "lint", err)
+	}
+
+	if *format == "json" {
+		if findings == nil {
+			findings = []manifest.Finding{}
+		}
+		b, err := json.MarshalIndent(findings, "", "  ")
+		if err != nil {
+			return prog.ReportError(stderr, "lint", err)
+		}
+		fmt.Fprintln(stdout, string(b))
+	} else {
+		writeFindings(stdout, findings)
+	}
+
+	if len(manifest.FailingFindings(findings, lint.FailOn)) > 0 {
+		return cli.ExitPrecondition
+	}
+	return 0
+}
+
+// lintOptions returns the options for linting with the rules in
+// rulesFile, if it is set.
+func lintOptions(rulesFile, failOn string) (*manifest.LintOptions, error) {
+	if err := validSeverity(failOn); err != nil {
+		return nil, err
+	}
+	lint := &manifest.LintOptions{FailOn: failOn}
+	if rulesFile != "" {
+		rules, err := manifest.ReadRuleSet(rulesFile)
+		if err != nil {
+			return nil, err
+		}
+		lint.Rules = rules
+	}
+	return lint, nil
+}
+
+func validSeverity(s string) error {
+	switch s {
+	case manifest.SeverityInfo, manifest.SeverityWarning, manifest.SeverityError:
+		return nil
+	}
+	return cli.ArgumentError(fmt.Errorf("invalid severity %q, must be info, warning or error", s))
+}
+
+// writeFindings lists lint findings, one per line.
+func writeFindings(w io.Writer, findings []manifest.Finding) {
+	for _, f := range findings {
+		fmt.Fprintln(w, f)
+	}
+	fmt.Fprintf(w, "%d findings\n", len(findings))
+}
+
+func writeLintRules(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "RULE\tSEVERITY\tDESCRIPTION")
+	for _, r := range manifest.LintRules() {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", r.Name, r.Severity, r.Description)
+	}
+	tw.Flush()
+}
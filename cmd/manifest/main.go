@@ -3,14 +3,15 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
-	"io"
 	"io/ioutil"
 	"os"
 	"strings"
 
 	"github.com/enaml-ops/enaml"
+	"github.com/enaml-ops/omg-transform/cli"
 	"github.com/enaml-ops/omg-transform/manifest"
 	yaml "gopkg.in/yaml.v2"
 )
@@ -18,14 +19,6 @@ import (
 // Version is the version of omg-transform.
 var Version = "v0.0.0-localcompile"
 
-func init() {
-	RegisterTransformationBuilder("change-network", manifest.ChangeNetworkTransformation)
-	RegisterTransformationBuilder("clone", manifest.CloneTransformation)
-	RegisterTransformationBuilder("change-az", manifest.ChangeAZTransformation)
-	RegisterTransformationBuilder("add-tags", manifest.AddTagsTransformation)
-	RegisterTransformationBuilder("add-vm-extension", manifest.AddVMExtensionTransformation)
-}
-
 func main() {
 
 	if len(os.Args) == 2 && strings.HasSuffix(os.Args[1], "version") {
@@ -33,78 +26,155 @@ func main() {
 		os.Exit(0)
 	}
 
-	if len(os.Args) <= 1 {
-		fmt.Fprintf(os.Stderr, "Usage: %s <transform> [args...]\n", os.Args[0])
-		writeTransforms(os.Stderr)
-		os.Exit(1)
+	// plugins are only run when they are used, or listed by help and docs
+	manifest.UsePlugins(manifest.PluginDirs())
+
+	prog := program()
+	args := os.Args[1:]
+	prov := &provenance{}
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		switch {
+		case args[0] == "-json-errors":
+			prog.JSONErrors = true
+		case args[0] == "-annotate":
+			prov.annotate = true
+		case args[0] == "-journal" && len(args) > 1:
+			prov.journalFile = args[1]
+			args = args[1:]
+		default:
+			prog.WriteUsage(os.Stderr)
+			os.Exit(cli.ExitUsage)
+		}
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		prog.WriteUsage(os.Stderr)
+		os.Exit(cli.ExitUsage)
 	}
 
-	name := os.Args[1]
-	builder, ok := transformationBuilders[name]
+	name := args[0]
+	switch name {
+	case "help":
+		manifest.RegisterPlugins(manifest.PluginDirs())
+		os.Exit(program().Help(args[1:], os.Stdout, os.Stderr))
+	case "docs":
+		manifest.RegisterPlugins(manifest.PluginDirs())
+		os.Exit(program().Docs(args[1:], os.Stdout, os.Stderr))
+	case "completion":
+		os.Exit(prog.Completion(args[1:], os.Stdout, os.Stderr))
+	case "inspect":
+		os.Exit(inspectCommand(args[1:], os.Stdin, os.Stdout, os.Stderr))
+	case "query":
+		os.Exit(queryCommand(args[1:], os.Stdin, os.Stdout, os.Stderr))
+	case "graph":
+		os.Exit(graphCommand(args[1:], os.Stdin, os.Stdout, os.Stderr))
+	case "run":
+		os.Exit(runCommand(prog, prov, args[1:], os.Stdin, os.Stdout, os.Stderr))
+	case "generate-cloudconfig":
+		os.Exit(generateCloudConfigCommand(prog, args[1:], os.Stdin, os.Stdout, os.Stderr))
+	case "lint":
+		os.Exit(lintCommand(prog, args[1:], os.Stdin, os.Stdout, os.Stderr))
+	case "rename-reference":
+		os.Exit(renameReferenceCommand(prog, args[1:], os.Stderr))
+	case "undo":
+		os.Exit(undoCommand(prog, args[1:], os.Stdin, os.Stdout, os.Stderr))
+	case "history":
+		os.Exit(historyCommand(prog, prov, args[1:], os.Stdin, os.Stdout, os.Stderr))
+	case "serve":
+		os.Exit(serveCommand(prog, args[1:], os.Stderr))
+	case cli.CompleteCommand:
+		prog.WriteCompletions(os.Stdout, args[1:])
+		os.Exit(0)
+	}
+
+	info, ok := manifest.LookupTransformation(name)
 	if !ok {
-		fmt.Fprintf(os.Stderr, "Usage: %s <transform> [args...]\n", os.Args[0])
-		fmt.Fprintf(os.Stderr, "ERROR: unknown transform %q\n", name)
-		writeTransforms(os.Stderr)
-		os.Exit(1)
+		os.Exit(prog.ReportError(os.Stderr, name, prog.UnknownTransform(name)))
 	}
 
 	// create the transform based on the arg passed in by the user
-	transform, err := builder(os.Args[2:])
+	transform, err := info.Builder(args[1:])
 	if err == flag.ErrHelp {
-		// help message was printed, so just exit
+		prog.WriteHelp(os.Stderr, name)
 		os.Exit(1)
 	}
 
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
-		os.Exit(1)
+		os.Exit(prog.ReportError(os.Stderr, name, cli.ArgumentError(err)))
 	}
 
+	step := manifest.Step{Transform: name, Args: args[1:]}
+
 	// read manifest from stdin
 	b, err := ioutil.ReadAll(os.Stdin)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
-		os.Exit(1)
+		os.Exit(prog.ReportError(os.Stderr, name, err))
 	}
 
 	manifest := enaml.NewDeploymentManifest(b)
 	if manifest == nil {
-		fmt.Fprintln(os.Stderr, "ERROR: invalid input manifest")
-		os.Exit(1)
+		os.Exit(prog.ReportError(os.Stderr, name, errors.New("invalid input manifest")))
+	}
+
+	// record the transformation if -journal or -annotate was given
+	err = prov.record(step, manifest)
+	if err != nil {
+		os.Exit(prog.ReportError(os.Stderr, name, err))
 	}
 
 	// apply the transformation
 	err = transform.Apply(manifest)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
-		os.Exit(1)
+		os.Exit(prog.ReportError(os.Stderr, name, err))
 	}
 
 	// write the transformed manifest back to stdout
-	b, err = yaml.Marshal(manifest)
+	out, err := yaml.Marshal(manifest)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
-		os.Exit(1)
+		os.Exit(prog.ReportError(os.Stderr, name, err))
 	}
-	os.Stdout.Write(b)
-}
-
-func writeTransforms(w io.Writer) {
-	fmt.Fprintf(w, "Transforms:\n")
-	for t := range transformationBuilders {
-		fmt.Fprintf(w, "  %s\n", t)
+	out, err = prov.finish(b, out)
+	if err != nil {
+		os.Exit(prog.ReportError(os.Stderr, name, err))
 	}
+	os.Stdout.Write(out)
 }
 
-var transformationBuilders map[string]manifest.TransformationBuilder
-
-// RegisterTransformationBuilder registers a transformation builder with the specified name.
-func RegisterTransformationBuilder(name string, tb manifest.TransformationBuilder) {
-	if transformationBuilders == nil {
-		transformationBuilders = make(map[string]manifest.TransformationBuilder)
+// program describes omg-transform and its transformations.
+func program() *cli.Program {
+	p := &cli.Program{
+		Name:        "omg-transform",
+		Description: "apply transformations to bosh deployment manifests",
+		Version:     Version,
+		Options:     []string{"-journal file", "-annotate"},
+		Commands: []cli.Command{
+			{Name: "help", Description: "show help for a transform"},
+			{Name: "docs", Description: "generate Markdown or man page reference docs"},
+			{Name: "inspect", Description: "describe the instance groups, releases and stemcells in a manifest"},
+			{Name: "query", Description: "print the values in a manifest that match an expression"},
+			{Name: "graph", Description: "export the links and dependencies in a manifest as DOT, Mermaid or JSON"},
+			{Name: "run", Description: "apply the steps of a pipeline file, optionally checking idempotency"},
+			{Name: "lint", Description: "check a manifest against policy rules"},
+			{Name: "generate-cloudconfig", Description: "generate a cloud config skeleton for a manifest"},
+			{Name: "rename-reference", Description: "rename an AZ, network, vm type, vm extension or disk type in a cloud config and the manifests that use it"},
+			{Name: "undo", Description: "roll back the steps recorded in a transform log"},
+			{Name: "history", Description: "list or replay the transformations recorded in a journal"},
+			{Name: "serve", Description: "apply manifest and cloud config transformations over HTTP"},
+			{Name: "completion", Description: "generate a bash, zsh or fish completion script"},
+		},
 	}
-	if _, ok := transformationBuilders[name]; ok {
-		panic(fmt.Errorf("duplicate transformation %q\n\nThis is a development error and should be reported at https://github.com/enaml-ops/omg-transform/issues", name))
+	for _, t := range manifest.Transformations() {
+		ct := cli.Transformation{
+			Name:        t.Name,
+			Description: t.Description,
+			Usage:       t.Usage,
+			Examples:    t.Examples,
+			Complete:    t.Complete,
+		}
+		if t.Flags != nil {
+			ct.Flags = t.Flags()
+		}
+		p.Transformations = append(p.Transformations, ct)
 	}
-	transformationBuilders[name] = tb
+	return p
 }
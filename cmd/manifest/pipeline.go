@@ -0,0 +1,158 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/enaml-ops/enaml"
+	"github.com/enaml-ops/omg-transform/cli"
+	"github.com/enaml-ops/omg-transform/manifest"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// runCommand implements the 'run' command, which applies the steps of a
+// pipeline file to the manifest read from stdin and writes a summary of
+// the steps to stderr.
+func runCommand(prog *cli.Program, prov *provenance, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	file := fs.String("f", "", "the pipeline file to run")
+	check := fs.Bool("check-idempotency", false, "apply each idempotent step twice and fail if the second run changes the manifest")
+	logFile := fs.String("log", "", "write a transform log, used by 'undo', to this file")
+	varsFile := fs.String("vars", "", "a YAML file of variables for the templates in the steps' arguments")
+	lint := fs.Bool("lint", false, "lint the result, and fail without writing it if it has findings at or above -lint-fail-on")
+	lintRules := fs.String("lint-rules", "", "lint the result with the rules in this file, as with -lint")
+	lintFailOn := fs.String("lint-fail-on", manifest.SeverityError, "the severity of lint findings that fails the run (info, warning or error)")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: omg-transform run -f pipeline.yml [-vars vars.yml] [-check-idempotency] [-lint] [-lint-rules rules.yml] [-log file] < manifest.yml")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return cli.ExitUsage
+	}
+	if *file == "" || fs.NArg() > 0 {
+		fs.Usage()
+		return cli.ExitUsage
+	}
+
+	p, err := manifest.ReadPipeline(*file)
+	if err != nil {
+		return prog.ReportError(stderr, "run", err)
+	}
+	var vars map[string]interface{}
+	if *varsFile != "" {
+		if vars, err = manifest.ReadVars(*varsFile); err != nil {
+			return prog.ReportError(stderr, "run", err)
+		}
+	}
+	opts := manifest.RunOptions{CheckIdempotency: *check, Journal: prov.options(), Vars: vars}
+	if *lint || *lintRules != "" {
+		if opts.Lint, err = lintOptions(*lintRules, *lintFailOn); err != nil {
+			return prog.ReportError(stderr, "run", err)
+		}
+	}
+	b, err := ioutil.ReadAll(stdin)
+	if err != nil {
+		return prog.ReportError(stderr, "run", err)
+	}
+	dm := enaml.NewDeploymentManifest(b)
+	if dm == nil {
+		return prog.ReportError(stderr, "run", errors.New("invalid input manifest"))
+	}
+
+	log, err := p.Run(dm, opts)
+	if err != nil {
+		return prog.ReportError(stderr, "run", err)
+	}
+	writeSummary(stderr, log)
+	if *logFile != "" {
+		if err = log.WriteFile(*logFile); err != nil {
+			return prog.ReportError(stderr, "run", err)
+		}
+	}
+
+	out, err := yaml.Marshal(dm)
+	if err != nil {
+		return prog.ReportError(stderr, "run", err)
+	}
+	if out, err = prov.finish(b, out); err != nil {
+		return prog.ReportError(stderr, "run", err)
+	}
+	stdout.Write(out)
+	return 0
+}
+
+// undoCommand implements the 'undo' command, which rolls back the steps
+// recorded in a transform log.
+func undoCommand(prog *cli.Program, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("undo", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	logFile := fs.String("log", "", "the transform log written by 'run'")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: omg-transform undo -log file < manifest.yml")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return cli.ExitUsage
+	}
+	if *logFile == "" || fs.NArg() > 0 {
+		fs.Usage()
+		return cli.ExitUsage
+	}
+
+	log, err := manifest.ReadTransformLog(*logFile)
+	if err != nil {
+		return prog.ReportError(stderr, "undo", err)
+	}
+	dm, err := readManifest(stdin)
+	if err != nil {
+		return prog.ReportError(stderr, "undo", err)
+	}
+	if err = manifest.Undo(dm, log); err != nil {
+		return prog.ReportError(stderr, "undo", err)
+	}
+	return writeManifest(prog, stdout, stderr, "undo", dm)
+}
+
+// writeSummary lists the steps in a transform log.
+func writeSummary(w io.Writer, log *manifest.TransformLog) {
+	skipped := 0
+	for _, e := range log.Entries {
+		if e.Skipped != "" {
+			skipped++
+		}
+	}
+	if skipped > 0 {
+		fmt.Fprintf(w, "applied %d steps, skipped %d:\n", len(log.Entries)-skipped, skipped)
+	} else {
+		fmt.Fprintf(w, "applied %d steps:\n", len(log.Entries))
+	}
+	for i, e := range log.Entries {
+		note := ""
+		switch {
+		case e.Skipped != "":
+			note = " (skipped: " + e.Skipped + ")"
+		case e.Irreversible != "":
+			note = " (can't be undone)"
+		}
+		fmt.Fprintf(w, "  %d. %s%s\n", i+1, e.Step, note)
+	}
+	if len(log.Findings) > 0 {
+		fmt.Fprintf(w, "lint findings:\n")
+		for _, f := range log.Findings {
+			fmt.Fprintf(w, "  %s\n", f)
+		}
+	}
+}
+
+func writeManifest(prog *cli.Program, stdout, stderr io.Writer, command string, v interface{}) int {
+	b, err := yaml.Marshal(v)
+	if err != nil {
+		return prog.ReportError(stderr, command, err)
+	}
+	stdout.Write(b)
+	return 0
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/enaml-ops/enaml"
+	"github.com/enaml-ops/omg-transform/cli"
+	"github.com/enaml-ops/omg-transform/manifest"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// renameReferenceCommand implements the 'rename-reference' command, which
+// renames an entity of a cloud config and every reference to it in the
+// cloud config and the manifests given as arguments, and rewrites them
+// all.
+func renameReferenceCommand(prog *cli.Program, args []string, stderr io.Writer) int {
+	fs := flag.NewFlagSet("rename-reference", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	cloudConfig := fs.String("cloud-config", "", "the cloud config that defines the entity")
+	kind := fs.String("kind", "", "the kind of entity ("+strings.Join(manifest.ReferenceKinds, ", ")+")")
+	from := fs.String("from", "", "the entity's current name")
+	to := fs.String("to", "", "the entity's new name")
+	outputDir := fs.String("output-dir", "", "write the files to this directory instead of rewriting them")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: omg-transform rename-reference -cloud-config file -kind kind -from name -to name [-output-dir dir] manifest.yml...")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return cli.ExitUsage
+	}
+	if *cloudConfig == "" || *kind == "" || *from == "" || *to == "" {
+		fs.Usage()
+		return cli.ExitUsage
+	}
+
+	// every file is read before any is written, so that an error
+	// leaves them all as they were
+	paths := append([]string{*cloudConfig}, fs.Args()...)
+	outputs := make(map[string]string, len(paths))
+	for _, path := range paths {
+		out := path
+		if *outputDir != "" {
+			out = filepath.Join(*outputDir, filepath.Base(path))
+		}
+		for in, o := range outputs {
+			if o == out {
+				err := fmt.Errorf("%s and %s would both be written to %s", in, path, out)
+				return prog.ReportError(stderr, "rename-reference", cli.ArgumentError(err))
+			}
+		}
+		outputs[path] = out
+	}
+
+	b, err := ioutil.ReadFile(*cloudConfig)
+	if err != nil {
+		return prog.ReportError(stderr, "rename-reference", err)
+	}
+	cc := enaml.NewCloudConfigManifest(b)
+	if cc == nil {
+		return prog.ReportError(stderr, "rename-reference", fmt.Errorf("invalid cloud config %s", *cloudConfig))
+	}
+	var dms []*enaml.DeploymentManifest
+	for _, path := range fs.Args() {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return prog.ReportError(stderr, "rename-reference", err)
+		}
+		dm := enaml.NewDeploymentManifest(b)
+		if dm == nil {
+			return prog.ReportError(stderr, "rename-reference", fmt.Errorf("invalid manifest %s", path))
+		}
+		dms = append(dms, dm)
+	}
+
+	renamed, err := manifest.RenameReference(cc, dms, *kind, *from, *to)
+	if err != nil {
+		return prog.ReportError(stderr, "rename-reference", cli.ArgumentError(err))
+	}
+
+	values := []interface{}{cc}
+	counts := []int{renamed.CloudConfig}
+	for i, dm := range dms {
+		values = append(values, dm)
+		counts = append(counts, renamed.Manifests[i])
+	}
+	if err = writeFiles(paths, outputs, values); err != nil {
+		return prog.ReportError(stderr, "rename-reference", err)
+	}
+	fmt.Fprintf(stderr, "renamed %s %s to %s:\n", *kind, *from, *to)
+	for i, path := range paths {
+		fmt.Fprintf(stderr, "  %s: %d references\n", outputs[path], counts[i])
+	}
+	return 0
+}
+
+// writeFiles writes each value as YAML to the output of its path.  The
+// files are written to temporary files next to their outputs first, and
+// only renamed into place once they have all been written, so that an
+// error writing one leaves them all as they were.  If renaming one
+// fails, the error lists the files that were already replaced.
+func writeFiles(paths []string, outputs map[string]string, values []interface{}) error {
+	temps := make([]string, len(paths))
+	defer func() {
+		for _, temp := range temps {
+			if temp != "" {
+				os.Remove(temp)
+			}
+		}
+	}()
+	for i, path := range paths {
+		b, err := yaml.Marshal(values[i])
+		if err != nil {
+			return err
+		}
+		out := outputs[path]
+		f, err := ioutil.TempFile(filepath.Dir(out), "."+filepath.Base(out)+".")
+		if err != nil {
+			return err
+		}
+		temps[i] = f.Name()
+		_, err = f.Write(b)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil {
+			err = os.Chmod(f.Name(), 0644)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	var replaced []string
+	for i, path := range paths {
+		if err := os.Rename(temps[i], outputs[path]); err != nil {
+			if len(replaced) == 0 {
+				return err
+			}
+			return fmt.Errorf("%v; %s already written, the other files are unchanged", err, strings.Join(replaced, ", "))
+		}
+		temps[i] = ""
+		replaced = append(replaced, outputs[path])
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/enaml-ops/omg-transform/cli"
+	"github.com/enaml-ops/omg-transform/script"
+	"github.com/enaml-ops/omg-transform/server"
+)
+
+// serveCommand implements the 'serve' command, which applies manifest
+// and cloud config transformations over HTTP.
+func serveCommand(prog *cli.Program, args []string, stderr io.Writer) int {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	addr := fs.String("addr", "localhost:8080", "the address to listen on")
+	maxBody := fs.Int64("max-body-size", server.DefaultMaxBodySize, "the largest request body accepted, in bytes")
+	maxConcurrent := fs.Int("max-concurrent", 0, "the number of transformations applied at once (default the number of CPUs)")
+	maxScriptSteps := fs.Uint64("max-script-steps", script.DefaultMaxSteps, "the most execution steps a script may take")
+	timeout := fs.Duration("timeout", time.Minute, "the time allowed to read a request and to write its response")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: omg-transform serve [-addr host:port] [-max-body-size bytes] [-max-concurrent n] [-max-script-steps n] [-timeout duration]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return cli.ExitUsage
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return cli.ExitUsage
+	}
+
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           server.New(server.Options{MaxBodySize: *maxBody, MaxConcurrent: *maxConcurrent, MaxScriptSteps: *maxScriptSteps}),
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       *timeout,
+		WriteTimeout:      *timeout,
+		IdleTimeout:       2 * time.Minute,
+	}
+	fmt.Fprintf(stderr, "listening on %s\n", *addr)
+	return prog.ReportError(stderr, "serve", srv.ListenAndServe())
+}
@@ -0,0 +1,119 @@
+// omg-transform is a tool for applying transformations to
+// bosh runtime configs.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/enaml-ops/omg-transform/cli"
+	"github.com/enaml-ops/omg-transform/runtimeconfig"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Version is the version of omg-transform.
+var Version = "v0.0.0-localcompile"
+
+func main() {
+
+	if len(os.Args) == 2 && strings.HasSuffix(os.Args[1], "version") {
+		fmt.Fprintf(os.Stdout, "Version: %s \n", Version)
+		os.Exit(0)
+	}
+
+	prog := program()
+	args := os.Args[1:]
+	if len(args) > 0 && args[0] == "-json-errors" {
+		prog.JSONErrors = true
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		prog.WriteUsage(os.Stderr)
+		os.Exit(cli.ExitUsage)
+	}
+
+	name := args[0]
+	switch name {
+	case "help":
+		os.Exit(prog.Help(args[1:], os.Stdout, os.Stderr))
+	case "docs":
+		os.Exit(prog.Docs(args[1:], os.Stdout, os.Stderr))
+	case "completion":
+		os.Exit(prog.Completion(args[1:], os.Stdout, os.Stderr))
+	case cli.CompleteCommand:
+		prog.WriteCompletions(os.Stdout, args[1:])
+		os.Exit(0)
+	}
+
+	info, ok := runtimeconfig.LookupTransformation(name)
+	if !ok {
+		os.Exit(prog.ReportError(os.Stderr, name, prog.UnknownTransform(name)))
+	}
+
+	// create the transform based on the arg passed in by the user
+	transform, err := info.Builder(args[1:])
+	if err == flag.ErrHelp {
+		prog.WriteHelp(os.Stderr, name)
+		os.Exit(1)
+	}
+
+	if err != nil {
+		os.Exit(prog.ReportError(os.Stderr, name, cli.ArgumentError(err)))
+	}
+
+	// read runtime config from stdin
+	b, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		os.Exit(prog.ReportError(os.Stderr, name, err))
+	}
+
+	runtimeConfig := runtimeconfig.NewRuntimeConfig(b)
+	if runtimeConfig == nil {
+		os.Exit(prog.ReportError(os.Stderr, name, errors.New("invalid input runtime config")))
+	}
+
+	// apply the transformation
+	err = transform.Apply(runtimeConfig)
+	if err != nil {
+		os.Exit(prog.ReportError(os.Stderr, name, err))
+	}
+
+	// write the transformed manifest back to stdout
+	b, err = yaml.Marshal(runtimeConfig)
+	if err != nil {
+		os.Exit(prog.ReportError(os.Stderr, name, err))
+	}
+	os.Stdout.Write(b)
+}
+
+// program describes omg-transform-runtimeconfig and its transformations.
+func program() *cli.Program {
+	p := &cli.Program{
+		Name:        "omg-transform-runtimeconfig",
+		Description: "apply transformations to bosh runtime configs",
+		Version:     Version,
+		Commands: []cli.Command{
+			{Name: "help", Description: "show help for a transform"},
+			{Name: "docs", Description: "generate Markdown or man page reference docs"},
+			{Name: "completion", Description: "generate a bash, zsh or fish completion script"},
+		},
+	}
+	for _, t := range runtimeconfig.Transformations() {
+		ct := cli.Transformation{
+			Name:        t.Name,
+			Description: t.Description,
+			Usage:       t.Usage,
+			Examples:    t.Examples,
+			Complete:    t.Complete,
+		}
+		if t.Flags != nil {
+			ct.Flags = t.Flags()
+		}
+		p.Transformations = append(p.Transformations, ct)
+	}
+	return p
+}
@@ -0,0 +1,58 @@
+// Package errs defines the kinds of error returned by transformations
+// and the argument and precondition errors that the manifest,
+// cloudconfig and runtimeconfig packages share.
+package errs
+
+import "fmt"
+
+// The kinds of error returned by transformations.  Each error type
+// reports its kind with an ErrorKind method, so that callers can
+// classify errors from any package without knowing their types.
+const (
+	KindNotFound     = "not_found"
+	KindArgument     = "argument"
+	KindPrecondition = "precondition"
+	KindFailure      = "failure"
+)
+
+// ArgumentError is returned when the arguments to a transformation are
+// missing or invalid.
+type ArgumentError struct {
+	Flag    string `json:"flag,omitempty"` // the flag at fault, without the dash
+	Message string `json:"message"`
+}
+
+func (e *ArgumentError) Error() string { return e.Message }
+
+func (e *ArgumentError) ErrorKind() string { return KindArgument }
+
+// PreconditionError is returned when a document isn't in a state that
+// the transformation can be applied to.
+type PreconditionError struct {
+	Message string `json:"message"`
+}
+
+func (e *PreconditionError) Error() string { return e.Message }
+
+func (e *PreconditionError) ErrorKind() string { return KindPrecondition }
+
+// MissingFlag returns the error for a required flag that wasn't given.
+func MissingFlag(name string) error {
+	return &ArgumentError{Flag: name, Message: "missing required flag -" + name}
+}
+
+// InvalidFlag returns the error for a flag with an invalid value.
+func InvalidFlag(name, format string, args ...interface{}) error {
+	return &ArgumentError{Flag: name, Message: fmt.Sprintf(format, args...)}
+}
+
+// InvalidArgs returns the error for arguments that are invalid
+// together, or that aren't flags.
+func InvalidArgs(format string, args ...interface{}) error {
+	return &ArgumentError{Message: fmt.Sprintf(format, args...)}
+}
+
+// Preconditionf returns a PreconditionError with a formatted message.
+func Preconditionf(format string, args ...interface{}) error {
+	return &PreconditionError{Message: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,13 @@
+package errs
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestErrs(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Errs Suite")
+}
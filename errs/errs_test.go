@@ -0,0 +1,21 @@
+package errs
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("errors", func() {
+	It("reports the flag at fault for invalid arguments", func() {
+		Ω(MissingFlag("az")).Should(Equal(&ArgumentError{Flag: "az", Message: "missing required flag -az"}))
+		Ω(InvalidFlag("instances", "invalid value %d", -1)).Should(Equal(&ArgumentError{Flag: "instances", Message: "invalid value -1"}))
+		Ω(InvalidArgs("unexpected argument %q", "x")).Should(Equal(&ArgumentError{Message: `unexpected argument "x"`}))
+		Ω(MissingFlag("az").(*ArgumentError).ErrorKind()).Should(Equal(KindArgument))
+	})
+
+	It("reports failed preconditions", func() {
+		err := Preconditionf("instance group %s already exists", "router")
+		Ω(err).Should(MatchError("instance group router already exists"))
+		Ω(err.(*PreconditionError).ErrorKind()).Should(Equal(KindPrecondition))
+	})
+})
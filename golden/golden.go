@@ -0,0 +1,268 @@
+// Package golden compares the output of transformations with golden
+// files, either in full or as a structural diff of the input.
+package golden
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Diff returns the differences between two YAML documents, one line
+// per changed value, in the order the values appear:
+//
+//	~ instance_groups[name=router].instances: 2 -> 3
+//	+ instance_groups[name=router].vm_extensions[1]: "public-lb"
+//	- tags.owner: "ops"
+//
+// Lists whose elements all have a name (or alias) are compared by name
+// rather than by position, so that adding or removing one element
+// doesn't show up as a change to every element after it.  Values are
+// written as JSON.
+func Diff(before, after []byte) ([]string, error) {
+	var a, b interface{}
+	if err := yaml.Unmarshal(before, &a); err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(after, &b); err != nil {
+		return nil, err
+	}
+	var lines []string
+	diff("", normalize(a), normalize(b), &lines)
+	return lines, nil
+}
+
+func diff(path string, a, b interface{}, lines *[]string) {
+	switch {
+	case a == nil && b == nil:
+		return
+	case a == nil:
+		*lines = append(*lines, fmt.Sprintf("+ %s: %s", pathOrRoot(path), value(b)))
+		return
+	case b == nil:
+		*lines = append(*lines, fmt.Sprintf("- %s: %s", pathOrRoot(path), value(a)))
+		return
+	}
+
+	switch av := a.(type) {
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok {
+			break
+		}
+		for _, k := range unionKeys(av, bv) {
+			diff(join(path, k), av[k], bv[k], lines)
+		}
+		return
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok {
+			break
+		}
+		if key := listKey(av, bv); key != "" {
+			diffByKey(path, key, av, bv, lines)
+			return
+		}
+		for i := 0; i < len(av) || i < len(bv); i++ {
+			var ae, be interface{}
+			if i < len(av) {
+				ae = av[i]
+			}
+			if i < len(bv) {
+				be = bv[i]
+			}
+			diff(fmt.Sprintf("%s[%d]", path, i), ae, be, lines)
+		}
+		return
+	}
+
+	if value(a) != value(b) {
+		*lines = append(*lines, fmt.Sprintf("~ %s: %s -> %s", pathOrRoot(path), value(a), value(b)))
+	}
+}
+
+// diffByKey compares two lists of maps by the value of key, in the
+// order elements appear in a followed by those only in b.
+func diffByKey(path, key string, a, b []interface{}, lines *[]string) {
+	index := func(list []interface{}) (map[string]interface{}, []string) {
+		m := make(map[string]interface{}, len(list))
+		var order []string
+		for _, e := range list {
+			k := fmt.Sprint(e.(map[string]interface{})[key])
+			m[k] = e
+			order = append(order, k)
+		}
+		return m, order
+	}
+	am, aorder := index(a)
+	bm, border := index(b)
+
+	seen := make(map[string]bool)
+	for _, k := range append(aorder, border...) {
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		diff(fmt.Sprintf("%s[%s=%s]", path, key, k), am[k], bm[k], lines)
+	}
+}
+
+// listKey returns the key that identifies the elements of both lists,
+// or an empty string if they should be compared by position.
+func listKey(a, b []interface{}) string {
+	for _, key := range []string{"name", "alias"} {
+		ok := len(a)+len(b) > 0
+		seen := make(map[string]int)
+		for _, e := range append(append([]interface{}{}, a...), b...) {
+			m, isMap := e.(map[string]interface{})
+			if !isMap {
+				return ""
+			}
+			if _, has := m[key]; !has {
+				ok = false
+				break
+			}
+		}
+		// names must be unique within each list
+		for _, list := range [][]interface{}{a, b} {
+			for k := range seen {
+				delete(seen, k)
+			}
+			for _, e := range list {
+				if !ok {
+					break
+				}
+				k := fmt.Sprint(e.(map[string]interface{})[key])
+				seen[k]++
+				if seen[k] > 1 {
+					ok = false
+				}
+			}
+		}
+		if ok {
+			return key
+		}
+	}
+	return ""
+}
+
+// Check compares got with the golden file at path.  If update is set
+// the golden file is written instead, creating its directory if needed.
+func Check(path string, got []byte, update bool) error {
+	if update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		return ioutil.WriteFile(path, got, 0644)
+	}
+
+	want, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("golden file %s doesn't exist, run the tests with -update to create it", path)
+		}
+		return err
+	}
+	if bytes.Equal(want, got) {
+		return nil
+	}
+	return fmt.Errorf("output doesn't match golden file %s (run the tests with -update if the change is expected):\n%s",
+		path, lineDiff(string(want), string(got)))
+}
+
+// lineDiff lists the lines only in want (prefixed with '-') and only in
+// got (prefixed with '+').
+func lineDiff(want, got string) string {
+	wantLines := strings.Split(strings.TrimSuffix(want, "\n"), "\n")
+	gotLines := strings.Split(strings.TrimSuffix(got, "\n"), "\n")
+	count := func(lines []string) map[string]int {
+		m := make(map[string]int)
+		for _, l := range lines {
+			m[l]++
+		}
+		return m
+	}
+	inWant, inGot := count(wantLines), count(gotLines)
+
+	var buf bytes.Buffer
+	for _, l := range wantLines {
+		if inGot[l] > 0 {
+			inGot[l]--
+			continue
+		}
+		fmt.Fprintf(&buf, "-%s\n", l)
+	}
+	inGot = count(gotLines)
+	for _, l := range gotLines {
+		if inWant[l] > 0 {
+			inWant[l]--
+			continue
+		}
+		fmt.Fprintf(&buf, "+%s\n", l)
+	}
+	return buf.String()
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func pathOrRoot(path string) string {
+	if path == "" {
+		return "$"
+	}
+	return path
+}
+
+// value formats v as JSON, with map keys sorted.
+func value(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+func unionKeys(a, b map[string]interface{}) []string {
+	seen := make(map[string]bool, len(a)+len(b))
+	var keys []string
+	for _, m := range []map[string]interface{}{a, b} {
+		for k := range m {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// normalize converts the maps produced by the yaml package into maps
+// with string keys.
+func normalize(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[fmt.Sprint(k)] = normalize(e)
+		}
+		return m
+	case []interface{}:
+		for i := range v {
+			v[i] = normalize(v[i])
+		}
+		return v
+	}
+	return v
+}
@@ -0,0 +1,90 @@
+package golden
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("golden files", func() {
+	Context("when diffing YAML documents", func() {
+		It("reports changed, added and removed values", func() {
+			lines, err := Diff([]byte("a: 1\nb: {c: x}\nd: old\n"), []byte("a: 2\nb: {c: x, e: [1, 2]}\n"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(lines).Should(Equal([]string{
+				"~ a: 1 -> 2",
+				`+ b.e: [1,2]`,
+				`- d: "old"`,
+			}))
+		})
+
+		It("compares lists of named elements by name", func() {
+			before := []byte("groups:\n- {name: a, size: 1}\n- {name: b, size: 1}\n")
+			after := []byte("groups:\n- {name: b, size: 2}\n- {name: c, size: 1}\n")
+			lines, err := Diff(before, after)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(lines).Should(Equal([]string{
+				`- groups[name=a]: {"name":"a","size":1}`,
+				"~ groups[name=b].size: 1 -> 2",
+				`+ groups[name=c]: {"name":"c","size":1}`,
+			}))
+		})
+
+		It("compares other lists by position", func() {
+			lines, err := Diff([]byte("l: [x, v]\n"), []byte("l: [x, z, w]\n"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(lines).Should(Equal([]string{
+				`~ l[1]: "v" -> "z"`,
+				`+ l[2]: "w"`,
+			}))
+		})
+
+		It("reports nothing for identical documents", func() {
+			lines, err := Diff([]byte("a: [1, {b: 2}]\n"), []byte("a: [1, {b: 2}]\n"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(lines).Should(BeEmpty())
+		})
+
+		It("returns an error for invalid YAML", func() {
+			_, err := Diff([]byte("a: [\n"), []byte("a: 1\n"))
+			Ω(err).Should(HaveOccurred())
+		})
+	})
+
+	Context("when checking golden files", func() {
+		var dir string
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "golden")
+			Ω(err).ShouldNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(dir)
+		})
+
+		It("writes the golden file when updating", func() {
+			path := filepath.Join(dir, "golden", "out.diff")
+			Ω(Check(path, []byte("~ a: 1 -> 2\n"), true)).Should(Succeed())
+			Ω(Check(path, []byte("~ a: 1 -> 2\n"), false)).Should(Succeed())
+		})
+
+		It("reports the lines that differ", func() {
+			path := filepath.Join(dir, "out.diff")
+			Ω(Check(path, []byte("x\ny\n"), true)).Should(Succeed())
+
+			err := Check(path, []byte("x\nz\n"), false)
+			Ω(err).Should(HaveOccurred())
+			Ω(err.Error()).Should(ContainSubstring("-y\n+z\n"))
+		})
+
+		It("explains how to create a missing golden file", func() {
+			err := Check(filepath.Join(dir, "missing.diff"), nil, false)
+			Ω(err).Should(MatchError(ContainSubstring("run the tests with -update")))
+		})
+	})
+})
@@ -0,0 +1,173 @@
+package manifest
+
+import (
+	"flag"
+	"strings"
+
+	"github.com/enaml-ops/enaml"
+	"github.com/enaml-ops/omg-transform/cloudconfig"
+	"github.com/enaml-ops/omg-transform/errs"
+	"github.com/enaml-ops/omg-transform/registry"
+)
+
+// ErrandAdder is a transformation that adds an errand instance group
+// running a single job.
+//
+// Any settings that aren't provided are copied from an existing
+// instance group: the one named by From, or the first instance group
+// in the manifest.  Static IPs are never copied.
+type ErrandAdder struct {
+	Name    string // name of the new instance group
+	Job     string
+	Release string
+	VMType  string
+	Network string
+	AZs     []string
+	From    string // instance group to copy defaults from
+
+	// CloudConfig, if set, is used to check the vm type, network and
+	// AZs of the errand.
+	CloudConfig *enaml.CloudConfigManifest
+
+	azsFlag         string
+	cloudConfigFlag string
+}
+
+func (e *ErrandAdder) Apply(dm *enaml.DeploymentManifest) error {
+	if dm.GetInstanceGroupByName(e.Name) != nil {
+		return errs.Preconditionf("instance group %s already exists", e.Name)
+	}
+
+	var from *enaml.InstanceGroup
+	if e.From != "" {
+		from = dm.GetInstanceGroupByName(e.From)
+		if from == nil {
+			return instanceGroupNotFound(dm, e.From)
+		}
+	} else if len(dm.InstanceGroups) > 0 {
+		from = dm.InstanceGroups[0]
+	}
+
+	errand := &enaml.InstanceGroup{
+		Name:      e.Name,
+		Instances: 1,
+		Lifecycle: LifecycleErrand,
+		VMType:    e.VMType,
+		AZs:       e.AZs,
+		Jobs: []enaml.InstanceJob{
+			{Name: e.Job, Release: e.Release},
+		},
+	}
+	if e.Network != "" {
+		errand.Networks = []enaml.Network{{Name: e.Network}}
+	}
+
+	if from != nil {
+		if errand.VMType == "" {
+			errand.VMType = from.VMType
+		}
+		if len(errand.AZs) == 0 {
+			errand.AZs = append([]string(nil), from.AZs...)
+		}
+		if len(errand.Networks) == 0 {
+			for _, n := range from.Networks {
+				errand.Networks = append(errand.Networks, enaml.Network{Name: n.Name, Default: n.Default})
+			}
+		}
+		errand.Stemcell = from.Stemcell
+		errand.Update = from.Update
+	} else if len(dm.Stemcells) > 0 {
+		errand.Stemcell = dm.Stemcells[0].Alias
+	}
+
+	if errand.VMType == "" {
+		return errs.InvalidFlag("vm-type", "no vm type provided and no instance group to copy it from")
+	}
+	if len(errand.Networks) == 0 {
+		return errs.InvalidFlag("network", "no network provided and no instance group to copy it from")
+	}
+	if indexOfRelease(dm.Releases, e.Release) < 0 {
+		return errs.Preconditionf("release %s is not part of the deployment", e.Release)
+	}
+	if e.CloudConfig != nil {
+		if err := checkPlacement(e.CloudConfig, errand); err != nil {
+			return err
+		}
+	}
+
+	return dm.AddInstanceGroup(errand)
+}
+
+// Inverse removes the errand.
+func (e *ErrandAdder) Inverse(dm *enaml.DeploymentManifest) ([]Step, error) {
+	return []Step{{Transform: "remove-instance-group", Args: []string{"-instance-group", e.Name}}}, nil
+}
+
+// checkPlacement checks that the vm type, networks and AZs of an
+// instance group are defined in the cloud config.
+func checkPlacement(cc *enaml.CloudConfigManifest, ig *enaml.InstanceGroup) error {
+	if err := cloudconfig.CheckVMType(cc, ig.VMType); err != nil {
+		return err
+	}
+	for _, n := range ig.Networks {
+		if err := cloudconfig.CheckNetwork(cc, n.Name); err != nil {
+			return err
+		}
+	}
+	for _, az := range ig.AZs {
+		if err := cloudconfig.CheckAZ(cc, az); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *ErrandAdder) flagSet() *flag.FlagSet {
+	fs := registry.NewFlagSet("add-errand")
+	fs.StringVar(&e.Name, "name", "", "name of the new errand instance group (defaults to the job name)")
+	fs.StringVar(&e.Job, "job", "", "name of the job the errand runs")
+	fs.StringVar(&e.Release, "release", "", "the release that provides the job")
+	fs.StringVar(&e.VMType, "vm-type", "", "the vm type to use")
+	fs.StringVar(&e.Network, "network", "", "the name of the network to use")
+	fs.StringVar(&e.azsFlag, "az", "", "a comma separated list of az names")
+	fs.StringVar(&e.From, "from", "", "an instance group to copy unspecified settings from")
+	fs.StringVar(&e.cloudConfigFlag, "cloud-config", "", "path to a cloud config used to check the vm type, network and AZs")
+	return fs
+}
+
+// AddErrandTransformation is a TransformationBuilder that builds the
+// 'add-errand' transformation.
+func AddErrandTransformation(args []string) (Transformation, error) {
+	e := &ErrandAdder{}
+	fs := e.flagSet()
+	err := fs.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+
+	if e.Job == "" {
+		return nil, errs.MissingFlag("job")
+	}
+	if e.Release == "" {
+		return nil, errs.MissingFlag("release")
+	}
+	if e.Name == "" {
+		e.Name = e.Job
+	}
+	if e.azsFlag != "" {
+		if strings.Contains(e.azsFlag, " ") {
+			return nil, errs.InvalidFlag("az", "invalid format for az, cannot contain space")
+		}
+		e.AZs = registry.Split(e.azsFlag, ",")
+		if len(e.AZs) == 0 {
+			return nil, errs.InvalidFlag("az", "invalid format for az, must be comma-separated")
+		}
+	}
+	if e.cloudConfigFlag != "" {
+		e.CloudConfig, err = readCloudConfig(e.cloudConfigFlag)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
@@ -0,0 +1,101 @@
+package manifest
+
+import (
+	"os"
+
+	"github.com/enaml-ops/enaml"
+	"github.com/enaml-ops/omg-transform/cloudconfig"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("add errand transformation", func() {
+	Context("when creating the transformation", func() {
+		It("returns an error if no arguments are provided", func() {
+			_, err := AddErrandTransformation(nil)
+			Ω(err).Should(HaveOccurred())
+		})
+
+		It("returns an error if the job argument is missing", func() {
+			_, err := AddErrandTransformation([]string{"-release", "cf"})
+			Ω(err).Should(HaveOccurred())
+		})
+
+		It("returns an error if the release argument is missing", func() {
+			_, err := AddErrandTransformation([]string{"-job", "smoke-tests"})
+			Ω(err).Should(HaveOccurred())
+		})
+
+		It("returns an error if the az argument is malformed", func() {
+			_, err := AddErrandTransformation([]string{"-job", "smoke-tests", "-release", "cf", "-az", "az1 az2"})
+			Ω(err).Should(HaveOccurred())
+		})
+
+		It("names the errand after the job by default", func() {
+			t, err := AddErrandTransformation([]string{"-job", "smoke-tests", "-release", "cf", "-az", "az1,az2"})
+			Ω(err).ShouldNot(HaveOccurred())
+
+			e := t.(*ErrandAdder)
+			Ω(e.Name).Should(Equal("smoke-tests"))
+			Ω(e.AZs).Should(Equal([]string{"az1", "az2"}))
+		})
+	})
+
+	Context("PCF 1.8 AWS manifest", func() {
+		var manifest *enaml.DeploymentManifest
+
+		BeforeEach(func() {
+			f, err := os.Open("fixtures/pcf-aws-1.8.00-build.373.yml")
+			Ω(err).ShouldNot(HaveOccurred())
+			manifest = enaml.NewDeploymentManifestFromFile(f)
+		})
+
+		It("adds an errand with the provided settings", func() {
+			expectGolden(goldenCase{
+				Transform: "add-errand",
+				Args: []string{"-name", "rotate-certs", "-job", "rotate_certs", "-release", "cf",
+					"-vm-type", "t2.micro", "-network", "services", "-az", "z1"},
+				Golden: "add-errand-rotate-certs.diff",
+			})
+		})
+
+		It("copies defaults from an existing instance group", func() {
+			expectGolden(goldenCase{
+				Transform: "add-errand",
+				Args:      []string{"-name", "rotate-certs", "-job", "rotate_certs", "-release", "cf", "-from", "router"},
+				Golden:    "add-errand-rotate-certs-from-router.diff",
+			})
+		})
+
+		It("returns an error if the instance group already exists", func() {
+			e := ErrandAdder{Name: "smoke-tests", Job: "smoke-tests", Release: "cf"}
+			Ω(e.Apply(manifest)).ShouldNot(Succeed())
+		})
+
+		It("returns an error if the release isn't in the deployment", func() {
+			e := ErrandAdder{Name: "rotate-certs", Job: "rotate_certs", Release: "credhub"}
+			Ω(e.Apply(manifest)).ShouldNot(Succeed())
+		})
+
+		It("returns an error when copying from an invalid instance group", func() {
+			e := ErrandAdder{Name: "rotate-certs", Job: "rotate_certs", Release: "cf", From: "foobar"}
+			Ω(e.Apply(manifest)).ShouldNot(Succeed())
+		})
+
+		It("checks the vm type, network and AZs against a cloud config", func() {
+			cloudConfig, err := readCloudConfig("fixtures/cloud-config-aws.yml")
+			Ω(err).ShouldNot(HaveOccurred())
+
+			e := ErrandAdder{Name: "rotate-certs", Job: "rotate_certs", Release: "cf", VMType: "t2-micro", CloudConfig: cloudConfig}
+			Ω(e.Apply(manifest)).Should(MatchError("vm type t2-micro is not defined in the cloud config, did you mean t2.micro?"))
+
+			e = ErrandAdder{Name: "rotate-certs", Job: "rotate_certs", Release: "cf", AZs: []string{"us-west-1a"}, CloudConfig: cloudConfig}
+			err = e.Apply(manifest)
+			Ω(err).Should(BeAssignableToTypeOf(&cloudconfig.ErrAZNotFound{}))
+			Ω(err.(*cloudconfig.ErrAZNotFound).Suggestions).Should(Equal([]string{"us-west-1b", "us-west-1c"}))
+
+			e = ErrandAdder{Name: "rotate-certs", Job: "rotate_certs", Release: "cf", CloudConfig: cloudConfig}
+			Ω(e.Apply(manifest)).Should(Succeed())
+		})
+	})
+})
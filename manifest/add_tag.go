@@ -1,63 +1,235 @@
 package manifest
 
 import (
-	"errors"
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"sort"
 	"strings"
 
 	"github.com/enaml-ops/enaml"
+	"github.com/enaml-ops/omg-transform/errs"
+	"github.com/enaml-ops/omg-transform/registry"
+	yaml "gopkg.in/yaml.v2"
 )
 
+// TagAdder is a transformation that adds key-value pairs for VM tagging.
+// Tags are added to the deployment, or to a single instance group's
+// bosh env if InstanceGroup is set.
 type TagAdder struct {
-	Args []string
+	Args          []string // tags in key=value format
+	InstanceGroup string
+	file          string
+	envPrefix     string
 }
 
 func (t *TagAdder) Apply(dm *enaml.DeploymentManifest) error {
-	for _, arg := range t.Args {
-		parts := strings.Split(arg, "=")
-		if len(parts) != 2 {
-			continue
+	tags, err := parseTags(t.Args)
+	if err != nil {
+		return err
+	}
+
+	if t.InstanceGroup == "" {
+		for _, tag := range tags {
+			dm.AddTag(tag[0], tag[1])
 		}
-		key, value := parts[0], parts[1]
-		dm.AddTag(key, value)
+		return nil
 	}
+
+	ig := dm.GetInstanceGroupByName(t.InstanceGroup)
+	if ig == nil {
+		return instanceGroupNotFound(dm, t.InstanceGroup)
+	}
+	igTags := instanceGroupTags(ig)
+	for _, tag := range tags {
+		igTags[tag[0]] = tag[1]
+	}
+	setInstanceGroupTags(ig, igTags)
 	return nil
 }
 
-func (t *TagAdder) flagSet() *flag.FlagSet {
-	fs := flag.NewFlagSet("add-tag", flag.ContinueOnError)
-	fs.Usage = func() {
-		fmt.Fprintln(os.Stderr, "Usage of add-tags:")
-		fmt.Fprintln(os.Stderr, "   add-tags key=value key=value ...")
+func (t *TagAdder) Idempotent() bool { return true }
+
+// Inverse removes the tags that are added and restores the values of
+// the tags that are replaced.
+func (t *TagAdder) Inverse(dm *enaml.DeploymentManifest) ([]Step, error) {
+	tags, err := parseTags(t.Args)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := currentTags(dm, t.InstanceGroup)
+	if err != nil {
+		return nil, err
+	}
+
+	var added, replaced []string
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		if seen[tag[0]] {
+			continue
+		}
+		seen[tag[0]] = true
+		if v, ok := existing[tag[0]]; !ok {
+			added = append(added, tag[0])
+		} else if v != tag[1] {
+			replaced = append(replaced, tag[0]+"="+v)
+		}
+	}
+	return tagSteps(t.InstanceGroup, added, replaced)
+}
+
+// currentTags returns the tags of the deployment, or of an instance
+// group if instanceGroup is set.
+func currentTags(dm *enaml.DeploymentManifest, instanceGroup string) (map[string]string, error) {
+	if instanceGroup == "" {
+		return dm.Tags, nil
+	}
+	ig := dm.GetInstanceGroupByName(instanceGroup)
+	if ig == nil {
+		return nil, instanceGroupNotFound(dm, instanceGroup)
+	}
+	return instanceGroupTags(ig), nil
+}
+
+// tagSteps returns the steps that remove the named tags and then add
+// the tags in key=value format, on the deployment or an instance group.
+func tagSteps(instanceGroup string, remove, add []string) ([]Step, error) {
+	var opts []string
+	if instanceGroup != "" {
+		opts = []string{"-instance-group", instanceGroup}
+	}
+
+	var steps []Step
+	if len(remove) > 0 {
+		steps = append(steps, Step{Transform: "remove-tags", Args: append(append([]string(nil), opts...), remove...)})
+	}
+	if len(add) > 0 {
+		if _, err := parseTags(add); err != nil {
+			return nil, fmt.Errorf("tags can't be restored: %v", err)
+		}
+		steps = append(steps, Step{Transform: "add-tags", Args: append(append([]string(nil), opts...), add...)})
+	}
+	return steps, nil
+}
+
+// parseTags parses tags in key=value format.
+func parseTags(args []string) ([][2]string, error) {
+	tags := make([][2]string, 0, len(args))
+	for _, arg := range args {
+		if c := strings.Count(arg, "="); c != 1 {
+			return nil, errs.InvalidArgs("invalid tag specifier %q, expected format key=value", arg)
+		}
+		parts := strings.Split(arg, "=")
+		if parts[0] == "" || parts[1] == "" {
+			return nil, errs.InvalidArgs("invalid tag specifier %q, expected format key=value", arg)
+		}
+		tags = append(tags, [2]string{parts[0], parts[1]})
+	}
+	return tags, nil
+}
+
+// instanceGroupTags returns the tags in an instance group's bosh env.
+func instanceGroupTags(ig *enaml.InstanceGroup) map[string]string {
+	tags := make(map[string]string)
+	bosh, _ := stringMap(ig.Env["bosh"])
+	existing, _ := stringMap(bosh["tags"])
+	for k, v := range existing {
+		tags[k] = fmt.Sprint(v)
+	}
+	return tags
+}
+
+// setInstanceGroupTags replaces the tags in an instance group's bosh env,
+// removing the tags section entirely if there are no tags.
+func setInstanceGroupTags(ig *enaml.InstanceGroup, tags map[string]string) {
+	bosh, ok := stringMap(ig.Env["bosh"])
+	if !ok {
+		if len(tags) == 0 {
+			return
+		}
+		bosh = make(map[string]interface{})
+	}
+	if len(tags) == 0 {
+		delete(bosh, "tags")
+	} else {
+		bosh["tags"] = tags
+	}
+	if ig.Env == nil {
+		ig.Env = make(map[string]interface{})
+	}
+	ig.Env["bosh"] = bosh
+}
+
+// tagsFromFile reads tags from a YAML file containing a map of
+// tag names to values.
+func tagsFromFile(path string) ([]string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]string
+	if err = yaml.Unmarshal(b, &m); err != nil {
+		return nil, errs.InvalidFlag("file", "invalid tags file %s: %v", path, err)
 	}
+	var tags []string
+	for k, v := range m {
+		tags = append(tags, k+"="+v)
+	}
+	sort.Strings(tags)
+	return tags, nil
+}
+
+// tagsFromEnv builds tags from the environment variables whose names
+// start with prefix.  The prefix is removed to form the tag name, so
+// with a prefix of TAG_, TAG_owner=team becomes the tag owner=team.
+func tagsFromEnv(prefix string, environ []string) []string {
+	var tags []string
+	for _, kv := range environ {
+		if strings.HasPrefix(kv, prefix) {
+			tags = append(tags, strings.TrimPrefix(kv, prefix))
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+func (t *TagAdder) flagSet() *flag.FlagSet {
+	fs := registry.NewFlagSet("add-tags")
+	fs.StringVar(&t.InstanceGroup, "instance-group", "", "add the tags to this instance group's bosh env instead of the deployment")
+	fs.StringVar(&t.file, "file", "", "read tags from a YAML file of key: value pairs")
+	fs.StringVar(&t.envPrefix, "env-prefix", "", "read tags from environment variables with this prefix")
 	return fs
 }
 
 func AddTagsTransformation(args []string) (Transformation, error) {
 	t := &TagAdder{}
 
-	// we don't actually need a FlagSet here, but we use it to get a nice
-	// usage message if given a `-help` argument.
+	// we use a FlagSet to parse the options, the tags are the
+	// remaining arguments.
 	fs := t.flagSet()
 	err := fs.Parse(args)
 	if err != nil {
 		return nil, err
 	}
-	if len(args) == 0 {
-		return nil, errors.New("missing tag specifier(s) [format key=value]")
-	}
 
-	t.Args = fs.Args()
-	for _, arg := range t.Args {
-		if c := strings.Count(arg, "="); c != 1 {
-			return nil, fmt.Errorf("invalid tag specifier %q, expected format key=value", arg)
-		}
-		parts := strings.Split(arg, "=")
-		if parts[0] == "" || parts[1] == "" {
-			return nil, fmt.Errorf("invalid tag specifier %q, expected format key=value", arg)
+	if t.file != "" {
+		tags, err := tagsFromFile(t.file)
+		if err != nil {
+			return nil, err
 		}
+		t.Args = append(t.Args, tags...)
+	}
+	if t.envPrefix != "" {
+		t.Args = append(t.Args, tagsFromEnv(t.envPrefix, os.Environ())...)
+	}
+	t.Args = append(t.Args, fs.Args()...)
+	if len(t.Args) == 0 {
+		return nil, errs.InvalidArgs("missing tag specifier(s) [format key=value]")
+	}
+
+	if _, err := parseTags(t.Args); err != nil {
+		return nil, err
 	}
 	return t, nil
 }
@@ -1,6 +1,8 @@
 package manifest
 
 import (
+	"fmt"
+	"io/ioutil"
 	"os"
 
 	"github.com/enaml-ops/enaml"
@@ -45,6 +47,37 @@ var _ = Describe("add tags transformation", func() {
 			Ω(ok).Should(BeTrue())
 			Ω(at.Args).Should(HaveLen(2))
 		})
+
+		It("returns an error if the tags file doesn't exist", func() {
+			_, err := AddTagsTransformation([]string{"-file", "fixtures/does-not-exist.yml"})
+			Ω(err).Should(HaveOccurred())
+		})
+
+		It("reads tags from a file", func() {
+			f, err := ioutil.TempFile("", "tags")
+			Ω(err).ShouldNot(HaveOccurred())
+			defer os.Remove(f.Name())
+			fmt.Fprintln(f, "owner: cloudops\ncost-center: \"1234\"")
+			f.Close()
+
+			t, err := AddTagsTransformation([]string{"-file", f.Name(), "key1=value1"})
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(t.(*TagAdder).Args).Should(Equal([]string{"cost-center=1234", "owner=cloudops", "key1=value1"}))
+		})
+
+		It("reads tags from environment variables", func() {
+			os.Setenv("OMG_TEST_TAG_owner", "cloudops")
+			defer os.Unsetenv("OMG_TEST_TAG_owner")
+
+			t, err := AddTagsTransformation([]string{"-env-prefix", "OMG_TEST_TAG_"})
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(t.(*TagAdder).Args).Should(Equal([]string{"owner=cloudops"}))
+		})
+
+		It("returns an error if no tags are found in the environment", func() {
+			_, err := AddTagsTransformation([]string{"-env-prefix", "OMG_TEST_TAG_UNSET_"})
+			Ω(err).Should(HaveOccurred())
+		})
 	})
 
 	Context("PCF 1.8 AWS manifest", func() {
@@ -58,13 +91,34 @@ var _ = Describe("add tags transformation", func() {
 		})
 
 		It("adds tags", func() {
+			expectGolden(goldenCase{
+				Transform: "add-tags",
+				Args:      []string{"key1=value1", "key2=value2"},
+				Golden:    "add-tags.diff",
+			})
+		})
+
+		It("returns an error for malformed tags", func() {
+			t := &TagAdder{
+				Args: []string{"key1=value1", "key2"},
+			}
+			Ω(t.Apply(manifest)).ShouldNot(Succeed())
+		})
+
+		It("adds tags to an instance group", func() {
+			expectGolden(goldenCase{
+				Transform: "add-tags",
+				Args:      []string{"-instance-group", "router", "key1=value1"},
+				Golden:    "add-tags-router.diff",
+			})
+		})
+
+		It("returns an error when given an invalid instance group", func() {
 			t := &TagAdder{
-				Args: []string{"key1=value1", "key2=value2"},
+				Args:          []string{"key1=value1"},
+				InstanceGroup: "foobar",
 			}
-			Ω(t.Apply(manifest)).Should(Succeed())
-			Ω(manifest.Tags).Should(HaveLen(2))
-			Ω(manifest.Tag("key1")).Should(Equal("value1"))
-			Ω(manifest.Tag("key2")).Should(Equal("value2"))
+			Ω(t.Apply(manifest)).ShouldNot(Succeed())
 		})
 	})
 })
@@ -1,14 +1,16 @@
 package manifest
 
 import (
-	"errors"
 	"flag"
-	"fmt"
+	"strings"
 
 	"github.com/enaml-ops/enaml"
+	"github.com/enaml-ops/omg-transform/errs"
+	"github.com/enaml-ops/omg-transform/registry"
 )
 
-// VMExtension is a transformation that adds a vm extension to the given instance group
+// VMExtension is a transformation that adds a vm extension to the given instance group.
+// Extensions that the instance group already has are not added again.
 type VMExtension struct {
 	Name          string
 	InstanceGroup string
@@ -18,14 +20,39 @@ type VMExtension struct {
 func (ve *VMExtension) Apply(dm *enaml.DeploymentManifest) error {
 	ig := dm.GetInstanceGroupByName(ve.InstanceGroup)
 	if ig == nil {
-		return fmt.Errorf("couldn't find instance group %s", ve.InstanceGroup)
+		return instanceGroupNotFound(dm, ve.InstanceGroup)
+	}
+	for _, ext := range ve.Extensions {
+		if !contains(ig.VMExtensions, ext) {
+			ig.VMExtensions = append(ig.VMExtensions, ext)
+		}
 	}
-	ig.VMExtensions = append(ig.VMExtensions, ve.Extensions...)
 	return nil
 }
 
+func (ve *VMExtension) Idempotent() bool { return true }
+
+// Inverse removes the extensions that the instance group doesn't
+// already have.
+func (ve *VMExtension) Inverse(dm *enaml.DeploymentManifest) ([]Step, error) {
+	ig := dm.GetInstanceGroupByName(ve.InstanceGroup)
+	if ig == nil {
+		return nil, instanceGroupNotFound(dm, ve.InstanceGroup)
+	}
+	var added []string
+	for _, ext := range ve.Extensions {
+		if !contains(ig.VMExtensions, ext) && !contains(added, ext) {
+			added = append(added, ext)
+		}
+	}
+	if len(added) == 0 {
+		return nil, nil
+	}
+	return []Step{{Transform: "remove-vm-extension", Args: []string{"-instance-group", ig.Name, "-name", strings.Join(added, ",")}}}, nil
+}
+
 func (ve *VMExtension) flagSet() *flag.FlagSet {
-	fs := flag.NewFlagSet("add-vm-extension", flag.ContinueOnError)
+	fs := registry.NewFlagSet("add-vm-extension")
 	fs.StringVar(&ve.InstanceGroup, "instance-group", "", "Name of the instance group")
 	fs.StringVar(&ve.Name, "name", "", "Name(s) of the vm extension [If multiple, comma separate values]")
 	return fs
@@ -42,14 +69,14 @@ func AddVMExtensionTransformation(args []string) (Transformation, error) {
 	}
 
 	if ve.InstanceGroup == "" {
-		return nil, errors.New("missing required flag instance-group")
+		return nil, errs.MissingFlag("instance-group")
 	}
 	if ve.Name == "" {
-		return nil, errors.New("missing required flag name")
+		return nil, errs.MissingFlag("name")
 	}
-	ve.Extensions = split(ve.Name, ",")
+	ve.Extensions = registry.Split(ve.Name, ",")
 	if len(ve.Extensions) == 0 {
-		return nil, errors.New("invalid format for extension names, must be comma-separated")
+		return nil, errs.InvalidFlag("name", "invalid format for extension names, must be comma-separated")
 	}
 
 	return ve, nil
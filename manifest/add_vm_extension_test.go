@@ -63,15 +63,11 @@ var _ = Describe("add vm extension", func() {
 		})
 
 		It("add vm-extensions to an existing instance group", func() {
-			ve := VMExtension{
-				InstanceGroup: "mysql_proxy",
-				Extensions:    []string{"public-lbs1"},
-			}
-			Ω(ve.Apply(manifest)).Should(Succeed())
-
-			ig := manifest.GetInstanceGroupByName("mysql_proxy")
-			Ω(ig.VMExtensions).Should(HaveLen(1))
-			Ω(ig.VMExtensions[0]).Should(Equal(ve.Extensions[0]))
+			expectGolden(goldenCase{
+				Transform: "add-vm-extension",
+				Args:      []string{"-instance-group", "mysql_proxy", "-name", "public-lbs1"},
+				Golden:    "add-vm-extension-mysql-proxy.diff",
+			})
 		})
 
 		It("add vm-extensions to an existing instance group where one extension name with comma", func() {
@@ -87,30 +83,31 @@ var _ = Describe("add vm extension", func() {
 		})
 
 		It("add vm-extensions to an existing instance group where one extension already exists", func() {
-			ve := VMExtension{
-				InstanceGroup: "nats",
-				Extensions:    []string{"public-lbs1,"},
-			}
-			Ω(ve.Apply(manifest)).Should(Succeed())
-
-			ig := manifest.GetInstanceGroupByName("nats")
-			Ω(ig.VMExtensions).Should(HaveLen(2))
-			Ω(ig.VMExtensions[0]).Should(Equal("test"))
-			Ω(ig.VMExtensions[1]).Should(Equal(ve.Extensions[0]))
-
+			expectGolden(goldenCase{
+				Transform: "add-vm-extension",
+				Args:      []string{"-instance-group", "nats", "-name", "public-lbs1,"},
+				Golden:    "add-vm-extension-nats.diff",
+			})
 		})
 
 		It("add vm-extensions to an existing instance group with multiple names", func() {
+			expectGolden(goldenCase{
+				Transform: "add-vm-extension",
+				Args:      []string{"-instance-group", "mysql_proxy", "-name", "public-lbs1,public-lbs2"},
+				Golden:    "add-vm-extension-mysql-proxy-multiple.diff",
+			})
+		})
+
+		It("doesn't add extensions that already exist", func() {
 			ve := VMExtension{
-				InstanceGroup: "mysql_proxy",
-				Extensions:    []string{"public-lbs1", "public-lbs2"},
+				InstanceGroup: "nats",
+				Extensions:    []string{"test", "public-lbs1", "public-lbs1"},
 			}
 			Ω(ve.Apply(manifest)).Should(Succeed())
+			Ω(ve.Apply(manifest)).Should(Succeed())
 
-			ig := manifest.GetInstanceGroupByName("mysql_proxy")
-			Ω(ig.VMExtensions).Should(HaveLen(2))
-			Ω(ig.VMExtensions[0]).Should(Equal(ve.Extensions[0]))
-			Ω(ig.VMExtensions[1]).Should(Equal(ve.Extensions[1]))
+			ig := manifest.GetInstanceGroupByName("nats")
+			Ω(ig.VMExtensions).Should(Equal([]string{"test", "public-lbs1"}))
 		})
 
 	})
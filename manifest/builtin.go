@@ -0,0 +1,242 @@
+package manifest
+
+import (
+	"flag"
+
+	"github.com/enaml-ops/omg-transform/registry"
+)
+
+func init() {
+	RegisterTransformation(TransformationInfo{
+		Info: registry.Info{
+			Name:        "change-network",
+			Description: "change an instance group's network",
+			Usage:       "-instance-group name -network name [-static-ips ranges] [-cloud-config file]",
+			Examples: []string{
+				"change-network -instance-group router -network public",
+				"change-network -instance-group router -network public -static-ips 10.0.16.10-10.0.16.12",
+			},
+			Flags: func() *flag.FlagSet { return new(NetworkMover).flagSet() },
+			Complete: map[string]string{
+				"instance-group": "instance-group",
+				"network":        "network",
+			},
+		},
+		Builder: ChangeNetworkTransformation,
+	})
+	RegisterTransformation(TransformationInfo{
+		Info: registry.Info{
+			Name:        "clone",
+			Description: "clone an instance group",
+			Usage:       "-instance-group name -clone name",
+			Examples: []string{
+				"clone -instance-group router -clone router_internal",
+			},
+			Flags: func() *flag.FlagSet { return new(Cloner).flagSet() },
+			Complete: map[string]string{
+				"instance-group": "instance-group",
+			},
+		},
+		Builder: CloneTransformation,
+	})
+	RegisterTransformation(TransformationInfo{
+		Info: registry.Info{
+			Name:        "remove-instance-group",
+			Description: "remove instance groups from the deployment",
+			Usage:       "-instance-group names",
+			Examples: []string{
+				"remove-instance-group -instance-group router_internal",
+			},
+			Flags: func() *flag.FlagSet { return new(InstanceGroupRemover).flagSet() },
+			Complete: map[string]string{
+				"instance-group": "instance-group",
+			},
+		},
+		Builder: RemoveInstanceGroupTransformation,
+	})
+	RegisterTransformation(TransformationInfo{
+		Info: registry.Info{
+			Name:        "change-az",
+			Description: "change an instance group's AZs, optionally rebalancing instances and static IPs",
+			Usage:       "-instance-group name -az azs [-rebalance] [-cloud-config file] [-plan]",
+			Examples: []string{
+				"change-az -instance-group diego_cell -az z1,z2,z3",
+				"change-az -instance-group router -az z1,z2 -rebalance -cloud-config cloud-config.yml -plan",
+			},
+			Flags: func() *flag.FlagSet { return new(AZChanger).flagSet() },
+			Complete: map[string]string{
+				"instance-group": "instance-group",
+				"az":             "az",
+			},
+		},
+		Builder: ChangeAZTransformation,
+	})
+	RegisterTransformation(TransformationInfo{
+		Info: registry.Info{
+			Name:        "make-ha",
+			Description: "spread every service instance group across AZs with the instance counts of an HA profile",
+			Usage:       "-az azs [-profile name] [-cloud-config file] [-plan]",
+			Examples: []string{
+				"make-ha -az us-west-1a,us-west-1b,us-west-1c -cloud-config cloud-config.yml",
+			},
+			Flags: func() *flag.FlagSet { return new(HAMaker).flagSet() },
+			Complete: map[string]string{
+				"az": "az",
+			},
+		},
+		Builder: MakeHATransformation,
+	})
+	RegisterTransformation(TransformationInfo{
+		Info: registry.Info{
+			Name:        "scale",
+			Description: "change the number of instances in an instance group",
+			Usage:       "-instance-group name -instances count",
+			Examples: []string{
+				"scale -instance-group diego_cell -instances 6",
+			},
+			Flags: func() *flag.FlagSet { return new(ScaleInstance).flagSet() },
+			Complete: map[string]string{
+				"instance-group": "instance-group",
+			},
+		},
+		Builder: ScaleInstanceTransform,
+	})
+	RegisterTransformation(TransformationInfo{
+		Info: registry.Info{
+			Name:        "add-tags",
+			Description: "add key-value pairs for VM tagging",
+			Usage:       "[-instance-group name] [-file tags.yml] [-env-prefix PREFIX] key=value ...",
+			Examples: []string{
+				"add-tags owner=cloudops cost-center=1234",
+				"add-tags -file tags.yml",
+				"add-tags -env-prefix TAG_ -instance-group diego_cell",
+			},
+			Flags: func() *flag.FlagSet { return new(TagAdder).flagSet() },
+			Complete: map[string]string{
+				"instance-group": "instance-group",
+			},
+		},
+		Builder: AddTagsTransformation,
+	})
+	RegisterTransformation(TransformationInfo{
+		Info: registry.Info{
+			Name:        "remove-tags",
+			Description: "remove VM tags",
+			Usage:       "[-instance-group name] key ...",
+			Examples: []string{
+				"remove-tags owner cost-center",
+			},
+			Flags: func() *flag.FlagSet { return new(TagRemover).flagSet() },
+			Complete: map[string]string{
+				"instance-group": "instance-group",
+			},
+		},
+		Builder: RemoveTagsTransformation,
+	})
+	RegisterTransformation(TransformationInfo{
+		Info: registry.Info{
+			Name:        "add-vm-extension",
+			Description: "add a vm extension to an existing instance group",
+			Usage:       "-instance-group name -name extensions",
+			Examples: []string{
+				"add-vm-extension -instance-group router -name public-lbs",
+			},
+			Flags: func() *flag.FlagSet { return new(VMExtension).flagSet() },
+			Complete: map[string]string{
+				"instance-group": "instance-group",
+				"name":           "vm-extension",
+			},
+		},
+		Builder: AddVMExtensionTransformation,
+	})
+	RegisterTransformation(TransformationInfo{
+		Info: registry.Info{
+			Name:        "remove-vm-extension",
+			Description: "remove a vm extension from an existing instance group",
+			Usage:       "-instance-group name -name extensions",
+			Examples: []string{
+				"remove-vm-extension -instance-group router -name public-lbs",
+			},
+			Flags: func() *flag.FlagSet { return new(VMExtensionRemover).flagSet() },
+			Complete: map[string]string{
+				"instance-group": "instance-group",
+				"name":           "vm-extension",
+			},
+		},
+		Builder: RemoveVMExtensionTransformation,
+	})
+	RegisterTransformation(TransformationInfo{
+		Info: registry.Info{
+			Name:        "change-lifecycle",
+			Description: "switch an instance group between a service and an errand",
+			Usage:       "-instance-group name -lifecycle service|errand",
+			Examples: []string{
+				"change-lifecycle -instance-group smoke-tests -lifecycle errand",
+			},
+			Flags: func() *flag.FlagSet { return new(LifecycleChanger).flagSet() },
+			Complete: map[string]string{
+				"instance-group": "instance-group",
+			},
+		},
+		Builder: ChangeLifecycleTransformation,
+	})
+	RegisterTransformation(TransformationInfo{
+		Info: registry.Info{
+			Name:        "add-errand",
+			Description: "add an errand instance group, copying defaults from an existing group",
+			Usage:       "-job name -release name [-name name] [-vm-type type] [-network name] [-az azs] [-from name] [-cloud-config file]",
+			Examples: []string{
+				"add-errand -job smoke_tests -release cf -from smoke-tests -name smoke-tests-2",
+			},
+			Flags: func() *flag.FlagSet { return new(ErrandAdder).flagSet() },
+			Complete: map[string]string{
+				"vm-type": "vm-type",
+				"network": "network",
+				"az":      "az",
+				"from":    "instance-group",
+			},
+		},
+		Builder: AddErrandTransformation,
+	})
+	RegisterTransformation(TransformationInfo{
+		Info: registry.Info{
+			Name:        "split",
+			Description: "move instance groups into a new deployment manifest",
+			Usage:       "-instance-group names -deployment name -output file",
+			Examples: []string{
+				"split -instance-group diego_cell,diego_brain -deployment cf-diego -output cf-diego.yml",
+			},
+			Flags: func() *flag.FlagSet { return new(Splitter).flagSet() },
+			Complete: map[string]string{
+				"instance-group": "instance-group",
+			},
+		},
+		Builder: SplitTransformation,
+	})
+	RegisterTransformation(TransformationInfo{
+		Info: registry.Info{
+			Name:        "merge",
+			Description: "merge another deployment manifest into this one",
+			Usage:       "-manifest file [-on-conflict error|keep|replace]",
+			Examples: []string{
+				"merge -manifest cf-diego.yml",
+				"merge -manifest cf-diego.yml -on-conflict replace",
+			},
+			Flags: func() *flag.FlagSet { return new(Merger).flagSet() },
+		},
+		Builder: MergeTransformation,
+	})
+	RegisterTransformation(TransformationInfo{
+		Info: registry.Info{
+			Name:        "script",
+			Description: "edit the manifest with a Starlark script",
+			Usage:       "-file script.star | -e script [-max-steps n]",
+			Examples: []string{
+				"script -file bump-routers.star",
+				`script -e 'instance_group("router")["instances"] = 3'`,
+			},
+			Flags: func() *flag.FlagSet { return new(Script).flagSet() },
+		},
+		Builder: ScriptTransformation,
+	})
+}
@@ -1,46 +1,366 @@
 package manifest
 
 import (
-	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
 	"strings"
+	"text/tabwriter"
 
 	"github.com/enaml-ops/enaml"
+	"github.com/enaml-ops/omg-transform/cloudconfig"
+	"github.com/enaml-ops/omg-transform/errs"
+	"github.com/enaml-ops/omg-transform/registry"
 )
 
 type AZChanger struct {
 	InstanceGroup string
 	AZs           []string
-	azsFlag       string
+
+	// Rebalance raises the number of instances so that they are spread
+	// evenly across the new AZs.
+	Rebalance bool
+
+	// CloudConfig, if set, is used to move static IPs into the
+	// subnets of the new AZs.
+	CloudConfig *enaml.CloudConfigManifest
+
+	// Plan, if set, receives a description of how instances
+	// move between AZs.
+	Plan io.Writer
+
+	azsFlag         string
+	cloudConfigFlag string
+	planFlag        bool
 }
 
 func (a *AZChanger) Apply(dm *enaml.DeploymentManifest) error {
 	ig := dm.GetInstanceGroupByName(a.InstanceGroup)
 	if ig == nil {
-		return fmt.Errorf("couldn't find instance group %s", a.InstanceGroup)
+		return instanceGroupNotFound(dm, a.InstanceGroup)
+	}
+
+	if a.CloudConfig != nil {
+		for _, az := range a.AZs {
+			if err := cloudconfig.CheckAZ(a.CloudConfig, az); err != nil {
+				return err
+			}
+		}
+	}
+
+	instances := ig.Instances
+	if a.Rebalance {
+		instances = rebalance(instances, len(a.AZs))
+	}
+
+	if a.CloudConfig != nil {
+		err := placeStaticIPs(dm, ig, a.CloudConfig, distribute(instances, a.AZs), a.AZs)
+		if err != nil {
+			return err
+		}
+	} else if instances != ig.Instances && hasStaticIPs(ig) {
+		return errs.Preconditionf("instance group %s has static IPs, a cloud config is required to rebalance it", ig.Name)
+	}
+
+	if a.Plan != nil {
+		m := PlanAZMigration(ig, a.AZs, instances)
+		if err := m.Write(a.Plan); err != nil {
+			return err
+		}
 	}
 
 	ig.AZs = a.AZs
+	ig.Instances = instances
 	return nil
 }
 
-// split is like strings.Split but does not return empty elements.
-func split(str, sep string) []string {
-	orig := strings.Split(str, sep)
-	var result []string
-	for i := range orig {
-		if orig[i] != "" {
-			result = append(result, orig[i])
+func (a *AZChanger) Idempotent() bool { return true }
+
+// Inverse restores the AZs and number of instances of the instance
+// group and, when static IPs are moved, its static IPs.
+func (a *AZChanger) Inverse(dm *enaml.DeploymentManifest) ([]Step, error) {
+	ig := dm.GetInstanceGroupByName(a.InstanceGroup)
+	if ig == nil {
+		return nil, instanceGroupNotFound(dm, a.InstanceGroup)
+	}
+	if len(ig.AZs) == 0 {
+		return nil, errs.Preconditionf("instance group %s has no AZs to restore", ig.Name)
+	}
+	steps := []Step{
+		{Transform: "change-az", Args: []string{"-instance-group", ig.Name, "-az", strings.Join(ig.AZs, ",")}},
+		scaleStep(ig),
+	}
+	if a.CloudConfig != nil && hasStaticIPs(ig) {
+		step, err := networkStep(ig, true)
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+// AZMigration describes how the instances of an instance group
+// move between AZs when its AZs are changed.
+type AZMigration struct {
+	InstanceGroup string
+	AZs           []string       // every AZ used before or after the change
+	Before        map[string]int // instances per AZ before the change
+	After         map[string]int // instances per AZ after the change
+}
+
+// PlanAZMigration plans moving the instances of ig into the specified
+// AZs.  Instances are assumed to be spread evenly across AZs, which is
+// how BOSH places new instances.
+func PlanAZMigration(ig *enaml.InstanceGroup, azs []string, instances int) *AZMigration {
+	m := &AZMigration{
+		InstanceGroup: ig.Name,
+		Before:        distribute(ig.Instances, ig.AZs),
+		After:         distribute(instances, azs),
+	}
+	seen := make(map[string]bool)
+	for _, az := range append(append([]string{}, ig.AZs...), azs...) {
+		if !seen[az] {
+			seen[az] = true
+			m.AZs = append(m.AZs, az)
+		}
+	}
+	return m
+}
+
+// Deleted returns the number of instances deleted from their AZ.
+func (m *AZMigration) Deleted() int {
+	n := 0
+	for _, az := range m.AZs {
+		if d := m.Before[az] - m.After[az]; d > 0 {
+			n += d
+		}
+	}
+	return n
+}
+
+// Created returns the number of instances created in a new AZ.
+func (m *AZMigration) Created() int {
+	n := 0
+	for _, az := range m.AZs {
+		if d := m.After[az] - m.Before[az]; d > 0 {
+			n += d
+		}
+	}
+	return n
+}
+
+// Moved returns the number of existing instances that will be
+// recreated in another AZ.
+func (m *AZMigration) Moved() int {
+	d, c := m.Deleted(), m.Created()
+	if d < c {
+		return d
+	}
+	return c
+}
+
+// Write writes a human readable description of the migration.
+func (m *AZMigration) Write(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "instance group %s:\n", m.InstanceGroup)
+	for _, az := range m.AZs {
+		before, after := m.Before[az], m.After[az]
+		fmt.Fprintf(tw, "  %s:\t%d -> %d\t(%+d)\n", az, before, after, after-before)
+	}
+	total := 0
+	for _, n := range m.Before {
+		total += n
+	}
+	fmt.Fprintf(tw, "  %d of %d instances move to another AZ, %d deleted, %d created\n",
+		m.Moved(), total, m.Deleted(), m.Created())
+	return tw.Flush()
+}
+
+// distribute spreads instances across azs, placing any remainder in
+// the first AZs.
+func distribute(instances int, azs []string) map[string]int {
+	result := make(map[string]int, len(azs))
+	if len(azs) == 0 {
+		return result
+	}
+	for i, az := range azs {
+		result[az] = instances / len(azs)
+		if i < instances%len(azs) {
+			result[az]++
 		}
 	}
 	return result
 }
 
+// rebalance returns the smallest instance count, no smaller than
+// instances, that places the same number of instances in each AZ.
+func rebalance(instances, azs int) int {
+	if azs == 0 {
+		return instances
+	}
+	if r := instances % azs; r != 0 {
+		instances += azs - r
+	}
+	if instances < azs {
+		instances = azs
+	}
+	return instances
+}
+
+func hasStaticIPs(ig *enaml.InstanceGroup) bool {
+	for _, n := range ig.Networks {
+		if len(n.StaticIPs) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// staticIPAssignment is the static IPs planned for the networks of an
+// instance group, by network index.
+type staticIPAssignment map[int][]string
+
+func (a staticIPAssignment) apply(ig *enaml.InstanceGroup) {
+	for i, ips := range a {
+		ig.Networks[i].StaticIPs = ips
+	}
+}
+
+// placeStaticIPs assigns static IPs to each network of ig that uses
+// them, so that every AZ has one IP per instance from that AZ's subnet.
+// IPs that are already in the right subnet are kept, and new IPs are
+// never taken from other instance groups.  Every network is planned
+// before any is changed, so ig is left untouched if an error is
+// returned.
+func placeStaticIPs(dm *enaml.DeploymentManifest, ig *enaml.InstanceGroup, cc *enaml.CloudConfigManifest, perAZ map[string]int, azs []string) error {
+	a, err := planStaticIPs(dm, ig, cc, perAZ, azs, nil)
+	if err != nil {
+		return err
+	}
+	a.apply(ig)
+	return nil
+}
+
+// planStaticIPs returns the static IPs that placeStaticIPs assigns to
+// ig, without changing it.  planned holds the assignments already
+// planned for other instance groups, whose IPs are considered in use
+// instead of their current ones.
+func planStaticIPs(dm *enaml.DeploymentManifest, ig *enaml.InstanceGroup, cc *enaml.CloudConfigManifest, perAZ map[string]int, azs []string, planned map[string]staticIPAssignment) (staticIPAssignment, error) {
+	assignment := make(staticIPAssignment)
+	for i := range ig.Networks {
+		n := &ig.Networks[i]
+		if len(n.StaticIPs) == 0 {
+			continue
+		}
+
+		network, err := cloudconfig.GetNetworkByName(cc, n.Name)
+		if err != nil {
+			return nil, err
+		}
+		if network == nil {
+			return nil, cloudconfig.CheckNetwork(cc, n.Name)
+		}
+
+		current, err := cloudconfig.ExpandIPs(n.StaticIPs)
+		if err != nil {
+			return nil, err
+		}
+		used, err := staticIPsInUse(dm, ig.Name, n.Name, planned)
+		if err != nil {
+			return nil, err
+		}
+
+		var ips []string
+		for _, az := range azs {
+			var static []cloudconfig.IPRange
+			for _, subnet := range network.Subnets {
+				if !subnet.InAZ(az) {
+					continue
+				}
+				ranges, err := cloudconfig.ParseIPRanges(subnet.Static)
+				if err != nil {
+					return nil, err
+				}
+				static = append(static, ranges...)
+			}
+
+			var placed []string
+			for _, ip := range current {
+				if !used[ip.String()] && len(placed) < perAZ[az] && inRanges(ip, static) {
+					placed = append(placed, ip.String())
+					used[ip.String()] = true
+				}
+			}
+			for _, r := range static {
+				for _, ip := range r.IPs() {
+					if len(placed) == perAZ[az] {
+						break
+					}
+					if !used[ip.String()] {
+						placed = append(placed, ip.String())
+						used[ip.String()] = true
+					}
+				}
+			}
+			if len(placed) < perAZ[az] {
+				return nil, errs.Preconditionf("not enough static IPs available in network %s for AZ %s (need %d, found %d)", n.Name, az, perAZ[az], len(placed))
+			}
+			ips = append(ips, placed...)
+		}
+		assignment[i] = ips
+	}
+	return assignment, nil
+}
+
+// staticIPsInUse returns the static IPs on the named network used by
+// every instance group other than the one specified.  The IPs planned
+// for an instance group replace its current ones.
+func staticIPsInUse(dm *enaml.DeploymentManifest, except, network string, planned map[string]staticIPAssignment) (map[string]bool, error) {
+	used := make(map[string]bool)
+	for _, ig := range dm.InstanceGroups {
+		if ig.Name == except {
+			continue
+		}
+		for i, n := range ig.Networks {
+			if n.Name != network {
+				continue
+			}
+			static := n.StaticIPs
+			if ips, ok := planned[ig.Name][i]; ok {
+				static = ips
+			}
+			ips, err := cloudconfig.ExpandIPs(static)
+			if err != nil {
+				return nil, err
+			}
+			for _, ip := range ips {
+				used[ip.String()] = true
+			}
+		}
+	}
+	return used, nil
+}
+
+func inRanges(ip net.IP, ranges []cloudconfig.IPRange) bool {
+	for _, r := range ranges {
+		if r.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
 func (a *AZChanger) flagSet() *flag.FlagSet {
-	fs := flag.NewFlagSet("change-az", flag.ContinueOnError)
+	fs := registry.NewFlagSet("change-az")
 	fs.StringVar(&a.InstanceGroup, "instance-group", "", "name of the instance group")
 	fs.StringVar(&a.azsFlag, "az", "", "a comma separated list of az names")
+	fs.BoolVar(&a.Rebalance, "rebalance", false, "raise the number of instances to spread them evenly across the AZs")
+	fs.StringVar(&a.cloudConfigFlag, "cloud-config", "", "path to a cloud config used to move static IPs into the new AZs")
+	fs.BoolVar(&a.planFlag, "plan", false, "print how instances move between AZs to stderr")
 	return fs
 }
 
@@ -53,19 +373,42 @@ func ChangeAZTransformation(args []string) (Transformation, error) {
 	}
 
 	if a.InstanceGroup == "" {
-		return nil, errors.New("missing required flag -instance-group")
+		return nil, errs.MissingFlag("instance-group")
 	}
 	if a.azsFlag == "" {
-		return nil, errors.New("missing required flag -az")
+		return nil, errs.MissingFlag("az")
 	}
 
 	if strings.Contains(a.azsFlag, " ") {
-		return nil, errors.New("invalid format for az, cannot contain space")
+		return nil, errs.InvalidFlag("az", "invalid format for az, cannot contain space")
 	}
-	a.AZs = split(a.azsFlag, ",")
+	a.AZs = registry.Split(a.azsFlag, ",")
 	if len(a.AZs) == 0 {
-		return nil, errors.New("invalid format for az, must be comma-separated")
+		return nil, errs.InvalidFlag("az", "invalid format for az, must be comma-separated")
+	}
+
+	if a.cloudConfigFlag != "" {
+		a.CloudConfig, err = readCloudConfig(a.cloudConfigFlag)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if a.planFlag {
+		a.Plan = os.Stderr
 	}
 
 	return a, nil
 }
+
+// readCloudConfig reads a cloud config from the specified file.
+func readCloudConfig(path string) (*enaml.CloudConfigManifest, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cc := enaml.NewCloudConfigManifest(b)
+	if cc == nil {
+		return nil, fmt.Errorf("invalid cloud config %s", path)
+	}
+	return cc, nil
+}
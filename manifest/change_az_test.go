@@ -1,6 +1,7 @@
 package manifest
 
 import (
+	"bytes"
 	"os"
 
 	"github.com/enaml-ops/enaml"
@@ -41,6 +42,20 @@ var _ = Describe("change AZ transformation", func() {
 			Ω(err).ShouldNot(HaveOccurred())
 			Ω(t).ShouldNot(BeNil())
 		})
+
+		It("returns an error if the cloud config doesn't exist", func() {
+			_, err := ChangeAZTransformation([]string{"-instance-group", "foo", "-az", "az1", "-cloud-config", "fixtures/does-not-exist.yml"})
+			Ω(err).Should(HaveOccurred())
+		})
+
+		It("reads the cloud config when given valid args", func() {
+			t, err := ChangeAZTransformation([]string{"-instance-group", "foo", "-az", "az1", "-rebalance", "-cloud-config", "fixtures/cloud-config-aws.yml"})
+			Ω(err).ShouldNot(HaveOccurred())
+
+			a := t.(*AZChanger)
+			Ω(a.Rebalance).Should(BeTrue())
+			Ω(a.CloudConfig).ShouldNot(BeNil())
+		})
 	})
 
 	Context("PCF 1.8 AWS manifest", func() {
@@ -54,17 +69,11 @@ var _ = Describe("change AZ transformation", func() {
 		})
 
 		It("changes the AZ for an existing partition", func() {
-			n := AZChanger{
-				InstanceGroup: "router",
-				AZs:           []string{"az1", "az2"},
-			}
-			Ω(n.Apply(manifest)).Should(Succeed())
-
-			ig := manifest.GetInstanceGroupByName(n.InstanceGroup)
-			Ω(ig.AZs).Should(HaveLen(len(n.AZs)))
-			for i := range n.AZs {
-				Ω(ig.AZs[i]).Should(Equal(n.AZs[i]))
-			}
+			expectGolden(goldenCase{
+				Transform: "change-az",
+				Args:      []string{"-instance-group", "router", "-az", "az1,az2"},
+				Golden:    "change-az-router.diff",
+			})
 		})
 
 		It("returns an error when supplied with a non-existent partition", func() {
@@ -74,5 +83,135 @@ var _ = Describe("change AZ transformation", func() {
 			}
 			Ω(n.Apply(manifest)).ShouldNot(Succeed())
 		})
+
+		It("rebalances instances across the new AZs", func() {
+			expectGolden(goldenCase{
+				Transform: "change-az",
+				Args:      []string{"-instance-group", "diego_cell", "-az", "us-west-1b,us-west-1c", "-rebalance"},
+				Golden:    "change-az-diego-cell-rebalance.diff",
+			})
+		})
+
+		It("requires a cloud config to rebalance instance groups with static IPs", func() {
+			a := AZChanger{
+				InstanceGroup: "consul_server",
+				AZs:           []string{"us-west-1b", "us-west-1c"},
+				Rebalance:     true,
+			}
+			Ω(a.Apply(manifest)).ShouldNot(Succeed())
+		})
+
+		Context("when given a cloud config", func() {
+			var cloudConfig *enaml.CloudConfigManifest
+
+			BeforeEach(func() {
+				var err error
+				cloudConfig, err = readCloudConfig("fixtures/cloud-config-aws.yml")
+				Ω(err).ShouldNot(HaveOccurred())
+			})
+
+			It("returns an error for AZs that aren't in the cloud config", func() {
+				a := AZChanger{
+					InstanceGroup: "router",
+					AZs:           []string{"us-west-1b", "us_west_1c"},
+					CloudConfig:   cloudConfig,
+				}
+				Ω(a.Apply(manifest)).Should(MatchError("az us_west_1c is not defined in the cloud config, did you mean us-west-1c?"))
+			})
+
+			It("moves static IPs into the subnets of the new AZs", func() {
+				expectGolden(goldenCase{
+					Transform: "change-az",
+					Args:      []string{"-instance-group", "router", "-az", "us-west-1c", "-cloud-config", "fixtures/cloud-config-aws.yml"},
+					Golden:    "change-az-router-static-ips.diff",
+				})
+			})
+
+			It("keeps static IPs that are already in the right subnet", func() {
+				expectGolden(goldenCase{
+					Transform: "change-az",
+					Args:      []string{"-instance-group", "router", "-az", "us-west-1b,us-west-1c", "-rebalance", "-cloud-config", "fixtures/cloud-config-aws.yml"},
+					Golden:    "change-az-router-rebalance-static-ips.diff",
+				})
+			})
+
+			It("doesn't use static IPs that belong to other instance groups", func() {
+				a := AZChanger{
+					InstanceGroup: "router",
+					AZs:           []string{"us-west-1b"},
+					CloudConfig:   cloudConfig,
+				}
+				manifest.GetInstanceGroupByName("router").Networks[0].StaticIPs = []string{"10.0.4.6"}
+				manifest.GetInstanceGroupByName("nats").Networks[0].StaticIPs = []string{"10.0.0.6"}
+				Ω(a.Apply(manifest)).Should(Succeed())
+
+				ig := manifest.GetInstanceGroupByName("router")
+				Ω(ig.Networks[0].StaticIPs).Should(Equal([]string{"10.0.0.8"}))
+			})
+
+			It("doesn't give the same static IP to two AZs that share a subnet", func() {
+				cloudConfig, err := readCloudConfig("fixtures/cloud-config-multi-az.yml")
+				Ω(err).ShouldNot(HaveOccurred())
+				ig := manifest.GetInstanceGroupByName("router")
+				ig.Instances = 2
+				ig.Networks[0].StaticIPs = []string{"10.0.0.20", "10.0.0.21"}
+				a := AZChanger{
+					InstanceGroup: "router",
+					AZs:           []string{"us-west-1b", "us-west-1c"},
+					CloudConfig:   cloudConfig,
+				}
+				Ω(a.Apply(manifest)).Should(Succeed())
+				Ω(ig.Networks[0].StaticIPs).Should(Equal([]string{"10.0.0.20", "10.0.0.21"}))
+			})
+
+			It("leaves every network untouched if one can't be placed", func() {
+				ig := manifest.GetInstanceGroupByName("router")
+				before := ig.Networks[0].StaticIPs
+				ig.Networks = append(ig.Networks, enaml.Network{Name: "unknown", StaticIPs: []string{"10.1.0.5"}})
+				a := AZChanger{
+					InstanceGroup: "router",
+					AZs:           []string{"us-west-1c"},
+					CloudConfig:   cloudConfig,
+				}
+				Ω(a.Apply(manifest)).Should(MatchError(HavePrefix("network unknown is not defined in the cloud config")))
+				Ω(ig.Networks[0].StaticIPs).Should(Equal(before))
+				Ω(ig.AZs).ShouldNot(Equal([]string{"us-west-1c"}))
+			})
+
+			It("returns an error when the network isn't in the cloud config", func() {
+				manifest.GetInstanceGroupByName("router").Networks[0].Name = "unknown"
+				a := AZChanger{
+					InstanceGroup: "router",
+					AZs:           []string{"us-west-1c"},
+					CloudConfig:   cloudConfig,
+				}
+				Ω(a.Apply(manifest)).ShouldNot(Succeed())
+			})
+		})
+
+		It("writes a migration plan", func() {
+			var plan bytes.Buffer
+			a := AZChanger{
+				InstanceGroup: "diego_cell",
+				AZs:           []string{"us-west-1b", "us-west-1c", "us-west-1d"},
+				Plan:          &plan,
+			}
+			Ω(a.Apply(manifest)).Should(Succeed())
+			Ω(plan.String()).Should(ContainSubstring("instance group diego_cell:"))
+			Ω(plan.String()).Should(MatchRegexp(`us-west-1b:\s+3 -> 1\s+\(-2\)`))
+			Ω(plan.String()).Should(MatchRegexp(`us-west-1c:\s+0 -> 1\s+\(\+1\)`))
+			Ω(plan.String()).Should(ContainSubstring("2 of 3 instances move to another AZ"))
+		})
+	})
+
+	Context("when planning an AZ migration", func() {
+		It("counts instances that move between AZs", func() {
+			ig := &enaml.InstanceGroup{Name: "router", Instances: 3, AZs: []string{"z1"}}
+			m := PlanAZMigration(ig, []string{"z1", "z2", "z3"}, 6)
+			Ω(m.After).Should(Equal(map[string]int{"z1": 2, "z2": 2, "z3": 2}))
+			Ω(m.Deleted()).Should(Equal(1))
+			Ω(m.Created()).Should(Equal(4))
+			Ω(m.Moved()).Should(Equal(1))
+		})
 	})
 })
@@ -0,0 +1,77 @@
+package manifest
+
+import (
+	"flag"
+
+	"github.com/enaml-ops/enaml"
+	"github.com/enaml-ops/omg-transform/errs"
+	"github.com/enaml-ops/omg-transform/registry"
+)
+
+// Instance group lifecycles supported by bosh.
+const (
+	LifecycleService = "service"
+	LifecycleErrand  = "errand"
+)
+
+// LifecycleChanger is a transformation that switches an instance group
+// between a long running service and an errand.
+type LifecycleChanger struct {
+	InstanceGroup string
+	Lifecycle     string
+}
+
+func (l *LifecycleChanger) Apply(dm *enaml.DeploymentManifest) error {
+	ig := dm.GetInstanceGroupByName(l.InstanceGroup)
+	if ig == nil {
+		return instanceGroupNotFound(dm, l.InstanceGroup)
+	}
+
+	ig.Lifecycle = l.Lifecycle
+	return nil
+}
+
+func (l *LifecycleChanger) Idempotent() bool { return true }
+
+// Inverse restores the lifecycle.  An instance group without a
+// lifecycle is restored as a service, the default.
+func (l *LifecycleChanger) Inverse(dm *enaml.DeploymentManifest) ([]Step, error) {
+	ig := dm.GetInstanceGroupByName(l.InstanceGroup)
+	if ig == nil {
+		return nil, instanceGroupNotFound(dm, l.InstanceGroup)
+	}
+	lifecycle := ig.Lifecycle
+	if lifecycle == "" {
+		lifecycle = LifecycleService
+	}
+	return []Step{{Transform: "change-lifecycle", Args: []string{"-instance-group", ig.Name, "-lifecycle", lifecycle}}}, nil
+}
+
+func (l *LifecycleChanger) flagSet() *flag.FlagSet {
+	fs := registry.NewFlagSet("change-lifecycle")
+	fs.StringVar(&l.InstanceGroup, "instance-group", "", "name of the instance group")
+	fs.StringVar(&l.Lifecycle, "lifecycle", "", "the lifecycle to use (service or errand)")
+	return fs
+}
+
+// ChangeLifecycleTransformation is a TransformationBuilder that builds the
+// 'change-lifecycle' transformation.
+func ChangeLifecycleTransformation(args []string) (Transformation, error) {
+	l := &LifecycleChanger{}
+	fs := l.flagSet()
+	err := fs.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+
+	if l.InstanceGroup == "" {
+		return nil, errs.MissingFlag("instance-group")
+	}
+	if l.Lifecycle == "" {
+		return nil, errs.MissingFlag("lifecycle")
+	}
+	if l.Lifecycle != LifecycleService && l.Lifecycle != LifecycleErrand {
+		return nil, errs.InvalidFlag("lifecycle", "invalid lifecycle %q, must be %s or %s", l.Lifecycle, LifecycleService, LifecycleErrand)
+	}
+	return l, nil
+}
@@ -0,0 +1,70 @@
+package manifest
+
+import (
+	"os"
+
+	"github.com/enaml-ops/enaml"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("change lifecycle transformation", func() {
+	Context("when creating the transformation", func() {
+		It("returns an error if no arguments are provided", func() {
+			_, err := ChangeLifecycleTransformation(nil)
+			Ω(err).Should(HaveOccurred())
+		})
+
+		It("returns an error if the instance-group argument is missing", func() {
+			_, err := ChangeLifecycleTransformation([]string{"-lifecycle", "errand"})
+			Ω(err).Should(HaveOccurred())
+		})
+
+		It("returns an error if the lifecycle argument is missing", func() {
+			_, err := ChangeLifecycleTransformation([]string{"-instance-group", "foo"})
+			Ω(err).Should(HaveOccurred())
+		})
+
+		It("returns an error if the lifecycle is invalid", func() {
+			_, err := ChangeLifecycleTransformation([]string{"-instance-group", "foo", "-lifecycle", "daemon"})
+			Ω(err).Should(HaveOccurred())
+		})
+
+		It("returns a transformation when given valid args", func() {
+			t, err := ChangeLifecycleTransformation([]string{"-instance-group", "foo", "-lifecycle", "errand"})
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(t).ShouldNot(BeNil())
+		})
+	})
+
+	Context("PCF 1.8 AWS manifest", func() {
+		var manifest *enaml.DeploymentManifest
+
+		BeforeEach(func() {
+			f, err := os.Open("fixtures/pcf-aws-1.8.00-build.373.yml")
+			Ω(err).ShouldNot(HaveOccurred())
+			manifest = enaml.NewDeploymentManifestFromFile(f)
+		})
+
+		It("turns a service into an errand", func() {
+			expectGolden(goldenCase{
+				Transform: "change-lifecycle",
+				Args:      []string{"-instance-group", "router", "-lifecycle", LifecycleErrand},
+				Golden:    "change-lifecycle-router-errand.diff",
+			})
+		})
+
+		It("turns an errand into a service", func() {
+			expectGolden(goldenCase{
+				Transform: "change-lifecycle",
+				Args:      []string{"-instance-group", "smoke-tests", "-lifecycle", LifecycleService},
+				Golden:    "change-lifecycle-smoke-tests-service.diff",
+			})
+		})
+
+		It("returns an error when given an invalid instance group", func() {
+			l := LifecycleChanger{InstanceGroup: "foobar", Lifecycle: LifecycleErrand}
+			Ω(l.Apply(manifest)).ShouldNot(Succeed())
+		})
+	})
+})
@@ -1,13 +1,14 @@
 package manifest
 
 import (
-	"errors"
 	"flag"
-	"fmt"
 	"net"
 	"strings"
 
 	"github.com/enaml-ops/enaml"
+	"github.com/enaml-ops/omg-transform/cloudconfig"
+	"github.com/enaml-ops/omg-transform/errs"
+	"github.com/enaml-ops/omg-transform/registry"
 )
 
 // NetworkMover is a transformation that changes which network
@@ -16,17 +17,28 @@ type NetworkMover struct {
 	InstanceGroup string
 	Network       string
 	StaticIPs     []string
-	ipsFlag       string
+
+	// CloudConfig, if set, is used to check that the network exists.
+	CloudConfig *enaml.CloudConfigManifest
+
+	ipsFlag         string
+	cloudConfigFlag string
 }
 
 func (n *NetworkMover) Apply(dm *enaml.DeploymentManifest) error {
 	ig := dm.GetInstanceGroupByName(n.InstanceGroup)
 	if ig == nil {
-		return fmt.Errorf("couldn't find instance group %s", n.InstanceGroup)
+		return instanceGroupNotFound(dm, n.InstanceGroup)
+	}
+
+	if n.CloudConfig != nil {
+		if err := cloudconfig.CheckNetwork(n.CloudConfig, n.Network); err != nil {
+			return err
+		}
 	}
 
 	if l := len(ig.Networks); l != 1 {
-		return fmt.Errorf("expected 1 network, found %d", l)
+		return errs.Preconditionf("expected 1 network, found %d", l)
 	}
 
 	ig.Networks[0].Name = n.Network
@@ -38,11 +50,45 @@ func (n *NetworkMover) Apply(dm *enaml.DeploymentManifest) error {
 	return nil
 }
 
+func (n *NetworkMover) Idempotent() bool { return true }
+
+// Inverse moves the instance group back to its network, restoring its
+// static IPs if they are replaced.
+func (n *NetworkMover) Inverse(dm *enaml.DeploymentManifest) ([]Step, error) {
+	ig := dm.GetInstanceGroupByName(n.InstanceGroup)
+	if ig == nil {
+		return nil, instanceGroupNotFound(dm, n.InstanceGroup)
+	}
+	step, err := networkStep(ig, len(n.StaticIPs) > 0)
+	if err != nil {
+		return nil, err
+	}
+	return []Step{step}, nil
+}
+
+// networkStep returns a step that restores the network of an instance
+// group and, if staticIPs is set, its static IPs.
+func networkStep(ig *enaml.InstanceGroup, staticIPs bool) (Step, error) {
+	if l := len(ig.Networks); l != 1 {
+		return Step{}, errs.Preconditionf("expected 1 network, found %d", l)
+	}
+	network := ig.Networks[0]
+	step := Step{Transform: "change-network", Args: []string{"-instance-group", ig.Name, "-network", network.Name}}
+	if staticIPs {
+		if len(network.StaticIPs) == 0 {
+			return Step{}, errs.Preconditionf("instance group %s has no static IPs to restore", ig.Name)
+		}
+		step.Args = append(step.Args, "-static-ips", strings.Join(network.StaticIPs, ","))
+	}
+	return step, nil
+}
+
 func (n *NetworkMover) flagSet() *flag.FlagSet {
-	fs := flag.NewFlagSet("change-network", flag.ContinueOnError)
+	fs := registry.NewFlagSet("change-network")
 	fs.StringVar(&n.InstanceGroup, "instance-group", "", "name of the instance group")
 	fs.StringVar(&n.Network, "network", "", "the name of the network to use")
 	fs.StringVar(&n.ipsFlag, "static-ips", "", "comma-separated list of static IP ranges to set on the network")
+	fs.StringVar(&n.cloudConfigFlag, "cloud-config", "", "path to a cloud config used to check the network")
 	return fs
 }
 
@@ -57,28 +103,34 @@ func ChangeNetworkTransformation(args []string) (Transformation, error) {
 	}
 
 	if n.InstanceGroup == "" {
-		return nil, errors.New("missing required flag -instance-group")
+		return nil, errs.MissingFlag("instance-group")
 	}
 	if n.Network == "" {
-		return nil, errors.New("missing required flag network")
+		return nil, errs.MissingFlag("network")
 	}
 	if n.ipsFlag != "" {
-		n.StaticIPs = split(n.ipsFlag, ",")
+		n.StaticIPs = registry.Split(n.ipsFlag, ",")
 		if len(n.StaticIPs) == 0 {
-			return nil, errors.New("invalid -static-ips flag")
+			return nil, errs.InvalidFlag("static-ips", "invalid -static-ips flag")
 		}
 		for _, ipRange := range n.StaticIPs {
 			c := strings.Count(ipRange, "-")
 			if c > 1 {
-				return nil, fmt.Errorf("invalid IP range %q", ipRange)
+				return nil, errs.InvalidFlag("static-ips", "invalid IP range %q", ipRange)
 			}
 			parts := strings.Split(ipRange, "-")
 			for _, ipStr := range parts {
 				if ip := net.ParseIP(ipStr); ip == nil {
-					return nil, fmt.Errorf("%q is not a valid IP address", ipStr)
+					return nil, errs.InvalidFlag("static-ips", "%q is not a valid IP address", ipStr)
 				}
 			}
 		}
 	}
+	if n.cloudConfigFlag != "" {
+		n.CloudConfig, err = readCloudConfig(n.cloudConfigFlag)
+		if err != nil {
+			return nil, err
+		}
+	}
 	return n, nil
 }
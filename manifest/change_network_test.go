@@ -78,33 +78,19 @@ var _ = Describe("change network", func() {
 		})
 
 		It("changes the network name for an existing partition", func() {
-			const newNetwork = "newNetwork"
-			n := NetworkMover{
-				InstanceGroup: "mysql_proxy",
-				Network:       newNetwork,
-			}
-			Ω(n.Apply(manifest)).Should(Succeed())
-
-			ig := manifest.GetInstanceGroupByName("mysql_proxy")
-			Ω(ig.Networks).Should(HaveLen(1))
-			Ω(ig.Networks[0].Name).Should(Equal(newNetwork))
+			expectGolden(goldenCase{
+				Transform: "change-network",
+				Args:      []string{"-instance-group", "mysql_proxy", "-network", "newNetwork"},
+				Golden:    "change-network-mysql-proxy.diff",
+			})
 		})
 
 		It("changes the network's static IPs for an existing partition", func() {
-			const newNetwork = "newNetwork"
-			n := NetworkMover{
-				InstanceGroup: "mysql_proxy",
-				Network:       newNetwork,
-				StaticIPs:     []string{"10.0.0.3-10.0.0.10", "10.0.16.5"},
-			}
-			Ω(n.Apply(manifest)).Should(Succeed())
-
-			ig := manifest.GetInstanceGroupByName("mysql_proxy")
-			Ω(ig.Networks).Should(HaveLen(1))
-			Ω(ig.Networks[0].Name).Should(Equal(newNetwork))
-
-			Ω(ig.Networks[0].StaticIPs).Should(HaveLen(2))
-			Ω(ig.Networks[0].StaticIPs).Should(ConsistOf("10.0.0.3-10.0.0.10", "10.0.16.5"))
+			expectGolden(goldenCase{
+				Transform: "change-network",
+				Args:      []string{"-instance-group", "mysql_proxy", "-network", "newNetwork", "-static-ips", "10.0.0.3-10.0.0.10,10.0.16.5"},
+				Golden:    "change-network-mysql-proxy-static-ips.diff",
+			})
 		})
 
 		It("returns an error when supplied with a non-existent partition", func() {
@@ -114,5 +100,18 @@ var _ = Describe("change network", func() {
 			}
 			Ω(n.Apply(manifest)).ShouldNot(Succeed())
 		})
+
+		It("suggests similar instance groups", func() {
+			n := NetworkMover{InstanceGroup: "diego-cell", Network: "cf"}
+			Ω(n.Apply(manifest)).Should(MatchError("couldn't find instance group diego-cell, did you mean diego_cell?"))
+		})
+
+		It("checks the network against a cloud config", func() {
+			cloudConfig, err := readCloudConfig("fixtures/cloud-config-aws.yml")
+			Ω(err).ShouldNot(HaveOccurred())
+
+			n := NetworkMover{InstanceGroup: "router", Network: "cf2", CloudConfig: cloudConfig}
+			Ω(n.Apply(manifest)).Should(MatchError("network cf2 is not defined in the cloud config, did you mean cf?"))
+		})
 	})
 })
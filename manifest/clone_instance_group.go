@@ -1,13 +1,18 @@
 package manifest
 
 import (
+	"bytes"
 	"flag"
-	"fmt"
 
 	"github.com/enaml-ops/enaml"
+	"github.com/enaml-ops/omg-transform/errs"
+	"github.com/enaml-ops/omg-transform/registry"
+	yaml "gopkg.in/yaml.v2"
 )
 
-// Cloner is a transformation that clones an instance group.
+// Cloner is a transformation that clones an instance group.  The clone
+// doesn't take the original's static IPs, so it gets dynamic IPs unless
+// it is given static IPs of its own, for example with change-network.
 type Cloner struct {
 	InstanceGroup string // IG to clone
 	Clone         string // name for the copy
@@ -16,16 +21,101 @@ type Cloner struct {
 func (c *Cloner) Apply(dm *enaml.DeploymentManifest) error {
 	ig := dm.GetInstanceGroupByName(c.InstanceGroup)
 	if ig == nil {
-		return fmt.Errorf("couldn't find instance group %s", c.InstanceGroup)
+		return instanceGroupNotFound(dm, c.InstanceGroup)
 	}
 
-	clone := *ig
-	clone.Name = c.Clone
-	return dm.AddInstanceGroup(&clone)
+	clone, err := cloneInstanceGroup(ig, c.Clone)
+	if err != nil {
+		return err
+	}
+	if existing := dm.GetInstanceGroupByName(c.Clone); existing != nil {
+		same, err := sameClone(existing, clone)
+		if err != nil {
+			return err
+		}
+		if same {
+			// cloned by an earlier run, and possibly changed since
+			return nil
+		}
+		return errs.Preconditionf("instance group %s already exists", c.Clone)
+	}
+	return dm.AddInstanceGroup(clone)
+}
+
+// cloneInstanceGroup returns a deep copy of ig with a new name.  The
+// copy has no static IPs and isn't migrated from other instance groups,
+// as those belong to the instances of ig.
+func cloneInstanceGroup(ig *enaml.InstanceGroup, name string) (*enaml.InstanceGroup, error) {
+	b, err := yaml.Marshal(ig)
+	if err != nil {
+		return nil, err
+	}
+	clone := &enaml.InstanceGroup{}
+	if err = yaml.Unmarshal(b, clone); err != nil {
+		return nil, err
+	}
+	clone.Name = name
+	clone.MigratedFrom = nil
+	for i := range clone.Networks {
+		clone.Networks[i].StaticIPs = nil
+	}
+	return clone, nil
+}
+
+// Idempotent returns true.  An existing instance group with the clone's
+// name that differs from the clone only in what later steps change is
+// taken to be the result of an earlier run, so that pipelines that go
+// on to change the clone can be run again.
+func (c *Cloner) Idempotent() bool { return true }
+
+// Inverse removes the clone, unless it already exists.
+func (c *Cloner) Inverse(dm *enaml.DeploymentManifest) ([]Step, error) {
+	if dm.GetInstanceGroupByName(c.Clone) != nil {
+		return nil, nil
+	}
+	return []Step{{Transform: "remove-instance-group", Args: []string{"-instance-group", c.Clone}}}, nil
+}
+
+// sameClone returns true if existing differs from clone only in the
+// instance group settings other transformations change: instances, AZs,
+// lifecycle, vm type and extensions, networks, migrations and tags.
+func sameClone(existing, clone *enaml.InstanceGroup) (bool, error) {
+	a, err := cloneIdentity(existing)
+	if err != nil {
+		return false, err
+	}
+	b, err := cloneIdentity(clone)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(a, b), nil
+}
+
+// cloneIdentity returns the YAML representation of ig without the
+// settings sameClone ignores.
+func cloneIdentity(ig *enaml.InstanceGroup) ([]byte, error) {
+	c, err := cloneInstanceGroup(ig, ig.Name)
+	if err != nil {
+		return nil, err
+	}
+	c.Instances = 0
+	c.AZs = nil
+	c.Lifecycle = ""
+	c.VMType = ""
+	c.VMExtensions = nil
+	c.Networks = nil
+	setInstanceGroupTags(c, nil)
+	if bosh, ok := stringMap(c.Env["bosh"]); ok && len(bosh) == 0 {
+		delete(c.Env, "bosh")
+	}
+	if len(c.Env) == 0 {
+		c.Env = nil
+	}
+	return yaml.Marshal(c)
 }
 
 func (c *Cloner) flagSet() *flag.FlagSet {
-	fs := flag.NewFlagSet("clone", flag.ExitOnError)
+	fs := registry.NewFlagSet("clone")
 	fs.StringVar(&c.InstanceGroup, "instance-group", "", "name of the instance group to clone")
 	fs.StringVar(&c.Clone, "clone", "", "the name to use for the copy")
 	return fs
@@ -42,10 +132,10 @@ func CloneTransformation(args []string) (Transformation, error) {
 	}
 
 	if c.InstanceGroup == "" {
-		return nil, fmt.Errorf("missing required flag -instance-group")
+		return nil, errs.MissingFlag("instance-group")
 	}
 	if c.Clone == "" {
-		return nil, fmt.Errorf("missing required flag -clone")
+		return nil, errs.MissingFlag("clone")
 	}
 
 	return c, nil
@@ -64,6 +64,7 @@ var _ = Describe("clone instance group", func() {
 
 			It("should have added a new instance group", func() {
 				Ω(manifest.GetInstanceGroupByName(clone)).ShouldNot(BeNil())
+				Ω(manifest.InstanceGroups[len(manifest.InstanceGroups)-1].Name).Should(Equal(clone))
 			})
 
 			It("should have cloned correctly", func() {
@@ -72,21 +73,36 @@ var _ = Describe("clone instance group", func() {
 				Ω(orig).ShouldNot(BeNil())
 				Ω(clone).ShouldNot(BeNil())
 
-				By("having the same number of jobs")
-				Ω(len(clone.Jobs)).Should(Equal(len(orig.Jobs)))
+				By("having the same jobs and job properties")
+				Ω(clone.Jobs).Should(Equal(orig.Jobs))
 
-				By("having identical networks")
-				Ω(clone.Networks).Should(Equal(orig.Networks))
-
-				By("having identical lifecycle")
+				By("having the same instances, AZs, vm type and lifecycle")
+				Ω(clone.Instances).Should(Equal(orig.Instances))
+				Ω(clone.AZs).Should(Equal(orig.AZs))
+				Ω(clone.VMType).Should(Equal(orig.VMType))
 				Ω(clone.Lifecycle).Should(Equal(orig.Lifecycle))
 
-				By("having identical job properties")
-				for i := range clone.Jobs {
-					if clone.Jobs[i].Properties != nil {
-						Ω(clone.Jobs[i].Properties).Should(Equal(orig.Jobs[i].Properties), "job: %s", clone.Jobs[i].Name)
-					}
+				By("having the same networks without the original's static IPs")
+				Ω(orig.Networks[0].StaticIPs).ShouldNot(BeEmpty())
+				Ω(clone.Networks).Should(HaveLen(len(orig.Networks)))
+				for i, n := range clone.Networks {
+					Ω(n.Name).Should(Equal(orig.Networks[i].Name))
+					Ω(n.Default).Should(Equal(orig.Networks[i].Default))
+					Ω(n.StaticIPs).Should(BeEmpty())
 				}
+
+				By("not migrating the original's instances")
+				Ω(orig.MigratedFrom).ShouldNot(BeEmpty())
+				Ω(clone.MigratedFrom).Should(BeEmpty())
+			})
+
+			It("should not share anything with the original", func() {
+				clone := manifest.GetInstanceGroupByName(clone)
+				clone.AZs[0] = "z9"
+				clone.Jobs[0].Name = "changed"
+				orig := manifest.GetInstanceGroupByName("consul_server")
+				Ω(orig.AZs[0]).ShouldNot(Equal("z9"))
+				Ω(orig.Jobs[0].Name).ShouldNot(Equal("changed"))
 			})
 		})
 	})
@@ -0,0 +1,275 @@
+package manifest
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/enaml-ops/enaml"
+	"github.com/enaml-ops/omg-transform/errs"
+)
+
+// Condition decides whether a pipeline step is applied.  Every field
+// that is set must hold.
+//
+//	when:
+//	  instance_group: tcp_router
+//	  release: {name: cf, version: ">= 239"}
+//	  property: {query: ".instance_groups[name=router].instances", equals: 2}
+//	  not: {instance_group: router_internal}
+type Condition struct {
+	// InstanceGroup holds if the manifest has the instance group.
+	InstanceGroup string `yaml:"instance_group,omitempty"`
+
+	Release  *ReleaseCondition  `yaml:"release,omitempty"`
+	Property *PropertyCondition `yaml:"property,omitempty"`
+
+	// Not holds if its condition doesn't.
+	Not *Condition `yaml:"not,omitempty"`
+}
+
+// ReleaseCondition holds if the manifest uses a release, at a version
+// that satisfies Version if it is set.  Version is a version optionally
+// preceded by one of the operators =, !=, <, <=, > and >=, for example
+// ">= 239.0.5".  Versions are compared component by component,
+// numerically where both components are numbers.
+type ReleaseCondition struct {
+	Name    string `yaml:"name"`
+	Version string `yaml:"version,omitempty"`
+}
+
+// PropertyCondition holds if a query expression, as used by the query
+// command, matches a value in the manifest, and if Equals is set, if one
+// of the matched values has that string form.  If Min or Max are set,
+// every matched value must be a number within those bounds.
+type PropertyCondition struct {
+	Query  string   `yaml:"query"`
+	Equals *string  `yaml:"equals,omitempty"`
+	Min    *float64 `yaml:"min,omitempty"`
+	Max    *float64 `yaml:"max,omitempty"`
+}
+
+var versionOperators = []string{"!=", "<=", ">=", "==", "=", "<", ">"}
+
+// validate checks that a condition is well formed before it is
+// evaluated.
+func (c *Condition) validate() error {
+	if c.InstanceGroup == "" && c.Release == nil && c.Property == nil && c.Not == nil {
+		return fmt.Errorf("condition is empty")
+	}
+	if r := c.Release; r != nil {
+		if r.Name == "" {
+			return fmt.Errorf("release condition has no name")
+		}
+		if _, _, err := parseVersionConstraint(r.Version); err != nil {
+			return err
+		}
+	}
+	if p := c.Property; p != nil {
+		if p.Query == "" {
+			return fmt.Errorf("property condition has no query")
+		}
+		if _, err := parseQuery(p.Query); err != nil {
+			return err
+		}
+	}
+	if c.Not != nil {
+		return c.Not.validate()
+	}
+	return nil
+}
+
+// Eval returns true if the condition holds for dm, or false and the
+// reason it doesn't.
+func (c *Condition) Eval(dm *enaml.DeploymentManifest) (bool, string, error) {
+	if err := c.validate(); err != nil {
+		return false, "", errs.InvalidArgs("invalid condition: %v", err)
+	}
+
+	if c.InstanceGroup != "" && dm.GetInstanceGroupByName(c.InstanceGroup) == nil {
+		return false, fmt.Sprintf("instance group %s doesn't exist", c.InstanceGroup), nil
+	}
+	if r := c.Release; r != nil {
+		if ok, reason := r.eval(dm); !ok {
+			return false, reason, nil
+		}
+	}
+	if p := c.Property; p != nil {
+		ok, reason, err := p.eval(dm)
+		if err != nil || !ok {
+			return false, reason, err
+		}
+	}
+	if c.Not != nil {
+		ok, _, err := c.Not.Eval(dm)
+		if err != nil {
+			return false, "", err
+		}
+		if ok {
+			return false, fmt.Sprintf("not condition doesn't hold: %s", c.Not), nil
+		}
+	}
+	return true, "", nil
+}
+
+func (c *Condition) String() string {
+	var parts []string
+	if c.InstanceGroup != "" {
+		parts = append(parts, "instance group "+c.InstanceGroup+" exists")
+	}
+	if r := c.Release; r != nil {
+		s := "release " + r.Name
+		if r.Version != "" {
+			s += " " + r.Version
+		}
+		parts = append(parts, s)
+	}
+	if p := c.Property; p != nil {
+		s := p.Query + " exists"
+		if p.Equals != nil {
+			s = p.Query + " = " + *p.Equals
+		}
+		if p.Min != nil {
+			s += fmt.Sprintf(" and >= %v", *p.Min)
+		}
+		if p.Max != nil {
+			s += fmt.Sprintf(" and <= %v", *p.Max)
+		}
+		parts = append(parts, s)
+	}
+	if c.Not != nil {
+		parts = append(parts, "not ("+c.Not.String()+")")
+	}
+	return strings.Join(parts, " and ")
+}
+
+func (r *ReleaseCondition) eval(dm *enaml.DeploymentManifest) (bool, string) {
+	for _, rel := range dm.Releases {
+		if rel.Name != r.Name {
+			continue
+		}
+		if r.Version == "" {
+			return true, ""
+		}
+		op, want, _ := parseVersionConstraint(r.Version)
+		if compareWith(op, compareVersions(rel.Version, want)) {
+			return true, ""
+		}
+		return false, fmt.Sprintf("release %s is at version %s, not %s", r.Name, rel.Version, r.Version)
+	}
+	return false, fmt.Sprintf("release %s isn't used", r.Name)
+}
+
+func (p *PropertyCondition) eval(dm *enaml.DeploymentManifest) (bool, string, error) {
+	values, err := Query(dm, p.Query)
+	if err != nil {
+		return false, "", errs.InvalidArgs("invalid condition: %v", err)
+	}
+	if len(values) == 0 {
+		return false, fmt.Sprintf("%s doesn't match anything", p.Query), nil
+	}
+	for _, v := range values {
+		if ok, reason := p.inRange(v); !ok {
+			return false, reason, nil
+		}
+	}
+	if p.Equals == nil {
+		return true, "", nil
+	}
+	var got []string
+	for _, v := range values {
+		s := fmt.Sprint(v)
+		if s == *p.Equals {
+			return true, "", nil
+		}
+		got = append(got, s)
+	}
+	return false, fmt.Sprintf("%s is %s, not %s", p.Query, strings.Join(got, ", "), *p.Equals), nil
+}
+
+// inRange returns true if v is within the condition's bounds, or false
+// and the reason it isn't.
+func (p *PropertyCondition) inRange(v interface{}) (bool, string) {
+	if p.Min == nil && p.Max == nil {
+		return true, ""
+	}
+	n, err := strconv.ParseFloat(fmt.Sprint(v), 64)
+	if err != nil {
+		return false, fmt.Sprintf("%s is %v, not a number", p.Query, v)
+	}
+	if p.Min != nil && n < *p.Min {
+		return false, fmt.Sprintf("%s is %v, less than %v", p.Query, v, *p.Min)
+	}
+	if p.Max != nil && n > *p.Max {
+		return false, fmt.Sprintf("%s is %v, more than %v", p.Query, v, *p.Max)
+	}
+	return true, ""
+}
+
+// parseVersionConstraint splits a constraint such as ">= 1.2" into its
+// operator and version.  A constraint without an operator is an
+// equality.
+func parseVersionConstraint(c string) (string, string, error) {
+	c = strings.TrimSpace(c)
+	op := "="
+	for _, o := range versionOperators {
+		if strings.HasPrefix(c, o) {
+			op, c = o, strings.TrimSpace(strings.TrimPrefix(c, o))
+			break
+		}
+	}
+	if op == "==" {
+		op = "="
+	}
+	if c == "" && op != "=" {
+		return "", "", fmt.Errorf("version constraint %q has no version", op)
+	}
+	return op, c, nil
+}
+
+func compareWith(op string, cmp int) bool {
+	switch op {
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	}
+	return cmp == 0
+}
+
+// compareVersions compares two dotted versions and returns -1, 0 or 1.
+// Components are compared numerically if both are numbers, otherwise as
+// strings, and missing components count as 0.
+func compareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		x, y := "0", "0"
+		if i < len(as) {
+			x = as[i]
+		}
+		if i < len(bs) {
+			y = bs[i]
+		}
+		xn, xerr := strconv.Atoi(x)
+		yn, yerr := strconv.Atoi(y)
+		switch {
+		case xerr == nil && yerr == nil && xn != yn:
+			if xn < yn {
+				return -1
+			}
+			return 1
+		case (xerr != nil || yerr != nil) && x != y:
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,72 @@
+package manifest
+
+import (
+	"io/ioutil"
+
+	"github.com/enaml-ops/enaml"
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("conditions", func() {
+	var dm *enaml.DeploymentManifest
+
+	BeforeEach(func() {
+		b, err := ioutil.ReadFile("fixtures/rotate-certs.yml")
+		Ω(err).ShouldNot(HaveOccurred())
+		dm = enaml.NewDeploymentManifest(b)
+	})
+
+	equals := func(s string) *string { return &s }
+	number := func(n float64) *float64 { return &n }
+
+	table.DescribeTable("evaluates conditions",
+		func(c Condition, holds bool, reason string) {
+			ok, why, err := c.Eval(dm)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(ok).Should(Equal(holds))
+			Ω(why).Should(Equal(reason))
+		},
+		table.Entry("an instance group that exists", Condition{InstanceGroup: "rotate-certs"}, true, ""),
+		table.Entry("an instance group that doesn't exist", Condition{InstanceGroup: "tcp_router"}, false, "instance group tcp_router doesn't exist"),
+		table.Entry("a release", Condition{Release: &ReleaseCondition{Name: "credhub"}}, true, ""),
+		table.Entry("a release that isn't used", Condition{Release: &ReleaseCondition{Name: "routing"}}, false, "release routing isn't used"),
+		table.Entry("a release version", Condition{Release: &ReleaseCondition{Name: "cf", Version: "239.0.5"}}, true, ""),
+		table.Entry("a newer release version", Condition{Release: &ReleaseCondition{Name: "cf", Version: ">= 239"}}, true, ""),
+		table.Entry("an older release version", Condition{Release: &ReleaseCondition{Name: "cf", Version: "< 239.0.10"}}, true, ""),
+		table.Entry("a release version that doesn't match", Condition{Release: &ReleaseCondition{Name: "cf", Version: ">240"}}, false, "release cf is at version 239.0.5, not >240"),
+		table.Entry("a property that exists", Condition{Property: &PropertyCondition{Query: ".tags.owner"}}, true, ""),
+		table.Entry("a property that doesn't exist", Condition{Property: &PropertyCondition{Query: ".tags.team"}}, false, ".tags.team doesn't match anything"),
+		table.Entry("a property value", Condition{Property: &PropertyCondition{Query: ".tags.cost-center", Equals: equals("1234")}}, true, ""),
+		table.Entry("a property value that doesn't match", Condition{Property: &PropertyCondition{Query: ".tags.owner", Equals: equals("ops")}}, false, ".tags.owner is security, not ops"),
+		table.Entry("a property within bounds", Condition{Property: &PropertyCondition{Query: ".instance_groups[name=rotate-certs].instances", Min: number(1), Max: number(2)}}, true, ""),
+		table.Entry("a property below its minimum", Condition{Property: &PropertyCondition{Query: ".instance_groups[name=rotate-certs].instances", Min: number(3)}}, false, ".instance_groups[name=rotate-certs].instances is 1, less than 3"),
+		table.Entry("a property above its maximum", Condition{Property: &PropertyCondition{Query: ".tags.cost-center", Max: number(1000)}}, false, ".tags.cost-center is 1234, more than 1000"),
+		table.Entry("a property that isn't a number", Condition{Property: &PropertyCondition{Query: ".tags.owner", Min: number(1)}}, false, ".tags.owner is security, not a number"),
+		table.Entry("a negated condition", Condition{Not: &Condition{InstanceGroup: "tcp_router"}}, true, ""),
+		table.Entry("a negated condition that doesn't hold", Condition{Not: &Condition{InstanceGroup: "rotate-certs"}}, false, "not condition doesn't hold: instance group rotate-certs exists"),
+		table.Entry("several conditions", Condition{InstanceGroup: "rotate-certs", Release: &ReleaseCondition{Name: "routing"}}, false, "release routing isn't used"),
+	)
+
+	It("returns an error for invalid conditions", func() {
+		_, _, err := (&Condition{}).Eval(dm)
+		Ω(err).Should(MatchError("invalid condition: condition is empty"))
+		_, _, err = (&Condition{Release: &ReleaseCondition{Name: "cf", Version: ">="}}).Eval(dm)
+		Ω(err).Should(MatchError(`invalid condition: version constraint ">=" has no version`))
+		_, _, err = (&Condition{Property: &PropertyCondition{Query: ".tags["}}).Eval(dm)
+		Ω(err).Should(HaveOccurred())
+	})
+
+	table.DescribeTable("compares versions",
+		func(a, b string, want int) {
+			Ω(compareVersions(a, b)).Should(Equal(want))
+			Ω(compareVersions(b, a)).Should(Equal(-want))
+		},
+		table.Entry("equal", "1.2.3", "1.2.3", 0),
+		table.Entry("missing components", "1.2", "1.2.0", 0),
+		table.Entry("numerically", "1.10", "1.9", 1),
+		table.Entry("whole numbers", "641", "97", 1),
+		table.Entry("non-numeric components", "1.0.rc2", "1.0.rc1", 1),
+	)
+})
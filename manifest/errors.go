@@ -0,0 +1,32 @@
+package manifest
+
+import (
+	"fmt"
+
+	"github.com/enaml-ops/enaml"
+	"github.com/enaml-ops/omg-transform/errs"
+	"github.com/enaml-ops/omg-transform/suggest"
+)
+
+// ErrInstanceGroupNotFound is returned when a transformation refers to
+// an instance group that isn't in the manifest.
+type ErrInstanceGroupNotFound struct {
+	Name        string   `json:"name"`
+	Suggestions []string `json:"suggestions,omitempty"` // similar instance groups
+}
+
+func (e *ErrInstanceGroupNotFound) Error() string {
+	return fmt.Sprintf("couldn't find instance group %s%s", e.Name, suggest.DidYouMean(e.Suggestions))
+}
+
+func (e *ErrInstanceGroupNotFound) ErrorKind() string { return errs.KindNotFound }
+
+// instanceGroupNotFound returns an ErrInstanceGroupNotFound suggesting
+// the instance groups in dm with similar names.
+func instanceGroupNotFound(dm *enaml.DeploymentManifest, name string) error {
+	var names []string
+	for _, ig := range dm.InstanceGroups {
+		names = append(names, ig.Name)
+	}
+	return &ErrInstanceGroupNotFound{Name: name, Suggestions: suggest.Similar(name, names)}
+}
@@ -0,0 +1,45 @@
+package manifest
+
+import (
+	"os"
+
+	"github.com/enaml-ops/enaml"
+	"github.com/enaml-ops/omg-transform/errs"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("errors", func() {
+	var manifest *enaml.DeploymentManifest
+
+	BeforeEach(func() {
+		f, err := os.Open("fixtures/pcf-aws-1.8.00-build.373.yml")
+		Ω(err).ShouldNot(HaveOccurred())
+		defer f.Close()
+		manifest = enaml.NewDeploymentManifestFromFile(f)
+	})
+
+	It("reports missing instance groups by name", func() {
+		err := (&ScaleInstance{InstanceGroup: "diego-cell", Scale: 2}).Apply(manifest)
+		Ω(err).Should(BeAssignableToTypeOf(&ErrInstanceGroupNotFound{}))
+		Ω(err.(*ErrInstanceGroupNotFound).Name).Should(Equal("diego-cell"))
+		Ω(err.(*ErrInstanceGroupNotFound).Suggestions).Should(Equal([]string{"diego_cell"}))
+		Ω(err).Should(MatchError("couldn't find instance group diego-cell, did you mean diego_cell?"))
+		Ω(err.(*ErrInstanceGroupNotFound).ErrorKind()).Should(Equal(errs.KindNotFound))
+	})
+
+	It("reports the flag at fault for invalid arguments", func() {
+		_, err := ChangeAZTransformation([]string{"-instance-group", "router"})
+		Ω(err).Should(Equal(&errs.ArgumentError{Flag: "az", Message: "missing required flag -az"}))
+
+		_, err = ChangeLifecycleTransformation([]string{"-instance-group", "router", "-lifecycle", "daemon"})
+		Ω(err).Should(BeAssignableToTypeOf(&errs.ArgumentError{}))
+		Ω(err.(*errs.ArgumentError).Flag).Should(Equal("lifecycle"))
+	})
+
+	It("reports failed preconditions", func() {
+		err := (&ErrandAdder{Name: "router", Job: "smoke-tests", Release: "cf"}).Apply(manifest)
+		Ω(err).Should(BeAssignableToTypeOf(&errs.PreconditionError{}))
+		Ω(err.(*errs.PreconditionError).ErrorKind()).Should(Equal(errs.KindPrecondition))
+	})
+})
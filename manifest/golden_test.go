@@ -0,0 +1,59 @@
+package manifest
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/enaml-ops/enaml"
+	"github.com/enaml-ops/omg-transform/golden"
+	. "github.com/onsi/gomega"
+	yaml "gopkg.in/yaml.v2"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in fixtures/golden")
+
+const pcfManifest = "fixtures/pcf-aws-1.8.00-build.373.yml"
+
+// goldenCase applies a transformation to an input manifest and compares
+// the result with a golden file in fixtures/golden.  Golden files ending
+// in .diff hold the structural diff of the input and output, any other
+// golden file holds the output manifest.
+type goldenCase struct {
+	Input     string   // the input manifest, defaults to the PCF fixture
+	Transform string   // the name of a registered transformation
+	Args      []string // the transformation's arguments
+	Golden    string   // the golden file, relative to fixtures/golden
+}
+
+func expectGolden(c goldenCase) {
+	input := c.Input
+	if input == "" {
+		input = pcfManifest
+	}
+	before, err := ioutil.ReadFile(input)
+	Ω(err).ShouldNot(HaveOccurred())
+
+	info, ok := LookupTransformation(c.Transform)
+	Ω(ok).Should(BeTrue(), "unknown transformation %q", c.Transform)
+	t, err := info.Builder(c.Args)
+	Ω(err).ShouldNot(HaveOccurred())
+
+	dm := enaml.NewDeploymentManifest(before)
+	Ω(t.Apply(dm)).Should(Succeed())
+	after, err := yaml.Marshal(dm)
+	Ω(err).ShouldNot(HaveOccurred())
+
+	got := after
+	if strings.HasSuffix(c.Golden, ".diff") {
+		// diff against the round-tripped input so that only the
+		// transformation's changes show up
+		orig, err := yaml.Marshal(enaml.NewDeploymentManifest(before))
+		Ω(err).ShouldNot(HaveOccurred())
+		lines, err := golden.Diff(orig, after)
+		Ω(err).ShouldNot(HaveOccurred())
+		got = []byte(strings.Join(append(lines, ""), "\n"))
+	}
+	Ω(golden.Check(filepath.Join("fixtures", "golden", c.Golden), got, *update)).Should(Succeed())
+}
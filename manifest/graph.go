@@ -0,0 +1,390 @@
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/enaml-ops/enaml"
+	"github.com/enaml-ops/omg-transform/suggest"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// The kinds of nodes in a Graph.
+const (
+	NodeInstanceGroup = "instance_group"
+	NodeJob           = "job"
+	NodeRelease       = "release"
+)
+
+// The kinds of edges in a Graph.
+const (
+	// EdgeJob connects an instance group to the jobs it runs.
+	EdgeJob = "job"
+	// EdgeRelease connects a job to the release that provides it.
+	EdgeRelease = "release"
+	// EdgeLink connects a job to the job providing a link it consumes
+	// with an explicit 'from'.
+	EdgeLink = "link"
+	// EdgeImplicitLink connects a job to the job providing a link it
+	// consumes by name, without an explicit 'from'.
+	EdgeImplicitLink = "implicit_link"
+	// EdgeStaticIP connects an instance group to another instance group
+	// whose static IPs appear in its properties.  This is how jobs
+	// find each other in manifests that predate links.
+	EdgeStaticIP = "static_ip"
+)
+
+// GraphNode is an instance group, job or release.
+type GraphNode struct {
+	ID   string `json:"id"`
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+}
+
+// GraphEdge is a dependency of one node on another.
+type GraphEdge struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Kind  string `json:"kind"`
+	Label string `json:"label,omitempty"`
+}
+
+// Graph describes what depends on what in a deployment manifest.
+type Graph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+// NewGraph builds the dependency graph of a deployment manifest.
+//
+// Links are resolved from the consumes and provides blocks declared in
+// the manifest.  Links provided implicitly by a job's release spec
+// aren't declared in the manifest and can't be seen.
+func NewGraph(dm *enaml.DeploymentManifest) *Graph {
+	g := &Graph{}
+	seen := make(map[string]bool)
+	addNode := func(n GraphNode) {
+		if !seen[n.ID] {
+			seen[n.ID] = true
+			g.Nodes = append(g.Nodes, n)
+		}
+	}
+
+	// the job that provides each link, by link name
+	providers := make(map[string]string)
+	for _, ig := range dm.InstanceGroups {
+		for _, job := range ig.Jobs {
+			for name, opts := range linkEntries(job.Provides) {
+				if as, ok := opts["as"].(string); ok && as != "" {
+					name = as
+				}
+				providers[name] = jobID(ig.Name, job.Name)
+			}
+		}
+	}
+
+	// the instance group that owns each static IP
+	owners := make(map[string]string)
+	for _, ig := range dm.InstanceGroups {
+		for _, n := range ig.Networks {
+			for _, ip := range n.StaticIPs {
+				owners[ip] = ig.Name
+			}
+		}
+	}
+
+	for _, ig := range dm.InstanceGroups {
+		addNode(GraphNode{ID: igID(ig.Name), Kind: NodeInstanceGroup, Name: ig.Name})
+		for _, job := range ig.Jobs {
+			id := jobID(ig.Name, job.Name)
+			addNode(GraphNode{ID: id, Kind: NodeJob, Name: job.Name})
+			g.addEdge(GraphEdge{From: igID(ig.Name), To: id, Kind: EdgeJob})
+			if job.Release != "" {
+				addNode(GraphNode{ID: releaseID(job.Release), Kind: NodeRelease, Name: job.Release})
+				g.addEdge(GraphEdge{From: id, To: releaseID(job.Release), Kind: EdgeRelease})
+			}
+
+			// sort the links so that the graph renders the same
+			// every time
+			consumes := linkEntries(job.Consumes)
+			names := make([]string, 0, len(consumes))
+			for name := range consumes {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				opts := consumes[name]
+				if opts == nil {
+					// the link has been explicitly disabled
+					continue
+				}
+				kind, from := EdgeImplicitLink, name
+				if f, ok := opts["from"].(string); ok && f != "" {
+					kind, from = EdgeLink, f
+				}
+				if d, ok := opts["deployment"].(string); ok && d != "" && d != dm.Name {
+					// provided by another deployment
+					continue
+				}
+				if provider, ok := providers[from]; ok && provider != id {
+					g.addEdge(GraphEdge{From: id, To: provider, Kind: kind, Label: from})
+				}
+			}
+		}
+
+		for _, ip := range referencedIPs(ig) {
+			if owner, ok := owners[ip]; ok && owner != ig.Name {
+				g.addEdge(GraphEdge{From: igID(ig.Name), To: igID(owner), Kind: EdgeStaticIP, Label: ip})
+			}
+		}
+	}
+	return g
+}
+
+// addEdge adds an edge, merging the labels of parallel edges of the
+// same kind.
+func (g *Graph) addEdge(e GraphEdge) {
+	for i := range g.Edges {
+		existing := &g.Edges[i]
+		if existing.From != e.From || existing.To != e.To || existing.Kind != e.Kind {
+			continue
+		}
+		if e.Label != "" && !contains(strings.Split(existing.Label, ","), e.Label) {
+			existing.Label = strings.Trim(existing.Label+","+e.Label, ",")
+		}
+		return
+	}
+	g.Edges = append(g.Edges, e)
+}
+
+// Neighbourhood returns the part of the graph within depth dependencies
+// of the named instance group, in either direction.  Instance groups are
+// connected through links and static IPs, and each instance group in the
+// neighbourhood is returned with its jobs and their releases.
+func (g *Graph) Neighbourhood(instanceGroup string, depth int) (*Graph, error) {
+	start := igID(instanceGroup)
+	if g.node(start) == nil {
+		var names []string
+		for _, n := range g.Nodes {
+			if n.Kind == NodeInstanceGroup {
+				names = append(names, n.Name)
+			}
+		}
+		return nil, &ErrInstanceGroupNotFound{Name: instanceGroup, Suggestions: suggest.Similar(instanceGroup, names)}
+	}
+
+	// the instance group each job node belongs to
+	owner := make(map[string]string)
+	for _, e := range g.Edges {
+		if e.Kind == EdgeJob {
+			owner[e.To] = e.From
+		}
+	}
+	igOf := func(id string) string {
+		if o, ok := owner[id]; ok {
+			return o
+		}
+		return id
+	}
+
+	included := map[string]bool{start: true}
+	frontier := []string{start}
+	for d := 0; d < depth && len(frontier) > 0; d++ {
+		var next []string
+		for _, e := range g.Edges {
+			if e.Kind == EdgeJob || e.Kind == EdgeRelease {
+				continue
+			}
+			from, to := igOf(e.From), igOf(e.To)
+			for _, id := range frontier {
+				var other string
+				switch id {
+				case from:
+					other = to
+				case to:
+					other = from
+				default:
+					continue
+				}
+				if !included[other] {
+					included[other] = true
+					next = append(next, other)
+				}
+			}
+		}
+		frontier = next
+	}
+
+	// include the jobs and releases of every included instance group
+	for _, e := range g.Edges {
+		if e.Kind == EdgeJob && included[e.From] {
+			included[e.To] = true
+		}
+	}
+	for _, e := range g.Edges {
+		if e.Kind == EdgeRelease && included[e.From] {
+			included[e.To] = true
+		}
+	}
+
+	sub := &Graph{}
+	for _, n := range g.Nodes {
+		if included[n.ID] {
+			sub.Nodes = append(sub.Nodes, n)
+		}
+	}
+	for _, e := range g.Edges {
+		if included[e.From] && included[e.To] {
+			sub.Edges = append(sub.Edges, e)
+		}
+	}
+	return sub, nil
+}
+
+func (g *Graph) node(id string) *GraphNode {
+	for i := range g.Nodes {
+		if g.Nodes[i].ID == id {
+			return &g.Nodes[i]
+		}
+	}
+	return nil
+}
+
+// WriteDOT writes the graph in Graphviz DOT format.
+func (g *Graph) WriteDOT(w io.Writer) error {
+	fmt.Fprintln(w, "digraph manifest {")
+	fmt.Fprintln(w, "  rankdir=LR;")
+	for _, n := range g.Nodes {
+		fmt.Fprintf(w, "  %q [label=%q, shape=%s];\n", n.ID, n.Name, dotShape(n.Kind))
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(w, "  %q -> %q [%s];\n", e.From, e.To, dotEdgeAttrs(e))
+	}
+	_, err := fmt.Fprintln(w, "}")
+	return err
+}
+
+func dotShape(kind string) string {
+	switch kind {
+	case NodeInstanceGroup:
+		return "box"
+	case NodeRelease:
+		return "folder"
+	}
+	return "ellipse"
+}
+
+func dotEdgeAttrs(e GraphEdge) string {
+	attrs := []string{fmt.Sprintf("label=%q", e.Label)}
+	switch e.Kind {
+	case EdgeImplicitLink:
+		attrs = append(attrs, "style=dashed")
+	case EdgeStaticIP:
+		attrs = append(attrs, "style=dotted")
+	case EdgeJob, EdgeRelease:
+		attrs = append(attrs, "color=gray")
+	}
+	return strings.Join(attrs, ", ")
+}
+
+// WriteMermaid writes the graph as a Mermaid flowchart.
+func (g *Graph) WriteMermaid(w io.Writer) error {
+	ids := make(map[string]string, len(g.Nodes))
+	fmt.Fprintln(w, "graph LR")
+	for i, n := range g.Nodes {
+		id := fmt.Sprintf("n%d", i)
+		ids[n.ID] = id
+		label := mermaidLabel(n.Name)
+		switch n.Kind {
+		case NodeInstanceGroup:
+			fmt.Fprintf(w, "  %s[%s]\n", id, label)
+		case NodeRelease:
+			fmt.Fprintf(w, "  %s[(%s)]\n", id, label)
+		default:
+			fmt.Fprintf(w, "  %s(%s)\n", id, label)
+		}
+	}
+	for _, e := range g.Edges {
+		arrow := "-->"
+		switch e.Kind {
+		case EdgeImplicitLink, EdgeStaticIP:
+			arrow = "-.->"
+		}
+		if e.Label != "" {
+			fmt.Fprintf(w, "  %s %s|%s| %s\n", ids[e.From], arrow, mermaidLabel(e.Label), ids[e.To])
+		} else {
+			fmt.Fprintf(w, "  %s %s %s\n", ids[e.From], arrow, ids[e.To])
+		}
+	}
+	return nil
+}
+
+func mermaidLabel(s string) string {
+	return `"` + strings.Replace(s, `"`, "#quot;", -1) + `"`
+}
+
+// WriteJSON writes the graph as JSON.
+func (g *Graph) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(g)
+}
+
+// referencedIPs returns the IP addresses that appear in the properties
+// of an instance group and its jobs, including those in host:port form.
+func referencedIPs(ig *enaml.InstanceGroup) []string {
+	var ips []string
+	seen := make(map[string]bool)
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch v := v.(type) {
+		case map[interface{}]interface{}:
+			for _, e := range v {
+				walk(e)
+			}
+		case []interface{}:
+			for _, e := range v {
+				walk(e)
+			}
+		case string:
+			s := v
+			if host, _, err := net.SplitHostPort(s); err == nil {
+				s = host
+			}
+			if net.ParseIP(s) != nil && !seen[s] {
+				seen[s] = true
+				ips = append(ips, s)
+			}
+		}
+	}
+
+	walk(yamlValue(ig.Properties))
+	for _, job := range ig.Jobs {
+		walk(yamlValue(job.Properties))
+	}
+	return ips
+}
+
+// yamlValue converts v to the generic values produced by the yaml package.
+func yamlValue(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	b, err := yaml.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var result interface{}
+	if yaml.Unmarshal(b, &result) != nil {
+		return nil
+	}
+	return result
+}
+
+func igID(name string) string      { return "instance_group/" + name }
+func jobID(ig, job string) string  { return "job/" + ig + "/" + job }
+func releaseID(name string) string { return "release/" + name }
@@ -0,0 +1,146 @@
+package manifest
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+
+	"github.com/enaml-ops/enaml"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Graph", func() {
+	hasEdge := func(g *Graph, from, to, kind string) bool {
+		for _, e := range g.Edges {
+			if e.From == from && e.To == to && e.Kind == kind {
+				return true
+			}
+		}
+		return false
+	}
+
+	Context("with declared links", func() {
+		var (
+			dm *enaml.DeploymentManifest
+			g  *Graph
+		)
+
+		BeforeEach(func() {
+			dm = enaml.NewDeploymentManifest([]byte(`
+name: cf
+instance_groups:
+- name: database
+  jobs:
+  - name: postgres
+    release: postgres
+    provides:
+      db: {as: ccdb}
+- name: api
+  jobs:
+  - name: cloud_controller
+    release: cf
+    consumes:
+      database: {from: ccdb}
+      nats: {}
+      blobstore: ~
+  - name: metron_agent
+    release: cf
+- name: nats
+  jobs:
+  - name: nats
+    release: cf
+    provides:
+      nats: {}
+- name: logs
+  jobs:
+  - name: syslog
+    release: syslog
+`))
+			g = NewGraph(dm)
+		})
+
+		It("connects instance groups, jobs and releases", func() {
+			Ω(hasEdge(g, "instance_group/api", "job/api/cloud_controller", EdgeJob)).Should(BeTrue())
+			Ω(hasEdge(g, "job/api/cloud_controller", "release/cf", EdgeRelease)).Should(BeTrue())
+
+			releases := 0
+			for _, n := range g.Nodes {
+				if n.Kind == NodeRelease {
+					releases++
+				}
+			}
+			Ω(releases).Should(Equal(3))
+		})
+
+		It("connects consumers to providers", func() {
+			Ω(hasEdge(g, "job/api/cloud_controller", "job/database/postgres", EdgeLink)).Should(BeTrue())
+			Ω(hasEdge(g, "job/api/cloud_controller", "job/nats/nats", EdgeImplicitLink)).Should(BeTrue())
+		})
+
+		It("returns the neighbourhood of an instance group", func() {
+			sub, err := g.Neighbourhood("database", 1)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(sub.node("instance_group/api")).ShouldNot(BeNil())
+			Ω(sub.node("job/api/metron_agent")).ShouldNot(BeNil())
+			Ω(sub.node("instance_group/nats")).Should(BeNil())
+			Ω(sub.node("instance_group/logs")).Should(BeNil())
+
+			sub, err = g.Neighbourhood("database", 2)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(sub.node("instance_group/nats")).ShouldNot(BeNil())
+			Ω(sub.node("instance_group/logs")).Should(BeNil())
+		})
+
+		It("returns an error for an unknown instance group", func() {
+			_, err := g.Neighbourhood("missing", 1)
+			Ω(err).Should(MatchError("couldn't find instance group missing"))
+		})
+
+		It("writes DOT", func() {
+			var buf bytes.Buffer
+			Ω(g.WriteDOT(&buf)).Should(Succeed())
+			Ω(buf.String()).Should(HavePrefix("digraph manifest {\n"))
+			Ω(buf.String()).Should(ContainSubstring(`"instance_group/api" [label="api", shape=box];`))
+			Ω(buf.String()).Should(ContainSubstring(`"job/api/cloud_controller" -> "job/nats/nats" [label="nats", style=dashed];`))
+		})
+
+		It("writes Mermaid", func() {
+			var buf bytes.Buffer
+			Ω(g.WriteMermaid(&buf)).Should(Succeed())
+			Ω(buf.String()).Should(HavePrefix("graph LR\n"))
+			Ω(buf.String()).Should(ContainSubstring(`[("cf")]`))
+			Ω(buf.String()).Should(MatchRegexp(`n\d+ -->\|"ccdb"\| n\d+`))
+		})
+
+		It("renders the same every time", func() {
+			var first bytes.Buffer
+			Ω(g.WriteDOT(&first)).Should(Succeed())
+			for i := 0; i < 10; i++ {
+				var again bytes.Buffer
+				Ω(NewGraph(dm).WriteDOT(&again)).Should(Succeed())
+				Ω(again.String()).Should(Equal(first.String()))
+			}
+		})
+
+		It("writes JSON", func() {
+			var buf bytes.Buffer
+			Ω(g.WriteJSON(&buf)).Should(Succeed())
+			var decoded Graph
+			Ω(json.Unmarshal(buf.Bytes(), &decoded)).Should(Succeed())
+			Ω(decoded).Should(Equal(*g))
+		})
+	})
+
+	Context("PCF 1.8 AWS manifest", func() {
+		It("finds instance groups that reference each other's static IPs", func() {
+			f, err := os.Open("fixtures/pcf-aws-1.8.00-build.373.yml")
+			Ω(err).ShouldNot(HaveOccurred())
+			defer f.Close()
+			g := NewGraph(enaml.NewDeploymentManifestFromFile(f))
+
+			Ω(hasEdge(g, "instance_group/consul_server", "instance_group/nats", EdgeStaticIP)).Should(BeTrue())
+			Ω(hasEdge(g, "instance_group/nats", "instance_group/nats", EdgeStaticIP)).Should(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,97 @@
+package manifest
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/enaml-ops/enaml"
+)
+
+// Inspect writes tables describing the instance groups, releases and
+// stemcells of a deployment manifest.
+func Inspect(w io.Writer, dm *enaml.DeploymentManifest) error {
+	fmt.Fprintf(w, "Deployment: %s\n\n", dm.Name)
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "INSTANCE GROUP\tINSTANCES\tAZS\tVM TYPE\tNETWORKS\tSTATIC IPS\tSTEMCELL\tLIFECYCLE\tJOBS")
+	for _, ig := range dm.InstanceGroups {
+		var networks, ips []string
+		for _, n := range ig.Networks {
+			networks = append(networks, n.Name)
+			ips = append(ips, n.StaticIPs...)
+		}
+		var jobs []string
+		for _, j := range ig.Jobs {
+			jobs = append(jobs, j.Name)
+		}
+		lifecycle := ig.Lifecycle
+		if lifecycle == "" {
+			lifecycle = LifecycleService
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			ig.Name, ig.Instances, list(ig.AZs), orNone(ig.VMType), list(networks),
+			list(ips), orNone(ig.Stemcell), lifecycle, list(jobs))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w)
+	tw = tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "RELEASE\tVERSION\tUSED BY")
+	for _, r := range dm.Releases {
+		fmt.Fprintf(tw, "%s\t%s\t%d instance groups\n", r.Name, orNone(r.Version), releaseUsage(dm, r.Name))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w)
+	tw = tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "STEMCELL\tOS\tVERSION\tUSED BY")
+	for _, s := range dm.Stemcells {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d instance groups\n", s.Alias, orNone(s.OS), orNone(s.Version), stemcellUsage(dm, s.Alias))
+	}
+	return tw.Flush()
+}
+
+// releaseUsage returns the number of instance groups with a job
+// from the named release.
+func releaseUsage(dm *enaml.DeploymentManifest, release string) int {
+	n := 0
+	for _, ig := range dm.InstanceGroups {
+		for _, j := range ig.Jobs {
+			if j.Release == release {
+				n++
+				break
+			}
+		}
+	}
+	return n
+}
+
+func stemcellUsage(dm *enaml.DeploymentManifest, alias string) int {
+	n := 0
+	for _, ig := range dm.InstanceGroups {
+		if ig.Stemcell == alias {
+			n++
+		}
+	}
+	return n
+}
+
+func list(values []string) string {
+	if len(values) == 0 {
+		return "-"
+	}
+	return strings.Join(values, ",")
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
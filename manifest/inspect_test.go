@@ -0,0 +1,33 @@
+package manifest
+
+import (
+	"bytes"
+	"os"
+
+	"github.com/enaml-ops/enaml"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Inspect", func() {
+	var manifest *enaml.DeploymentManifest
+
+	BeforeEach(func() {
+		f, err := os.Open("fixtures/pcf-aws-1.8.00-build.373.yml")
+		Ω(err).ShouldNot(HaveOccurred())
+		defer f.Close()
+		manifest = enaml.NewDeploymentManifestFromFile(f)
+	})
+
+	It("describes instance groups, releases and stemcells", func() {
+		var buf bytes.Buffer
+		Ω(Inspect(&buf, manifest)).Should(Succeed())
+
+		out := buf.String()
+		Ω(out).Should(HavePrefix("Deployment: cf-d05e4cdd400adc2c32ce\n"))
+		Ω(out).Should(MatchRegexp(`(?m)^nats\s+1\s+us-west-1b\s+t2.small\s+cf\s+10.0.0.8\s+bosh-aws-xen-hvm-ubuntu-trusty-go_agent\s+service\s+nats,metron_agent$`))
+		Ω(out).Should(MatchRegexp(`(?m)^smoke-tests\s+.*\serrand\s`))
+		Ω(out).Should(MatchRegexp(`(?m)^consul\s+\S+\s+\d+ instance groups$`))
+		Ω(out).Should(MatchRegexp(`(?m)^bosh-aws-xen-hvm-ubuntu-trusty-go_agent\s+ubuntu-trusty\s+3262.4\s+\d+ instance groups$`))
+	})
+})
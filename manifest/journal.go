@@ -0,0 +1,130 @@
+package manifest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/enaml-ops/enaml"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// AnnotationKey is the top-level manifest key that holds a journal
+// annotation.
+const AnnotationKey = "omg_transform_journal"
+
+// JournalEntry records a transformation applied to a manifest.
+type JournalEntry struct {
+	Step    `yaml:",inline"`
+	Version string    `yaml:"version,omitempty"` // the omg-transform version
+	Time    time.Time `yaml:"time"`
+	Input   string    `yaml:"input"` // the checksum of the manifest the step was applied to
+}
+
+// Journal records the transformations applied to a manifest, so that
+// they can be reviewed and replayed.
+type Journal struct {
+	Entries []JournalEntry `yaml:"entries"`
+
+	// Version is recorded in new entries.
+	Version string `yaml:"-"`
+
+	now func() time.Time
+}
+
+// Record adds an entry for a step applied to a manifest with the given
+// checksum.
+func (j *Journal) Record(step Step, input string) {
+	now := time.Now
+	if j.now != nil {
+		now = j.now
+	}
+	j.Entries = append(j.Entries, JournalEntry{
+		Step:    step,
+		Version: j.Version,
+		Time:    now().UTC(),
+		Input:   input,
+	})
+}
+
+// Pipeline returns a pipeline that replays the journal.
+func (j *Journal) Pipeline() *Pipeline {
+	p := &Pipeline{}
+	for _, e := range j.Entries {
+		p.Steps = append(p.Steps, e.Step)
+	}
+	return p
+}
+
+// Checksum returns the SHA-256 checksum of a manifest, as omg-transform
+// writes it, in sha256:hex format.  Formatting, comments and unknown
+// keys in the original file don't affect the checksum.
+func Checksum(dm *enaml.DeploymentManifest) (string, error) {
+	b, err := yaml.Marshal(dm)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(b)), nil
+}
+
+// ReadJournal reads a journal file.  A file that doesn't exist is an
+// empty journal.
+func ReadJournal(path string) (*Journal, error) {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &Journal{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	j := &Journal{}
+	if err = yaml.Unmarshal(b, j); err != nil {
+		return nil, fmt.Errorf("invalid journal %s: %v", path, err)
+	}
+	return j, nil
+}
+
+// WriteFile writes the journal to a YAML file.
+func (j *Journal) WriteFile(path string) error {
+	b, err := yaml.Marshal(j)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, b, 0644)
+}
+
+// ReadAnnotation returns the journal annotation of a manifest, or an
+// empty journal if it doesn't have one.
+func ReadAnnotation(manifest []byte) (*Journal, error) {
+	var annotated struct {
+		Entries []JournalEntry `yaml:"omg_transform_journal"`
+	}
+	if err := yaml.Unmarshal(manifest, &annotated); err != nil {
+		return nil, fmt.Errorf("invalid %s annotation: %v", AnnotationKey, err)
+	}
+	return &Journal{Entries: annotated.Entries}, nil
+}
+
+// Annotate adds the entries of a journal to a manifest as a top-level
+// annotation.  The manifest must not already have one, which is the
+// case for any manifest written by yaml.Marshal from an
+// enaml.DeploymentManifest.
+func Annotate(manifest []byte, j *Journal) ([]byte, error) {
+	if len(j.Entries) == 0 {
+		return manifest, nil
+	}
+	b, err := yaml.Marshal(map[string][]JournalEntry{AnnotationKey: j.Entries})
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	buf.Write(manifest)
+	if len(manifest) > 0 && manifest[len(manifest)-1] != '\n' {
+		buf.WriteByte('\n')
+	}
+	buf.Write(b)
+	return buf.Bytes(), nil
+}
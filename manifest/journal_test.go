@@ -0,0 +1,154 @@
+package manifest
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/enaml-ops/enaml"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	yaml "gopkg.in/yaml.v2"
+)
+
+var _ = Describe("journals", func() {
+	var (
+		input []byte
+		dm    *enaml.DeploymentManifest
+		dir   string
+		clock = time.Date(2016, 11, 1, 12, 0, 0, 0, time.UTC)
+	)
+
+	BeforeEach(func() {
+		var err error
+		input, err = ioutil.ReadFile("fixtures/rotate-certs.yml")
+		Ω(err).ShouldNot(HaveOccurred())
+		dm = enaml.NewDeploymentManifest(input)
+
+		dir, err = ioutil.TempDir("", "omg-transform")
+		Ω(err).ShouldNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	newJournal := func() *Journal {
+		return &Journal{Version: "v1.2.3", now: func() time.Time { return clock }}
+	}
+
+	It("checksums the manifest as omg-transform writes it", func() {
+		sum, err := Checksum(dm)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(sum).Should(MatchRegexp(`^sha256:[0-9a-f]{64}$`))
+
+		b, err := yaml.Marshal(dm)
+		Ω(err).ShouldNot(HaveOccurred())
+		again, err := Checksum(enaml.NewDeploymentManifest(append([]byte("# a comment\n"), b...)))
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(again).Should(Equal(sum))
+
+		dm.AddTag("owner", "ops")
+		changed, err := Checksum(dm)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(changed).ShouldNot(Equal(sum))
+	})
+
+	It("records the steps of a pipeline with the checksum of their input", func() {
+		before, err := Checksum(dm)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		j := newJournal()
+		p := &Pipeline{Steps: []Step{
+			{Transform: "scale", Args: []string{"-instance-group", "rotate-certs", "-instances", "2"}},
+			{Transform: "add-tags", Args: []string{"env=prod"}},
+		}}
+		_, err = p.Run(dm, RunOptions{Journal: j})
+		Ω(err).ShouldNot(HaveOccurred())
+
+		Ω(j.Entries).Should(HaveLen(2))
+		Ω(j.Entries[0]).Should(Equal(JournalEntry{Step: p.Steps[0], Version: "v1.2.3", Time: clock, Input: before}))
+		Ω(j.Entries[1].Step).Should(Equal(p.Steps[1]))
+		Ω(j.Entries[1].Input).ShouldNot(Equal(before))
+	})
+
+	It("doesn't record a step that fails", func() {
+		j := newJournal()
+		p := &Pipeline{Steps: []Step{
+			{Transform: "add-tags", Args: []string{"env=prod"}},
+			{Transform: "scale", Args: []string{"-instance-group", "nope", "-instances", "2"}},
+		}}
+		_, err := p.Run(dm, RunOptions{Journal: j})
+		Ω(err).Should(HaveOccurred())
+		Ω(j.Entries).Should(HaveLen(1))
+	})
+
+	It("writes and reads journal files", func() {
+		path := filepath.Join(dir, "journal.yml")
+		j, err := ReadJournal(path)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(j.Entries).Should(BeEmpty())
+
+		j = newJournal()
+		j.Record(Step{Transform: "add-tags", Args: []string{"env=prod"}}, "sha256:abc")
+		Ω(j.WriteFile(path)).Should(Succeed())
+
+		read, err := ReadJournal(path)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(read.Entries).Should(Equal(j.Entries))
+	})
+
+	It("returns an error for invalid journal files", func() {
+		path := filepath.Join(dir, "journal.yml")
+		Ω(ioutil.WriteFile(path, []byte("entries: 3\n"), 0644)).Should(Succeed())
+		_, err := ReadJournal(path)
+		Ω(err).Should(MatchError(ContainSubstring("invalid journal")))
+	})
+
+	It("annotates manifests without changing them", func() {
+		j := newJournal()
+		j.Record(Step{Transform: "add-tags", Args: []string{"env=prod"}}, "sha256:abc")
+
+		b, err := yaml.Marshal(dm)
+		Ω(err).ShouldNot(HaveOccurred())
+		annotated, err := Annotate(b, j)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(string(annotated)).Should(ContainSubstring("\n" + AnnotationKey + ":\n- transform: add-tags\n"))
+
+		read, err := ReadAnnotation(annotated)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(read.Entries).Should(Equal(j.Entries))
+
+		again, err := yaml.Marshal(enaml.NewDeploymentManifest(annotated))
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(again).Should(Equal(b))
+	})
+
+	It("doesn't annotate manifests with an empty journal", func() {
+		annotated, err := Annotate(input, &Journal{})
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(annotated).Should(Equal(input))
+
+		j, err := ReadAnnotation(input)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(j.Entries).Should(BeEmpty())
+	})
+
+	It("replays a journal", func() {
+		j := newJournal()
+		p := &Pipeline{Steps: []Step{
+			{Transform: "scale", Args: []string{"-instance-group", "rotate-certs", "-instances", "2"}},
+			{Transform: "add-tags", Args: []string{"env=prod"}},
+		}}
+		_, err := p.Run(dm, RunOptions{Journal: j})
+		Ω(err).ShouldNot(HaveOccurred())
+		want, err := yaml.Marshal(dm)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		fresh := enaml.NewDeploymentManifest(input)
+		_, err = j.Pipeline().Run(fresh, RunOptions{})
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(yaml.Marshal(fresh)).Should(Equal(want))
+	})
+})
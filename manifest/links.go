@@ -0,0 +1,97 @@
+package manifest
+
+import (
+	"sort"
+
+	"github.com/enaml-ops/enaml"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// linkEntries converts a job's consumes or provides block into a map
+// of link name to link options.  Links that are declared without any
+// options (or explicitly disabled with nil) have a nil options map.
+func linkEntries(block interface{}) map[string]map[string]interface{} {
+	if block == nil {
+		return nil
+	}
+	b, err := yaml.Marshal(block)
+	if err != nil {
+		return nil
+	}
+	var raw map[string]interface{}
+	if err = yaml.Unmarshal(b, &raw); err != nil {
+		return nil
+	}
+	result := make(map[string]map[string]interface{}, len(raw))
+	for name, v := range raw {
+		opts, _ := stringMap(v)
+		result[name] = opts
+	}
+	return result
+}
+
+// linkBlock converts link entries back into a value suitable for a
+// job's consumes or provides block.
+func linkBlock(entries map[string]map[string]interface{}) map[string]interface{} {
+	block := make(map[string]interface{}, len(entries))
+	for name, opts := range entries {
+		if opts == nil {
+			block[name] = nil
+			continue
+		}
+		block[name] = opts
+	}
+	return block
+}
+
+// stringMap converts the generic maps produced by the yaml package into
+// a map with string keys.
+func stringMap(v interface{}) (map[string]interface{}, bool) {
+	switch m := v.(type) {
+	case map[string]interface{}:
+		return m, true
+	case map[string]string:
+		result := make(map[string]interface{}, len(m))
+		for k, v := range m {
+			result[k] = v
+		}
+		return result, true
+	case map[interface{}]interface{}:
+		result := make(map[string]interface{}, len(m))
+		for k, v := range m {
+			if s, ok := k.(string); ok {
+				result[s] = v
+			}
+		}
+		return result, true
+	}
+	return nil, false
+}
+
+// linkProviders returns the instance group that provides each named
+// link in the manifest.  The name of a link is its alias (if provided
+// with 'as') or the name of the link itself.
+func linkProviders(dm *enaml.DeploymentManifest) map[string]string {
+	providers := make(map[string]string)
+	for _, ig := range dm.InstanceGroups {
+		for _, job := range ig.Jobs {
+			for name, opts := range linkEntries(job.Provides) {
+				if as, ok := opts["as"].(string); ok && as != "" {
+					name = as
+				}
+				providers[name] = ig.Name
+			}
+		}
+	}
+	return providers
+}
+
+// sortedKeys returns the keys of m in sorted order.
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,360 @@
+package manifest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/enaml-ops/enaml"
+	"github.com/enaml-ops/omg-transform/errs"
+	"github.com/enaml-ops/omg-transform/suggest"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// The severities of lint findings, from least to most severe.
+const (
+	SeverityInfo    = "info"
+	SeverityWarning = "warning"
+	SeverityError   = "error"
+)
+
+var severities = []string{SeverityInfo, SeverityWarning, SeverityError}
+
+// severityLevel returns the position of a severity in severities, or -1
+// if it isn't one.
+func severityLevel(s string) int {
+	for i, sev := range severities {
+		if s == sev {
+			return i
+		}
+	}
+	return -1
+}
+
+func validSeverity(s string) error {
+	if severityLevel(s) < 0 {
+		return fmt.Errorf("invalid severity %q, must be one of %s", s, strings.Join(severities, ", "))
+	}
+	return nil
+}
+
+// Finding is a problem reported by a lint rule.  InstanceGroup is set
+// if the problem is with a single instance group.
+type Finding struct {
+	Rule          string `json:"rule" yaml:"rule"`
+	Severity      string `json:"severity" yaml:"severity"`
+	InstanceGroup string `json:"instance_group,omitempty" yaml:"instance_group,omitempty"`
+	Message       string `json:"message" yaml:"message"`
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: %s: %s", f.Severity, f.Rule, f.Message)
+}
+
+// LintRule is a built-in lint rule.
+type LintRule struct {
+	Name        string
+	Description string
+	Severity    string // the default severity of the rule's findings
+
+	// InstanceGroups are the instance groups the rule checks, if it
+	// only checks some of them.  Except are the instance groups it
+	// doesn't check.  Both can be overridden in a rules file.
+	InstanceGroups []string
+	Except         []string
+
+	// check returns the problems in dm, looking only at the instance
+	// groups for which igs returns true.
+	check func(dm *enaml.DeploymentManifest, igs func(*enaml.InstanceGroup) bool) []finding
+}
+
+// finding is a problem found by a built-in rule.  instanceGroup is
+// empty for problems with the whole manifest.
+type finding struct {
+	instanceGroup string
+	message       string
+}
+
+// lintRules are the built-in rules, which are the rules for production
+// deployments.
+var lintRules = []LintRule{
+	{
+		Name:        "singleton",
+		Description: "service instance groups must have more than one instance",
+		Severity:    SeverityError,
+		Except:      []string{"clock_global"},
+		check: func(dm *enaml.DeploymentManifest, igs func(*enaml.InstanceGroup) bool) []finding {
+			var found []finding
+			for _, ig := range dm.InstanceGroups {
+				if isService(ig) && igs(ig) && ig.Instances == 1 {
+					found = append(found, finding{ig.Name, fmt.Sprintf("instance group %s has a single instance", ig.Name)})
+				}
+			}
+			return found
+		},
+	},
+	{
+		Name:           "odd-quorum",
+		Description:    "instance groups that need a quorum must have an odd number of instances",
+		Severity:       SeverityError,
+		InstanceGroups: []string{"consul_server", "etcd_server", "etcd_tls_server", "mysql"},
+		check: func(dm *enaml.DeploymentManifest, igs func(*enaml.InstanceGroup) bool) []finding {
+			var found []finding
+			for _, ig := range dm.InstanceGroups {
+				if igs(ig) && ig.Instances%2 == 0 && ig.Instances > 0 {
+					found = append(found, finding{ig.Name, fmt.Sprintf("instance group %s has %d instances, it needs an odd number to keep a quorum", ig.Name, ig.Instances)})
+				}
+			}
+			return found
+		},
+	},
+	{
+		Name:        "multi-az",
+		Description: "service instance groups must span at least 2 availability zones",
+		Severity:    SeverityError,
+		check: func(dm *enaml.DeploymentManifest, igs func(*enaml.InstanceGroup) bool) []finding {
+			var found []finding
+			for _, ig := range dm.InstanceGroups {
+				if isService(ig) && igs(ig) && ig.Instances > 0 && len(ig.AZs) < 2 {
+					found = append(found, finding{ig.Name, fmt.Sprintf("instance group %s spans %d availability zones", ig.Name, len(ig.AZs))})
+				}
+			}
+			return found
+		},
+	},
+	{
+		Name:        "canaries",
+		Description: "updates must use at least one canary",
+		Severity:    SeverityError,
+		check: func(dm *enaml.DeploymentManifest, igs func(*enaml.InstanceGroup) bool) []finding {
+			// instance groups inherit the canaries of the deployment's
+			// update block, and an instance group that sets them to 0
+			// can't be told apart from one that doesn't set them
+			if dm.Update.Canaries < 1 {
+				return []finding{{"", fmt.Sprintf("the deployment updates with %d canaries", dm.Update.Canaries)}}
+			}
+			return nil
+		},
+	},
+}
+
+// LintRules returns the built-in lint rules.
+func LintRules() []LintRule {
+	return append([]LintRule(nil), lintRules...)
+}
+
+func lookupLintRule(name string) (LintRule, bool) {
+	for _, r := range lintRules {
+		if r.Name == name {
+			return r, true
+		}
+	}
+	return LintRule{}, false
+}
+
+func isService(ig *enaml.InstanceGroup) bool {
+	return ig.Lifecycle == "" || ig.Lifecycle == "service"
+}
+
+// RuleConfig configures a built-in rule or defines a rule.  A rule
+// defined in a rules file reports Message if its When condition holds
+// and its Require condition doesn't.
+type RuleConfig struct {
+	Name     string `yaml:"name"`
+	Severity string `yaml:"severity,omitempty"`
+	Disabled bool   `yaml:"disabled,omitempty"`
+
+	// InstanceGroups and Except override those of a built-in rule.
+	InstanceGroups []string `yaml:"instance_groups,omitempty"`
+	Except         []string `yaml:"except,omitempty"`
+
+	Message string     `yaml:"message,omitempty"`
+	When    *Condition `yaml:"when,omitempty"`
+	Require *Condition `yaml:"require,omitempty"`
+}
+
+// RuleSet is a set of lint rules.  The built-in rules are always
+// included, unless they are disabled.
+//
+//	rules:
+//	- name: singleton
+//	  except: [clock_global, tcp_router]
+//	- name: multi-az
+//	  severity: warning
+//	- name: routers
+//	  message: there must be at least 3 routers
+//	  require:
+//	    property: {query: ".instance_groups[name=router].instances", min: 3}
+type RuleSet struct {
+	Rules []RuleConfig `yaml:"rules"`
+}
+
+// ReadRuleSet reads a rule set from a YAML file.
+func ReadRuleSet(path string) (*RuleSet, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	rs := &RuleSet{}
+	if err = yaml.Unmarshal(b, rs); err != nil {
+		return nil, fmt.Errorf("invalid rules file %s: %v", path, err)
+	}
+	if err = rs.validate(); err != nil {
+		return nil, fmt.Errorf("invalid rules file %s: %v", path, err)
+	}
+	return rs, nil
+}
+
+func (rs *RuleSet) validate() error {
+	seen := map[string]bool{}
+	for i, r := range rs.Rules {
+		if r.Name == "" {
+			return fmt.Errorf("rule %d has no name", i+1)
+		}
+		if seen[r.Name] {
+			return fmt.Errorf("rule %s is defined twice", r.Name)
+		}
+		seen[r.Name] = true
+		if r.Severity != "" {
+			if err := validSeverity(r.Severity); err != nil {
+				return fmt.Errorf("rule %s: %v", r.Name, err)
+			}
+		}
+
+		if _, ok := lookupLintRule(r.Name); ok {
+			if r.Require != nil || r.When != nil {
+				return fmt.Errorf("rule %s is a built-in rule and can't be redefined", r.Name)
+			}
+			continue
+		}
+		if r.Require == nil {
+			var names []string
+			for _, b := range lintRules {
+				names = append(names, b.Name)
+			}
+			return fmt.Errorf("rule %s has no require condition and isn't a built-in rule%s", r.Name, suggest.DidYouMean(suggest.Similar(r.Name, names)))
+		}
+		if err := r.Require.validate(); err != nil {
+			return fmt.Errorf("rule %s: %v", r.Name, err)
+		}
+		if r.When != nil {
+			if err := r.When.validate(); err != nil {
+				return fmt.Errorf("rule %s: %v", r.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (rs *RuleSet) config(name string) RuleConfig {
+	if rs != nil {
+		for _, r := range rs.Rules {
+			if r.Name == name {
+				return r
+			}
+		}
+	}
+	return RuleConfig{Name: name}
+}
+
+// Lint checks dm against the built-in rules, as configured by rs, and
+// the rules defined in rs, which may be nil.  Findings are sorted by
+// severity, most severe first.
+func Lint(dm *enaml.DeploymentManifest, rs *RuleSet) ([]Finding, error) {
+	var findings []Finding
+	for _, rule := range lintRules {
+		cfg := rs.config(rule.Name)
+		if cfg.Disabled {
+			continue
+		}
+		severity := rule.Severity
+		if cfg.Severity != "" {
+			severity = cfg.Severity
+		}
+		only, except := rule.InstanceGroups, rule.Except
+		if cfg.InstanceGroups != nil {
+			only = cfg.InstanceGroups
+		}
+		if cfg.Except != nil {
+			except = cfg.Except
+		}
+		igs := func(ig *enaml.InstanceGroup) bool {
+			return (only == nil || contains(only, ig.Name)) && !contains(except, ig.Name)
+		}
+		for _, f := range rule.check(dm, igs) {
+			findings = append(findings, Finding{Rule: rule.Name, Severity: severity, InstanceGroup: f.instanceGroup, Message: f.message})
+		}
+	}
+
+	if rs != nil {
+		for _, r := range rs.Rules {
+			if _, ok := lookupLintRule(r.Name); ok || r.Disabled {
+				continue
+			}
+			f, err := r.eval(dm)
+			if err != nil {
+				return nil, fmt.Errorf("rule %s: %w", r.Name, err)
+			}
+			if f != nil {
+				findings = append(findings, *f)
+			}
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		return severityLevel(findings[i].Severity) > severityLevel(findings[j].Severity)
+	})
+	return findings, nil
+}
+
+// eval evaluates a rule defined in a rules file.
+func (r RuleConfig) eval(dm *enaml.DeploymentManifest) (*Finding, error) {
+	if r.When != nil {
+		ok, _, err := r.When.Eval(dm)
+		if err != nil || !ok {
+			return nil, err
+		}
+	}
+	ok, reason, err := r.Require.Eval(dm)
+	if err != nil || ok {
+		return nil, err
+	}
+
+	f := &Finding{Rule: r.Name, Severity: r.Severity, InstanceGroup: r.Require.InstanceGroup, Message: r.Message}
+	if f.Severity == "" {
+		f.Severity = SeverityError
+	}
+	if f.Message == "" {
+		f.Message = reason
+	}
+	return f, nil
+}
+
+// FailingFindings returns the findings that are at least as severe as
+// threshold.
+func FailingFindings(findings []Finding, threshold string) []Finding {
+	var failing []Finding
+	for _, f := range findings {
+		if severityLevel(f.Severity) >= severityLevel(threshold) {
+			failing = append(failing, f)
+		}
+	}
+	return failing
+}
+
+// ErrLintFailed is returned when a manifest has lint findings at or
+// above the severity that fails a run.
+type ErrLintFailed struct {
+	Findings []Finding
+}
+
+func (e *ErrLintFailed) Error() string {
+	lines := make([]string, len(e.Findings))
+	for i, f := range e.Findings {
+		lines[i] = f.String()
+	}
+	return fmt.Sprintf("the manifest has %d lint findings:\n  %s", len(e.Findings), strings.Join(lines, "\n  "))
+}
+
+func (e *ErrLintFailed) ErrorKind() string { return errs.KindPrecondition }
@@ -0,0 +1,127 @@
+package manifest
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/enaml-ops/enaml"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const lintManifest = `name: cf
+releases:
+- name: cf
+  version: "239"
+update:
+  canaries: 0
+instance_groups:
+- name: clock_global
+  instances: 1
+  azs: [z1, z2]
+- name: router
+  instances: 2
+  azs: [z1]
+- name: consul_server
+  instances: 2
+  azs: [z1, z2]
+- name: uaa
+  instances: 1
+  azs: [z1, z2]
+- name: smoke-tests
+  instances: 1
+  lifecycle: errand
+  azs: [z1]
+`
+
+var _ = Describe("lint", func() {
+	var (
+		dm  *enaml.DeploymentManifest
+		dir string
+	)
+
+	BeforeEach(func() {
+		dm = enaml.NewDeploymentManifest([]byte(lintManifest))
+		var err error
+		dir, err = ioutil.TempDir("", "omg-transform")
+		Ω(err).ShouldNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("checks the built-in rules", func() {
+		findings, err := Lint(dm, nil)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(findings).Should(Equal([]Finding{
+			{Rule: "singleton", Severity: SeverityError, InstanceGroup: "uaa", Message: "instance group uaa has a single instance"},
+			{Rule: "odd-quorum", Severity: SeverityError, InstanceGroup: "consul_server", Message: "instance group consul_server has 2 instances, it needs an odd number to keep a quorum"},
+			{Rule: "multi-az", Severity: SeverityError, InstanceGroup: "router", Message: "instance group router spans 1 availability zones"},
+			{Rule: "canaries", Severity: SeverityError, Message: "the deployment updates with 0 canaries"},
+		}))
+	})
+
+	It("configures the built-in rules and checks the rules defined in a rules file", func() {
+		path := filepath.Join(dir, "rules.yml")
+		Ω(ioutil.WriteFile(path, []byte(`
+rules:
+- name: singleton
+  except: [clock_global, uaa]
+- name: odd-quorum
+  instance_groups: [router]
+- name: multi-az
+  severity: warning
+- name: canaries
+  disabled: true
+- name: routers
+  severity: info
+  message: there should be 3 routers
+  when:
+    release: {name: cf, version: ">= 239"}
+  require:
+    property: {query: ".instance_groups[name=router].instances", min: 3}
+- name: no-uaa
+  require:
+    not: {instance_group: uaa}
+`), 0644)).Should(Succeed())
+		rs, err := ReadRuleSet(path)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		findings, err := Lint(dm, rs)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(findings).Should(Equal([]Finding{
+			{Rule: "odd-quorum", Severity: SeverityError, InstanceGroup: "router", Message: "instance group router has 2 instances, it needs an odd number to keep a quorum"},
+			{Rule: "no-uaa", Severity: SeverityError, Message: "not condition doesn't hold: instance group uaa exists"},
+			{Rule: "multi-az", Severity: SeverityWarning, InstanceGroup: "router", Message: "instance group router spans 1 availability zones"},
+			{Rule: "routers", Severity: SeverityInfo, Message: "there should be 3 routers"},
+		}))
+
+		Ω(FailingFindings(findings, SeverityWarning)).Should(HaveLen(3))
+		Ω(FailingFindings(findings, SeverityInfo)).Should(HaveLen(4))
+	})
+
+	It("returns an error for invalid rules files", func() {
+		for rules, msg := range map[string]string{
+			"rules:\n- severity: error\n":                              "rule 1 has no name",
+			"rules:\n- name: singleton\n  severity: fatal\n":           `rule singleton: invalid severity "fatal", must be one of info, warning, error`,
+			"rules:\n- name: singletons\n":                             "rule singletons has no require condition and isn't a built-in rule, did you mean singleton?",
+			"rules:\n- name: canaries\n  require: {instance_group: a}": "rule canaries is a built-in rule and can't be redefined",
+			"rules:\n- name: a\n  require: {}\n":                       "rule a: condition is empty",
+		} {
+			path := filepath.Join(dir, "rules.yml")
+			Ω(ioutil.WriteFile(path, []byte(rules), 0644)).Should(Succeed())
+			_, err := ReadRuleSet(path)
+			Ω(err).Should(MatchError("invalid rules file " + path + ": " + msg))
+		}
+	})
+
+	It("lists the built-in rules", func() {
+		var names []string
+		for _, r := range LintRules() {
+			names = append(names, r.Name)
+		}
+		Ω(names).Should(Equal([]string{"singleton", "odd-quorum", "multi-az", "canaries"}))
+	})
+})
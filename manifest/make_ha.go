@@ -0,0 +1,278 @@
+package manifest
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/enaml-ops/enaml"
+	"github.com/enaml-ops/omg-transform/cloudconfig"
+	"github.com/enaml-ops/omg-transform/errs"
+	"github.com/enaml-ops/omg-transform/registry"
+)
+
+// HAProfile holds the instance counts that make a deployment highly
+// available.
+type HAProfile struct {
+	Name string
+
+	// Minimums are the minimum numbers of instances of instance
+	// groups.  Other service instance groups get at least
+	// DefaultMinimum instances.
+	Minimums       map[string]int
+	DefaultMinimum int
+
+	// Consensus are the instance groups that need an odd number of
+	// instances to keep a quorum.
+	Consensus []string
+
+	// Singletons are the instance groups that can't run more than one
+	// instance, and whose number of instances is kept.
+	Singletons []string
+}
+
+// haProfiles are the built-in profiles, by name.
+var haProfiles = map[string]*HAProfile{
+	"pcf": {
+		Name: "pcf",
+		Minimums: map[string]int{
+			"consul_server":                 3,
+			"etcd_server":                   3,
+			"etcd_tls_server":               3,
+			"diego_database":                3,
+			"mysql":                         3,
+			"diego_cell":                    3,
+			"nats":                          2,
+			"mysql_proxy":                   2,
+			"uaa":                           2,
+			"cloud_controller":              2,
+			"cloud_controller_worker":       2,
+			"ha_proxy":                      2,
+			"diego_brain":                   2,
+			"doppler":                       2,
+			"loggregator_trafficcontroller": 2,
+			"router":                        2,
+			"tcp_router":                    2,
+		},
+		DefaultMinimum: 2,
+		Consensus:      []string{"consul_server", "diego_database", "etcd_server", "etcd_tls_server", "mysql"},
+		Singletons:     []string{"backup-prepare", "ccdb", "clock_global", "nfs_server", "uaadb"},
+	},
+}
+
+// HAProfiles returns the names of the built-in profiles.
+func HAProfiles() []string {
+	var names []string
+	for name := range haProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// instances returns the number of instances of ig once it is spread
+// across azs AZs.
+func (p *HAProfile) instances(ig *enaml.InstanceGroup, azs int) int {
+	if contains(p.Singletons, ig.Name) {
+		return ig.Instances
+	}
+	n := ig.Instances
+	min, ok := p.Minimums[ig.Name]
+	if !ok {
+		min = p.DefaultMinimum
+	}
+	if n < min {
+		n = min
+	}
+	if !contains(p.Consensus, ig.Name) {
+		return rebalance(n, azs)
+	}
+	if n < azs {
+		n = azs
+	}
+	if n%2 == 0 {
+		n++
+	}
+	return n
+}
+
+// HAMaker is a transformation that spreads every service instance group
+// that has instances across a list of AZs, and raises its number of
+// instances to the minimum of an HA profile.
+type HAMaker struct {
+	AZs     []string
+	Profile *HAProfile
+
+	// CloudConfig, if set, is used to assign static IPs in the
+	// subnets of each AZ.
+	CloudConfig *enaml.CloudConfigManifest
+
+	// Plan, if set, receives a description of how instances
+	// move between AZs.
+	Plan io.Writer
+
+	azsFlag         string
+	profileFlag     string
+	cloudConfigFlag string
+	planFlag        bool
+}
+
+func (h *HAMaker) Apply(dm *enaml.DeploymentManifest) error {
+	if h.CloudConfig != nil {
+		for _, az := range h.AZs {
+			if err := cloudconfig.CheckAZ(h.CloudConfig, az); err != nil {
+				return err
+			}
+		}
+	}
+
+	// every instance group is planned before any is changed, so that
+	// dm is left untouched and no plan is written if an error is
+	// returned
+	type haPlan struct {
+		ig        *enaml.InstanceGroup
+		azs       []string
+		instances int
+		staticIPs staticIPAssignment
+	}
+	var plans []haPlan
+	planned := make(map[string]staticIPAssignment)
+	for _, ig := range h.instanceGroups(dm) {
+		azs := h.AZs
+		if contains(h.Profile.Singletons, ig.Name) {
+			// keep singletons where they are
+			azs = currentFirst(ig.AZs, h.AZs)
+		}
+		p := haPlan{ig: ig, azs: azs, instances: h.Profile.instances(ig, len(azs))}
+
+		if h.CloudConfig != nil {
+			a, err := planStaticIPs(dm, ig, h.CloudConfig, distribute(p.instances, azs), azs, planned)
+			if err != nil {
+				return err
+			}
+			planned[ig.Name] = a
+			p.staticIPs = a
+		} else if p.instances != ig.Instances && hasStaticIPs(ig) {
+			return errs.Preconditionf("instance group %s has static IPs, a cloud config is required to make it highly available", ig.Name)
+		}
+		plans = append(plans, p)
+	}
+
+	if h.Plan != nil {
+		var buf bytes.Buffer
+		for _, p := range plans {
+			if err := PlanAZMigration(p.ig, p.azs, p.instances).Write(&buf); err != nil {
+				return err
+			}
+		}
+		if _, err := h.Plan.Write(buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	for _, p := range plans {
+		p.staticIPs.apply(p.ig)
+		p.ig.AZs = p.azs
+		p.ig.Instances = p.instances
+	}
+	return nil
+}
+
+// instanceGroups returns the instance groups of dm that are made highly
+// available: those that run services and have instances.
+func (h *HAMaker) instanceGroups(dm *enaml.DeploymentManifest) []*enaml.InstanceGroup {
+	var igs []*enaml.InstanceGroup
+	for _, ig := range dm.InstanceGroups {
+		if isService(ig) && ig.Instances > 0 {
+			igs = append(igs, ig)
+		}
+	}
+	return igs
+}
+
+// currentFirst returns azs with the AZs in current first, so that BOSH
+// leaves the instances in current where they are.
+func currentFirst(current, azs []string) []string {
+	var result []string
+	for _, az := range current {
+		if contains(azs, az) {
+			result = append(result, az)
+		}
+	}
+	for _, az := range azs {
+		if !contains(result, az) {
+			result = append(result, az)
+		}
+	}
+	return result
+}
+
+func (h *HAMaker) Idempotent() bool { return true }
+
+// Inverse restores the AZs and number of instances of every instance
+// group that is made highly available and, when static IPs are moved,
+// their static IPs.
+func (h *HAMaker) Inverse(dm *enaml.DeploymentManifest) ([]Step, error) {
+	var steps []Step
+	for _, ig := range h.instanceGroups(dm) {
+		a := &AZChanger{InstanceGroup: ig.Name, CloudConfig: h.CloudConfig}
+		s, err := a.Inverse(dm)
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, s...)
+	}
+	return steps, nil
+}
+
+func (h *HAMaker) flagSet() *flag.FlagSet {
+	fs := registry.NewFlagSet("make-ha")
+	fs.StringVar(&h.azsFlag, "az", "", "a comma separated list of the AZs to spread instance groups across")
+	fs.StringVar(&h.profileFlag, "profile", "pcf", "the profile with the minimum number of instances of each instance group ("+strings.Join(HAProfiles(), ", ")+")")
+	fs.StringVar(&h.cloudConfigFlag, "cloud-config", "", "path to a cloud config used to assign static IPs in each AZ")
+	fs.BoolVar(&h.planFlag, "plan", false, "print how instances move between AZs to stderr")
+	return fs
+}
+
+// MakeHATransformation is a TransformationBuilder that builds the
+// 'make-ha' transformation.
+func MakeHATransformation(args []string) (Transformation, error) {
+	h := &HAMaker{}
+	fs := h.flagSet()
+	err := fs.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, errs.InvalidArgs("unexpected arguments %v", fs.Args())
+	}
+
+	if h.azsFlag == "" {
+		return nil, errs.MissingFlag("az")
+	}
+	if strings.Contains(h.azsFlag, " ") {
+		return nil, errs.InvalidFlag("az", "invalid format for az, cannot contain space")
+	}
+	h.AZs = registry.Split(h.azsFlag, ",")
+	if len(h.AZs) == 0 {
+		return nil, errs.InvalidFlag("az", "invalid format for az, must be comma-separated")
+	}
+
+	var ok bool
+	if h.Profile, ok = haProfiles[h.profileFlag]; !ok {
+		return nil, errs.InvalidFlag("profile", "unknown profile %q, must be one of %s", h.profileFlag, strings.Join(HAProfiles(), ", "))
+	}
+
+	if h.cloudConfigFlag != "" {
+		h.CloudConfig, err = readCloudConfig(h.cloudConfigFlag)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if h.planFlag {
+		h.Plan = os.Stderr
+	}
+	return h, nil
+}
@@ -0,0 +1,151 @@
+package manifest
+
+import (
+	"bytes"
+	"os"
+
+	"github.com/enaml-ops/enaml"
+	"github.com/enaml-ops/omg-transform/errs"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	yaml "gopkg.in/yaml.v2"
+)
+
+var _ = Describe("make HA transformation", func() {
+	Context("when creating the transformation", func() {
+		It("returns an error if the az argument is missing", func() {
+			_, err := MakeHATransformation(nil)
+			Ω(err).Should(MatchError("missing required flag -az"))
+		})
+
+		It("returns an error if the az argument is malformed", func() {
+			_, err := MakeHATransformation([]string{"-az", "az1 az2"})
+			Ω(err).Should(HaveOccurred())
+			_, err = MakeHATransformation([]string{"-az", ",,"})
+			Ω(err).Should(HaveOccurred())
+		})
+
+		It("returns an error for unknown profiles", func() {
+			_, err := MakeHATransformation([]string{"-az", "az1,az2", "-profile", "pks"})
+			Ω(err).Should(MatchError(`unknown profile "pks", must be one of pcf`))
+			Ω(err.(*errs.ArgumentError).Flag).Should(Equal("profile"))
+		})
+
+		It("uses the PCF profile by default", func() {
+			t, err := MakeHATransformation([]string{"-az", "az1,az2", "-cloud-config", "fixtures/cloud-config-aws.yml"})
+			Ω(err).ShouldNot(HaveOccurred())
+			h := t.(*HAMaker)
+			Ω(h.AZs).Should(Equal([]string{"az1", "az2"}))
+			Ω(h.Profile.Name).Should(Equal("pcf"))
+			Ω(h.CloudConfig).ShouldNot(BeNil())
+		})
+	})
+
+	Context("PCF 1.8 AWS manifest", func() {
+		var manifest *enaml.DeploymentManifest
+
+		BeforeEach(func() {
+			f, err := os.Open(pcfManifest)
+			Ω(err).ShouldNot(HaveOccurred())
+			defer f.Close()
+			manifest = enaml.NewDeploymentManifestFromFile(f)
+		})
+
+		It("spreads the service instance groups and assigns static IPs in each AZ", func() {
+			expectGolden(goldenCase{
+				Transform: "make-ha",
+				Args:      []string{"-az", "us-west-1b,us-west-1c", "-cloud-config", "fixtures/cloud-config-aws.yml"},
+				Golden:    "make-ha-static-ips.diff",
+			})
+		})
+
+		It("raises instance counts to the profile's minimums", func() {
+			t, err := MakeHATransformation([]string{"-az", "us-west-1b,us-west-1c", "-cloud-config", "fixtures/cloud-config-aws.yml"})
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(t.Apply(manifest)).Should(Succeed())
+
+			for name, instances := range map[string]int{
+				"consul_server": 3, // consensus jobs keep an odd number
+				"mysql":         3,
+				"diego_cell":    4, // spread evenly
+				"router":        2,
+				"clock_global":  1, // singletons stay singletons
+				"nfs_server":    1,
+				"ccdb":          0, // disabled instance groups stay disabled
+				"smoke-tests":   1,
+			} {
+				Ω(manifest.GetInstanceGroupByName(name).Instances).Should(Equal(instances), name)
+			}
+			Ω(manifest.GetInstanceGroupByName("router").AZs).Should(Equal([]string{"us-west-1b", "us-west-1c"}))
+			Ω(manifest.GetInstanceGroupByName("ccdb").AZs).Should(Equal([]string{"us-west-1b"}))
+			Ω(manifest.GetInstanceGroupByName("smoke-tests").AZs).Should(Equal([]string{"us-west-1b"}))
+		})
+
+		It("keeps singletons in their AZ", func() {
+			t, err := MakeHATransformation([]string{"-az", "us-west-1c,us-west-1b", "-cloud-config", "fixtures/cloud-config-aws.yml"})
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(t.Apply(manifest)).Should(Succeed())
+
+			nfs := manifest.GetInstanceGroupByName("nfs_server")
+			Ω(nfs.AZs).Should(Equal([]string{"us-west-1b", "us-west-1c"}))
+			Ω(nfs.Networks[0].StaticIPs).Should(Equal([]string{"10.0.0.11"}))
+			Ω(manifest.GetInstanceGroupByName("router").AZs).Should(Equal([]string{"us-west-1c", "us-west-1b"}))
+		})
+
+		It("requires a cloud config to scale instance groups with static IPs", func() {
+			t, err := MakeHATransformation([]string{"-az", "us-west-1b,us-west-1c"})
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(t.Apply(manifest)).Should(MatchError("instance group consul_server has static IPs, a cloud config is required to make it highly available"))
+		})
+
+		It("returns an error for AZs that aren't in the cloud config", func() {
+			t, err := MakeHATransformation([]string{"-az", "us-west-1b,us-west-1d", "-cloud-config", "fixtures/cloud-config-aws.yml"})
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(t.Apply(manifest)).Should(HaveOccurred())
+		})
+
+		It("leaves the manifest untouched if an instance group can't be made highly available", func() {
+			// the last service instance group uses a network the cloud
+			// config doesn't define
+			h := &HAMaker{AZs: []string{"us-west-1b", "us-west-1c"}, Profile: haProfiles["pcf"]}
+			igs := h.instanceGroups(manifest)
+			last := igs[len(igs)-1]
+			last.Networks = append(last.Networks, enaml.Network{Name: "unknown", StaticIPs: []string{"10.9.0.1"}})
+			before, err := yaml.Marshal(manifest)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			var plan bytes.Buffer
+			h.Plan = &plan
+			h.CloudConfig, _ = readCloudConfig("fixtures/cloud-config-aws.yml")
+			Ω(h.Apply(manifest)).Should(MatchError(HavePrefix("network unknown is not defined in the cloud config")))
+			after, err := yaml.Marshal(manifest)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(string(after)).Should(Equal(string(before)))
+			Ω(plan.String()).Should(BeEmpty())
+		})
+
+		It("doesn't give the same static IP to two AZs that share a subnet", func() {
+			h := &HAMaker{AZs: []string{"us-west-1b", "us-west-1c"}, Profile: haProfiles["pcf"]}
+			h.CloudConfig, _ = readCloudConfig("fixtures/cloud-config-multi-az.yml")
+			Ω(h.Apply(manifest)).Should(Succeed())
+
+			seen := make(map[string]string)
+			for _, ig := range manifest.InstanceGroups {
+				for _, n := range ig.Networks {
+					for _, ip := range n.StaticIPs {
+						Ω(seen).ShouldNot(HaveKey(ip), "%s is used by %s and %s", ip, seen[ip], ig.Name)
+						seen[ip] = ig.Name
+					}
+				}
+			}
+		})
+
+		It("writes a plan", func() {
+			var plan bytes.Buffer
+			h := &HAMaker{AZs: []string{"us-west-1b", "us-west-1c"}, Profile: haProfiles["pcf"], Plan: &plan}
+			h.CloudConfig, _ = readCloudConfig("fixtures/cloud-config-aws.yml")
+			Ω(h.Apply(manifest)).Should(Succeed())
+			Ω(plan.String()).Should(ContainSubstring("instance group consul_server:\n  us-west-1b:  1 -> 2  (+1)\n  us-west-1c:  0 -> 1  (+1)\n"))
+		})
+	})
+})
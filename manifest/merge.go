@@ -0,0 +1,269 @@
+package manifest
+
+import (
+	"flag"
+	"os"
+	"reflect"
+
+	"github.com/enaml-ops/enaml"
+	"github.com/enaml-ops/omg-transform/errs"
+	"github.com/enaml-ops/omg-transform/registry"
+)
+
+// ConflictPolicy controls how Merge resolves elements that are present
+// in both manifests.
+type ConflictPolicy string
+
+const (
+	// ConflictError fails the merge on the first conflict.
+	ConflictError ConflictPolicy = "error"
+	// ConflictKeep keeps the element from the manifest being merged into.
+	ConflictKeep ConflictPolicy = "keep"
+	// ConflictReplace uses the element from the manifest being merged in.
+	ConflictReplace ConflictPolicy = "replace"
+)
+
+// Merger is a transformation that merges another deployment manifest
+// into the manifest being transformed.
+type Merger struct {
+	Manifest *enaml.DeploymentManifest // manifest to merge in
+	Policy   ConflictPolicy
+	path     string
+	policy   string
+}
+
+func (m *Merger) Apply(dm *enaml.DeploymentManifest) error {
+	return Merge(dm, m.Manifest, m.Policy)
+}
+
+// Idempotent returns true, merging the same manifest again finds only
+// identical elements.
+func (m *Merger) Idempotent() bool { return true }
+
+// Merge merges the instance groups, releases, stemcells, global
+// properties and tags of src into dst.  The name and update block of
+// dst are preserved.
+//
+// Two elements conflict when they share a name but differ: instance
+// groups by name, releases by name, stemcells by alias, and properties
+// and tags by key.  Identical elements are not considered conflicts.
+// Conflicts are resolved according to policy, and dst is left untouched
+// if the merge fails.
+//
+// Cross-deployment links between dst and src become regular links once
+// both live in the same deployment.
+func Merge(dst, src *enaml.DeploymentManifest, policy ConflictPolicy) error {
+	switch policy {
+	case ConflictError, ConflictKeep, ConflictReplace:
+	default:
+		return errs.InvalidFlag("on-conflict", "invalid conflict policy %q", policy)
+	}
+
+	resolve := func(kind, name string) (bool, error) {
+		switch policy {
+		case ConflictKeep:
+			return false, nil
+		case ConflictReplace:
+			return true, nil
+		}
+		return false, errs.Preconditionf("%s %s is defined differently in %s and %s", kind, name, dst.Name, src.Name)
+	}
+
+	instanceGroups := append([]*enaml.InstanceGroup(nil), dst.InstanceGroups...)
+	for _, ig := range src.InstanceGroups {
+		i := indexOfInstanceGroup(instanceGroups, ig.Name)
+		if i < 0 {
+			instanceGroups = append(instanceGroups, ig)
+			continue
+		}
+		if reflect.DeepEqual(instanceGroups[i], ig) {
+			continue
+		}
+		replace, err := resolve("instance group", ig.Name)
+		if err != nil {
+			return err
+		}
+		if replace {
+			instanceGroups[i] = ig
+		}
+	}
+
+	releases := append([]enaml.Release(nil), dst.Releases...)
+	for _, r := range src.Releases {
+		i := indexOfRelease(releases, r.Name)
+		if i < 0 {
+			releases = append(releases, r)
+			continue
+		}
+		if reflect.DeepEqual(releases[i], r) {
+			continue
+		}
+		replace, err := resolve("release", r.Name)
+		if err != nil {
+			return err
+		}
+		if replace {
+			releases[i] = r
+		}
+	}
+
+	stemcells := append([]enaml.Stemcell(nil), dst.Stemcells...)
+	for _, s := range src.Stemcells {
+		i := indexOfStemcell(stemcells, s.Alias)
+		if i < 0 {
+			stemcells = append(stemcells, s)
+			continue
+		}
+		if reflect.DeepEqual(stemcells[i], s) {
+			continue
+		}
+		replace, err := resolve("stemcell", s.Alias)
+		if err != nil {
+			return err
+		}
+		if replace {
+			stemcells[i] = s
+		}
+	}
+
+	properties := make(enaml.Properties, len(dst.Properties))
+	for k, v := range dst.Properties {
+		properties[k] = v
+	}
+	for k, v := range src.Properties {
+		existing, ok := properties[k]
+		if ok && !reflect.DeepEqual(existing, v) {
+			replace, err := resolve("property", k)
+			if err != nil {
+				return err
+			}
+			if !replace {
+				continue
+			}
+		}
+		properties[k] = v
+	}
+
+	tags := make(map[string]string, len(dst.Tags))
+	for k, v := range dst.Tags {
+		tags[k] = v
+	}
+	for k, v := range src.Tags {
+		existing, ok := tags[k]
+		if ok && existing != v {
+			replace, err := resolve("tag", k)
+			if err != nil {
+				return err
+			}
+			if !replace {
+				continue
+			}
+		}
+		tags[k] = v
+	}
+
+	dst.InstanceGroups = instanceGroups
+	dst.Releases = releases
+	dst.Stemcells = stemcells
+	if len(properties) > 0 {
+		dst.Properties = properties
+	}
+	if len(tags) > 0 {
+		dst.Tags = tags
+	}
+	localizeLinks(dst, dst.Name, src.Name)
+	return nil
+}
+
+// localizeLinks removes the deployment from links that are consumed
+// from any of the named deployments.
+func localizeLinks(dm *enaml.DeploymentManifest, deployments ...string) {
+	local := make(map[string]bool, len(deployments))
+	for _, d := range deployments {
+		local[d] = true
+	}
+
+	for _, ig := range dm.InstanceGroups {
+		for i := range ig.Jobs {
+			job := &ig.Jobs[i]
+			consumes := linkEntries(job.Consumes)
+			changed := false
+			for _, opts := range consumes {
+				if d, ok := opts["deployment"].(string); ok && local[d] {
+					delete(opts, "deployment")
+					changed = true
+				}
+			}
+			if changed {
+				job.Consumes = linkBlock(consumes)
+			}
+		}
+	}
+}
+
+func indexOfInstanceGroup(igs []*enaml.InstanceGroup, name string) int {
+	for i := range igs {
+		if igs[i].Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func indexOfRelease(releases []enaml.Release, name string) int {
+	for i := range releases {
+		if releases[i].Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func indexOfStemcell(stemcells []enaml.Stemcell, alias string) int {
+	for i := range stemcells {
+		if stemcells[i].Alias == alias {
+			return i
+		}
+	}
+	return -1
+}
+
+func (m *Merger) flagSet() *flag.FlagSet {
+	fs := registry.NewFlagSet("merge")
+	fs.StringVar(&m.path, "manifest", "", "path to the deployment manifest to merge in")
+	fs.StringVar(&m.policy, "on-conflict", string(ConflictError), "how to resolve conflicting names (error, keep or replace)")
+	return fs
+}
+
+// MergeTransformation is a TransformationBuilder that builds the
+// 'merge' transformation.
+func MergeTransformation(args []string) (Transformation, error) {
+	m := &Merger{}
+	fs := m.flagSet()
+	err := fs.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.path == "" {
+		return nil, errs.MissingFlag("manifest")
+	}
+	m.Policy = ConflictPolicy(m.policy)
+	switch m.Policy {
+	case ConflictError, ConflictKeep, ConflictReplace:
+	default:
+		return nil, errs.InvalidFlag("on-conflict", "invalid value %q for -on-conflict, must be one of error, keep or replace", m.policy)
+	}
+
+	f, err := os.Open(m.path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	m.Manifest = enaml.NewDeploymentManifestFromFile(f)
+	if m.Manifest == nil {
+		return nil, errs.InvalidFlag("manifest", "invalid manifest %s", m.path)
+	}
+	return m, nil
+}
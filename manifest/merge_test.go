@@ -0,0 +1,119 @@
+package manifest
+
+import (
+	"os"
+
+	"github.com/enaml-ops/enaml"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("merge transformation", func() {
+	Context("when creating the transformation", func() {
+		It("returns an error if no arguments are provided", func() {
+			_, err := MergeTransformation(nil)
+			Ω(err).Should(HaveOccurred())
+		})
+
+		It("returns an error if the conflict policy is invalid", func() {
+			_, err := MergeTransformation([]string{"-manifest", "fixtures/pcf-aws-1.8.00-build.373.yml", "-on-conflict", "ignore"})
+			Ω(err).Should(HaveOccurred())
+		})
+
+		It("returns an error if the manifest doesn't exist", func() {
+			_, err := MergeTransformation([]string{"-manifest", "fixtures/does-not-exist.yml"})
+			Ω(err).Should(HaveOccurred())
+		})
+
+		It("returns a transformation when given valid args", func() {
+			t, err := MergeTransformation([]string{"-manifest", "fixtures/pcf-aws-1.8.00-build.373.yml", "-on-conflict", "keep"})
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(t).ShouldNot(BeNil())
+
+			m := t.(*Merger)
+			Ω(m.Policy).Should(Equal(ConflictKeep))
+			Ω(m.Manifest).ShouldNot(BeNil())
+		})
+	})
+
+	Context("PCF 1.8 AWS manifest", func() {
+		var manifest, diego *enaml.DeploymentManifest
+
+		BeforeEach(func() {
+			f, err := os.Open("fixtures/pcf-aws-1.8.00-build.373.yml")
+			Ω(err).ShouldNot(HaveOccurred())
+			manifest = enaml.NewDeploymentManifestFromFile(f)
+
+			diego, err = Split(manifest, "diego", []string{"diego_cell", "diego_brain"})
+			Ω(err).ShouldNot(HaveOccurred())
+		})
+
+		It("merges a split deployment back together", func() {
+			count := len(manifest.InstanceGroups) + len(diego.InstanceGroups)
+			releases := len(manifest.Releases)
+
+			Ω(Merge(manifest, diego, ConflictError)).Should(Succeed())
+			Ω(manifest.InstanceGroups).Should(HaveLen(count))
+			Ω(manifest.Releases).Should(HaveLen(releases))
+			Ω(manifest.GetInstanceGroupByName("diego_cell")).ShouldNot(BeNil())
+		})
+
+		It("adds the instance groups and releases of the other manifest", func() {
+			expectGolden(goldenCase{
+				Transform: "merge",
+				Args:      []string{"-manifest", "fixtures/rotate-certs.yml"},
+				Golden:    "merge-rotate-certs.diff",
+			})
+		})
+
+		Context("when the manifests conflict", func() {
+			BeforeEach(func() {
+				diego.Releases[0].Version = "9999"
+			})
+
+			It("returns an error by default", func() {
+				m := &Merger{Manifest: diego, Policy: ConflictError}
+				Ω(m.Apply(manifest)).ShouldNot(Succeed())
+				Ω(manifest.GetInstanceGroupByName("diego_cell")).Should(BeNil())
+			})
+
+			It("keeps the existing element", func() {
+				Ω(Merge(manifest, diego, ConflictKeep)).Should(Succeed())
+				Ω(manifest.Releases[indexOfRelease(manifest.Releases, diego.Releases[0].Name)].Version).ShouldNot(Equal("9999"))
+			})
+
+			It("replaces the existing element", func() {
+				Ω(Merge(manifest, diego, ConflictReplace)).Should(Succeed())
+				Ω(manifest.Releases[indexOfRelease(manifest.Releases, diego.Releases[0].Name)].Version).Should(Equal("9999"))
+			})
+		})
+	})
+
+	It("turns cross-deployment links into local links", func() {
+		dst := enaml.NewDeploymentManifest([]byte(`
+name: cf
+instance_groups:
+- name: database
+  jobs:
+  - name: postgres
+    release: postgres
+    provides:
+      db: {as: cc_db, shared: true}
+`))
+		src := enaml.NewDeploymentManifest([]byte(`
+name: api
+instance_groups:
+- name: api
+  jobs:
+  - name: cloud_controller
+    release: cf
+    consumes:
+      db: {from: cc_db, deployment: cf}
+`))
+		Ω(Merge(dst, src, ConflictError)).Should(Succeed())
+
+		consumes := linkEntries(dst.GetInstanceGroupByName("api").Jobs[0].Consumes)
+		Ω(consumes["db"]).Should(HaveKeyWithValue("from", "cc_db"))
+		Ω(consumes["db"]).ShouldNot(HaveKey("deployment"))
+	})
+})
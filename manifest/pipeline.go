@@ -0,0 +1,387 @@
+package manifest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/enaml-ops/enaml"
+	"github.com/enaml-ops/omg-transform/errs"
+	"github.com/enaml-ops/omg-transform/golden"
+	"github.com/enaml-ops/omg-transform/suggest"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Step is a registered transformation and its arguments.  In a
+// pipeline, the arguments are expanded as text/template templates and
+// the step is skipped unless its When condition holds.
+type Step struct {
+	Transform string     `yaml:"transform"`
+	Args      []string   `yaml:"args,omitempty"`
+	When      *Condition `yaml:"when,omitempty"`
+}
+
+func (s Step) String() string {
+	return strings.TrimSpace(s.Transform + " " + strings.Join(s.Args, " "))
+}
+
+// Build looks up the step's transformation and builds it from the
+// step's arguments.
+func (s Step) Build() (Transformation, error) {
+	info, ok := LookupTransformation(s.Transform)
+	if !ok {
+		names := transformations.Names()
+		return nil, errs.InvalidArgs("unknown transform %q%s", s.Transform, suggest.DidYouMean(suggest.Similar(s.Transform, names)))
+	}
+	return info.Builder(s.Args)
+}
+
+// Pipeline is a list of steps applied to a manifest in order.
+//
+//	steps:
+//	- transform: scale
+//	  args: [-instance-group, router, -instances, "{{ .routers }}"]
+//	- transform: add-vm-extension
+//	  args: [-instance-group, router, -name, public-lbs]
+//	  when:
+//	    release: {name: cf, version: ">= 239"}
+type Pipeline struct {
+	Steps []Step `yaml:"steps"`
+}
+
+// ReadPipeline reads a pipeline from a YAML file.
+func ReadPipeline(path string) (*Pipeline, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p := &Pipeline{}
+	if err = yaml.Unmarshal(b, p); err != nil {
+		return nil, fmt.Errorf("invalid pipeline %s: %v", path, err)
+	}
+	for i, s := range p.Steps {
+		if s.Transform == "" {
+			return nil, fmt.Errorf("invalid pipeline %s: step %d has no transform", path, i+1)
+		}
+		if s.When != nil {
+			if err := s.When.validate(); err != nil {
+				return nil, fmt.Errorf("invalid pipeline %s: step %d: %v", path, i+1, err)
+			}
+		}
+	}
+	return p, nil
+}
+
+// RunOptions control how a pipeline is run.
+type RunOptions struct {
+	// CheckIdempotency applies every step that declares itself
+	// idempotent a second time, and fails if that changes the manifest.
+	CheckIdempotency bool
+
+	// Journal, if set, records each step that is applied.
+	Journal *Journal
+
+	// Vars are the values available to the templates in the steps'
+	// arguments.
+	Vars map[string]interface{}
+
+	// Lint, if set, checks the manifest once every step has been
+	// applied.
+	Lint *LintOptions
+}
+
+// LintOptions control how a manifest is linted after a run.  Rules may
+// be nil, to check only the built-in rules.  The run fails if there are
+// findings at least as severe as FailOn, which defaults to
+// SeverityError.
+type LintOptions struct {
+	Rules  *RuleSet
+	FailOn string
+}
+
+// ReadVars reads the variables used by a pipeline's templates from a
+// YAML file.
+func ReadVars(path string) (map[string]interface{}, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var vars map[string]interface{}
+	if err = yaml.Unmarshal(b, &vars); err != nil {
+		return nil, fmt.Errorf("invalid vars file %s: %v", path, err)
+	}
+	for k, v := range vars {
+		vars[k] = normalize(v)
+	}
+	return vars, nil
+}
+
+// templateFuncs are the functions available to the templates in the
+// steps' arguments, besides the text/template built-ins.
+var templateFuncs = template.FuncMap{
+	"env": os.Getenv,
+	"join": func(list interface{}, sep string) (string, error) {
+		switch l := list.(type) {
+		case []string:
+			return strings.Join(l, sep), nil
+		case []interface{}:
+			s := make([]string, len(l))
+			for i := range l {
+				s[i] = fmt.Sprint(l[i])
+			}
+			return strings.Join(s, sep), nil
+		}
+		return "", fmt.Errorf("can't join %T", list)
+	},
+}
+
+// expand returns the step with its arguments expanded as templates.
+func (s Step) expand(vars map[string]interface{}) (Step, error) {
+	expanded := Step{Transform: s.Transform}
+	for i, arg := range s.Args {
+		if !strings.Contains(arg, "{{") {
+			expanded.Args = append(expanded.Args, arg)
+			continue
+		}
+		t, err := template.New(fmt.Sprintf("arg %d", i+1)).Funcs(templateFuncs).Option("missingkey=error").Parse(arg)
+		if err != nil {
+			return Step{}, errs.InvalidArgs("%v", err)
+		}
+		var buf bytes.Buffer
+		if err = t.Execute(&buf, vars); err != nil {
+			return Step{}, errs.InvalidArgs("%v", err)
+		}
+		expanded.Args = append(expanded.Args, buf.String())
+	}
+	return expanded, nil
+}
+
+// TransformLog records the steps applied to a manifest and how to undo
+// them, and the lint findings for the result if it was linted.
+type TransformLog struct {
+	Entries  []LogEntry `yaml:"entries"`
+	Findings []Finding  `yaml:"findings,omitempty"`
+}
+
+// LogEntry records a step, with its arguments expanded.  Undo holds the
+// steps that undo it, or Irreversible the reason it can't be undone.
+// Skipped is the reason a step whose condition didn't hold was skipped.
+type LogEntry struct {
+	Step         `yaml:",inline"`
+	Idempotent   bool   `yaml:"idempotent,omitempty"`
+	Undo         []Step `yaml:"undo,omitempty"`
+	Irreversible string `yaml:"irreversible,omitempty"`
+	Skipped      string `yaml:"skipped,omitempty"`
+}
+
+// ReadTransformLog reads a transform log written by WriteFile.
+func ReadTransformLog(path string) (*TransformLog, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	l := &TransformLog{}
+	if err = yaml.Unmarshal(b, l); err != nil {
+		return nil, fmt.Errorf("invalid transform log %s: %v", path, err)
+	}
+	return l, nil
+}
+
+// WriteFile writes the log to a YAML file.
+func (l *TransformLog) WriteFile(path string) error {
+	b, err := yaml.Marshal(l)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, b, 0644)
+}
+
+// StepError is returned when a step of a pipeline fails.
+type StepError struct {
+	Index int // the position of the step, starting at 1
+	Step  Step
+	Err   error
+}
+
+func (e *StepError) Error() string {
+	return fmt.Sprintf("step %d (%s): %v", e.Index, e.Step.Transform, e.Err)
+}
+
+func (e *StepError) Unwrap() error { return e.Err }
+
+// ErrNotIdempotent is returned when a step that declares itself
+// idempotent changes the manifest when it is applied a second time.
+type ErrNotIdempotent struct {
+	Changes []string // the changes made by the second run, as reported by golden.Diff
+}
+
+func (e *ErrNotIdempotent) Error() string {
+	return "applying the transformation a second time changed the manifest:\n  " + strings.Join(e.Changes, "\n  ")
+}
+
+// Run applies the steps of the pipeline to dm in order, and returns a
+// log of the steps that were applied or skipped.  The log is returned
+// even if a step fails, and covers the steps before it.  If
+// opts.Lint is set, an ErrLintFailed is returned if the result fails
+// the lint checks.
+func (p *Pipeline) Run(dm *enaml.DeploymentManifest, opts RunOptions) (*TransformLog, error) {
+	log := &TransformLog{}
+	for i, step := range p.Steps {
+		fail := func(err error) (*TransformLog, error) {
+			return log, &StepError{Index: i + 1, Step: step, Err: err}
+		}
+
+		if step.When != nil {
+			ok, reason, err := step.When.Eval(dm)
+			if err != nil {
+				return fail(err)
+			}
+			if !ok {
+				skipped := Step{Transform: step.Transform, Args: step.Args}
+				log.Entries = append(log.Entries, LogEntry{Step: skipped, Skipped: reason})
+				continue
+			}
+		}
+
+		s, err := step.expand(opts.Vars)
+		if err != nil {
+			return fail(err)
+		}
+
+		t, err := s.Build()
+		if err != nil {
+			return fail(err)
+		}
+
+		entry := LogEntry{Step: s, Idempotent: IsIdempotent(t)}
+		if inv, ok := t.(Invertible); ok {
+			entry.Undo, err = inv.Inverse(dm)
+			if err != nil {
+				entry.Irreversible = err.Error()
+			}
+		} else {
+			entry.Irreversible = s.Transform + " doesn't support undo"
+		}
+
+		var input string
+		if opts.Journal != nil {
+			if input, err = Checksum(dm); err != nil {
+				return fail(err)
+			}
+		}
+
+		if err = t.Apply(dm); err != nil {
+			return fail(err)
+		}
+		if opts.CheckIdempotency && entry.Idempotent {
+			if err = checkIdempotent(t, dm); err != nil {
+				return fail(err)
+			}
+		}
+		log.Entries = append(log.Entries, entry)
+		if opts.Journal != nil {
+			opts.Journal.Record(s, input)
+		}
+	}
+
+	if opts.Lint != nil {
+		failOn := opts.Lint.FailOn
+		if failOn == "" {
+			failOn = SeverityError
+		}
+		if err := validSeverity(failOn); err != nil {
+			return log, errs.InvalidArgs("%v", err)
+		}
+		findings, err := Lint(dm, opts.Lint.Rules)
+		if err != nil {
+			return log, err
+		}
+		log.Findings = findings
+		if failing := FailingFindings(findings, failOn); len(failing) > 0 {
+			return log, &ErrLintFailed{Findings: failing}
+		}
+	}
+	return log, nil
+}
+
+// checkIdempotent applies t to dm a second time and returns an
+// ErrNotIdempotent if that changes it.
+func checkIdempotent(t Transformation, dm *enaml.DeploymentManifest) error {
+	before, err := yaml.Marshal(dm)
+	if err != nil {
+		return err
+	}
+	if err = t.Apply(dm); err != nil {
+		return fmt.Errorf("applying the transformation a second time failed: %v", err)
+	}
+	after, err := yaml.Marshal(dm)
+	if err != nil {
+		return err
+	}
+	changes, err := golden.Diff(before, after)
+	if err != nil {
+		return err
+	}
+	if len(changes) > 0 {
+		return &ErrNotIdempotent{Changes: changes}
+	}
+	return nil
+}
+
+// Undo rolls back the steps recorded in a transform log, last step
+// first.  dm is left untouched if any of the steps can't be undone.
+func Undo(dm *enaml.DeploymentManifest, log *TransformLog) error {
+	type undoStep struct {
+		entry int // the index of the log entry being undone
+		step  Step
+		t     Transformation
+	}
+	var steps []undoStep
+	for i := len(log.Entries) - 1; i >= 0; i-- {
+		e := log.Entries[i]
+		if e.Skipped != "" {
+			continue
+		}
+		if e.Irreversible != "" {
+			return &StepError{Index: i + 1, Step: e.Step, Err: errs.Preconditionf("can't be undone: %s", e.Irreversible)}
+		}
+		for _, s := range e.Undo {
+			t, err := s.Build()
+			if err != nil {
+				return &StepError{Index: i + 1, Step: e.Step, Err: fmt.Errorf("undo %s: %w", s, err)}
+			}
+			steps = append(steps, undoStep{entry: i, step: s, t: t})
+		}
+	}
+
+	// the steps are applied to a copy, so that dm is left untouched if
+	// one of them fails
+	out, err := copyManifest(dm)
+	if err != nil {
+		return err
+	}
+	for _, s := range steps {
+		if err := s.t.Apply(out); err != nil {
+			return &StepError{Index: s.entry + 1, Step: log.Entries[s.entry].Step, Err: fmt.Errorf("undo %s: %w", s.step, err)}
+		}
+	}
+	*dm = *out
+	return nil
+}
+
+// copyManifest returns a deep copy of dm.
+func copyManifest(dm *enaml.DeploymentManifest) (*enaml.DeploymentManifest, error) {
+	b, err := yaml.Marshal(dm)
+	if err != nil {
+		return nil, err
+	}
+	out := enaml.NewDeploymentManifest(b)
+	if out == nil {
+		return nil, errors.New("invalid manifest")
+	}
+	return out, nil
+}
@@ -0,0 +1,414 @@
+package manifest
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/enaml-ops/enaml"
+	"github.com/enaml-ops/omg-transform/errs"
+	"github.com/enaml-ops/omg-transform/golden"
+	"github.com/enaml-ops/omg-transform/registry"
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// appendTag is a transformation that isn't idempotent but declares
+// that it is.
+type appendTag struct{}
+
+func (appendTag) Apply(dm *enaml.DeploymentManifest) error {
+	dm.AddTag("runs", dm.Tag("runs")+"x")
+	return nil
+}
+
+func (appendTag) Idempotent() bool { return true }
+
+var _ = Describe("pipelines", func() {
+	var (
+		manifest *enaml.DeploymentManifest
+		original []byte
+		dir      string
+	)
+
+	BeforeEach(func() {
+		var err error
+		original, err = ioutil.ReadFile(pcfManifest)
+		Ω(err).ShouldNot(HaveOccurred())
+		manifest = enaml.NewDeploymentManifest(original)
+		original, err = yaml.Marshal(manifest)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		dir, err = ioutil.TempDir("", "omg-transform")
+		Ω(err).ShouldNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	Context("when reading a pipeline", func() {
+		It("reads the steps", func() {
+			path := filepath.Join(dir, "pipeline.yml")
+			Ω(ioutil.WriteFile(path, []byte(`
+steps:
+- transform: scale
+  args: [-instance-group, router, -instances, "3"]
+- transform: add-tags
+  args: [owner=ops]
+`), 0644)).Should(Succeed())
+
+			p, err := ReadPipeline(path)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(p.Steps).Should(Equal([]Step{
+				{Transform: "scale", Args: []string{"-instance-group", "router", "-instances", "3"}},
+				{Transform: "add-tags", Args: []string{"owner=ops"}},
+			}))
+		})
+
+		It("returns an error for steps without a transform", func() {
+			path := filepath.Join(dir, "pipeline.yml")
+			Ω(ioutil.WriteFile(path, []byte("steps:\n- args: [owner=ops]\n"), 0644)).Should(Succeed())
+			_, err := ReadPipeline(path)
+			Ω(err).Should(MatchError(ContainSubstring("step 1 has no transform")))
+		})
+
+		It("reads conditions", func() {
+			path := filepath.Join(dir, "pipeline.yml")
+			Ω(ioutil.WriteFile(path, []byte(`
+steps:
+- transform: clone
+  args: [-instance-group, router, -clone, router_internal]
+  when:
+    instance_group: tcp_router
+    release: {name: cf, version: ">= 239"}
+    property: {query: ".instance_groups[name=router].instances", equals: 1}
+`), 0644)).Should(Succeed())
+
+			p, err := ReadPipeline(path)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(p.Steps[0].When.InstanceGroup).Should(Equal("tcp_router"))
+			Ω(p.Steps[0].When.Release).Should(Equal(&ReleaseCondition{Name: "cf", Version: ">= 239"}))
+			Ω(*p.Steps[0].When.Property.Equals).Should(Equal("1"))
+		})
+
+		It("returns an error for invalid conditions", func() {
+			path := filepath.Join(dir, "pipeline.yml")
+			Ω(ioutil.WriteFile(path, []byte("steps:\n- transform: scale\n  when: {release: {version: '1'}}\n"), 0644)).Should(Succeed())
+			_, err := ReadPipeline(path)
+			Ω(err).Should(MatchError(ContainSubstring("step 1: release condition has no name")))
+		})
+
+		It("reads vars files", func() {
+			path := filepath.Join(dir, "vars.yml")
+			Ω(ioutil.WriteFile(path, []byte("routers: 3\nazs: [z1, z2]\nnetwork: {name: cf}\n"), 0644)).Should(Succeed())
+			vars, err := ReadVars(path)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(vars).Should(Equal(map[string]interface{}{
+				"routers": 3,
+				"azs":     []interface{}{"z1", "z2"},
+				"network": map[string]interface{}{"name": "cf"},
+			}))
+		})
+	})
+
+	Context("when running a pipeline", func() {
+		It("applies the steps in order and logs how to undo them", func() {
+			p := &Pipeline{Steps: []Step{
+				{Transform: "clone", Args: []string{"-instance-group", "router", "-clone", "router2"}},
+				{Transform: "scale", Args: []string{"-instance-group", "router2", "-instances", "3"}},
+			}}
+			log, err := p.Run(manifest, RunOptions{})
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(manifest.GetInstanceGroupByName("router2").Instances).Should(Equal(3))
+
+			Ω(log.Entries).Should(HaveLen(2))
+			Ω(log.Entries[0].Undo).Should(Equal([]Step{{Transform: "remove-instance-group", Args: []string{"-instance-group", "router2"}}}))
+			Ω(log.Entries[1].Undo).Should(Equal([]Step{{Transform: "scale", Args: []string{"-instance-group", "router2", "-instances", "1"}}}))
+		})
+
+		It("reports the step that failed", func() {
+			p := &Pipeline{Steps: []Step{
+				{Transform: "scale", Args: []string{"-instance-group", "router", "-instances", "3"}},
+				{Transform: "scael", Args: []string{"-instance-group", "router", "-instances", "3"}},
+			}}
+			log, err := p.Run(manifest, RunOptions{})
+			Ω(err).Should(MatchError(`step 2 (scael): unknown transform "scael", did you mean scale?`))
+			Ω(log.Entries).Should(HaveLen(1))
+
+			var argErr *errs.ArgumentError
+			Ω(errors.As(err, &argErr)).Should(BeTrue())
+		})
+
+		It("skips steps whose condition doesn't hold", func() {
+			p := &Pipeline{Steps: []Step{
+				{Transform: "clone", Args: []string{"-instance-group", "router", "-clone", "router2"}, When: &Condition{InstanceGroup: "no_such_group"}},
+				{Transform: "scale", Args: []string{"-instance-group", "router", "-instances", "3"}, When: &Condition{Release: &ReleaseCondition{Name: "cf", Version: ">= 239"}}},
+			}}
+			j := &Journal{}
+			log, err := p.Run(manifest, RunOptions{Journal: j})
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(manifest.GetInstanceGroupByName("router2")).Should(BeNil())
+			Ω(manifest.GetInstanceGroupByName("router").Instances).Should(Equal(3))
+
+			Ω(log.Entries).Should(HaveLen(2))
+			Ω(log.Entries[0].Skipped).Should(Equal("instance group no_such_group doesn't exist"))
+			Ω(log.Entries[0].Step.When).Should(BeNil())
+			Ω(log.Entries[1].Skipped).Should(BeEmpty())
+			Ω(j.Entries).Should(HaveLen(1))
+
+			Ω(Undo(manifest, log)).Should(Succeed())
+			Ω(manifest.GetInstanceGroupByName("router").Instances).Should(Equal(1))
+		})
+
+		It("expands the arguments of steps as templates", func() {
+			os.Setenv("OMG_TRANSFORM_TEST_OWNER", "ops")
+			defer os.Unsetenv("OMG_TRANSFORM_TEST_OWNER")
+
+			p := &Pipeline{Steps: []Step{
+				{Transform: "scale", Args: []string{"-instance-group", "router", "-instances", "{{ .routers }}"}},
+				{Transform: "change-az", Args: []string{"-instance-group", "router", "-az", `{{ join .azs "," }}`}},
+				{Transform: "add-tags", Args: []string{`owner={{ env "OMG_TRANSFORM_TEST_OWNER" }}`}},
+			}}
+			log, err := p.Run(manifest, RunOptions{Vars: map[string]interface{}{
+				"routers": 3,
+				"azs":     []interface{}{"z1", "z2"},
+			}})
+			Ω(err).ShouldNot(HaveOccurred())
+			router := manifest.GetInstanceGroupByName("router")
+			Ω(router.Instances).Should(Equal(3))
+			Ω(router.AZs).Should(Equal([]string{"z1", "z2"}))
+			Ω(manifest.Tag("owner")).Should(Equal("ops"))
+			Ω(log.Entries[1].Args).Should(Equal([]string{"-instance-group", "router", "-az", "z1,z2"}))
+		})
+
+		It("returns an error for templates that refer to missing vars", func() {
+			p := &Pipeline{Steps: []Step{
+				{Transform: "scale", Args: []string{"-instance-group", "router", "-instances", "{{ .routers }}"}},
+			}}
+			_, err := p.Run(manifest, RunOptions{})
+			Ω(err).Should(MatchError(ContainSubstring(`step 1 (scale): template: arg 4:1:3: executing "arg 4" at <.routers>`)))
+			var argErr *errs.ArgumentError
+			Ω(errors.As(err, &argErr)).Should(BeTrue())
+		})
+
+		It("fails if the result fails the lint checks", func() {
+			p := &Pipeline{Steps: []Step{
+				{Transform: "scale", Args: []string{"-instance-group", "consul_server", "-instances", "2"}},
+			}}
+			rules := &RuleSet{Rules: []RuleConfig{
+				{Name: "singleton", Disabled: true},
+				{Name: "multi-az", Severity: SeverityWarning},
+			}}
+			log, err := p.Run(manifest, RunOptions{Lint: &LintOptions{Rules: rules}})
+			Ω(err).Should(MatchError(ContainSubstring("the manifest has 1 lint findings:\n  error: odd-quorum: instance group consul_server has 2 instances")))
+			Ω(err.(*ErrLintFailed).ErrorKind()).Should(Equal(errs.KindPrecondition))
+			Ω(log.Entries).Should(HaveLen(1))
+			Ω(log.Findings[0].Rule).Should(Equal("odd-quorum"))
+			Ω(log.Findings[len(log.Findings)-1].Severity).Should(Equal(SeverityWarning))
+
+			_, err = p.Run(manifest, RunOptions{Lint: &LintOptions{Rules: rules, FailOn: "fatal"}})
+			Ω(err).Should(MatchError(`invalid severity "fatal", must be one of info, warning, error`))
+		})
+
+		It("doesn't apply steps that aren't idempotent twice", func() {
+			p := &Pipeline{Steps: []Step{
+				{Transform: "add-errand", Args: []string{"-job", "rotate_certs", "-release", "cf"}},
+			}}
+			log, err := p.Run(manifest, RunOptions{CheckIdempotency: true})
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(log.Entries[0].Idempotent).Should(BeFalse())
+		})
+
+		It("records the steps that can't be undone", func() {
+			p := &Pipeline{Steps: []Step{
+				{Transform: "merge", Args: []string{"-manifest", "fixtures/rotate-certs.yml"}},
+			}}
+			log, err := p.Run(manifest, RunOptions{})
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(log.Entries[0].Irreversible).Should(Equal("merge doesn't support undo"))
+		})
+
+		It("runs clone a second time", func() {
+			p := &Pipeline{Steps: []Step{
+				{Transform: "clone", Args: []string{"-instance-group", "router", "-clone", "router2"}},
+			}}
+			_, err := p.Run(manifest, RunOptions{})
+			Ω(err).ShouldNot(HaveOccurred())
+			log, err := p.Run(manifest, RunOptions{})
+			Ω(err).ShouldNot(HaveOccurred())
+
+			By("not undoing what the first run added")
+			for _, e := range log.Entries {
+				Ω(e.Undo).Should(BeEmpty())
+			}
+		})
+
+		It("runs a pipeline that changes the clone a second time", func() {
+			p := &Pipeline{Steps: []Step{
+				{Transform: "clone", Args: []string{"-instance-group", "router", "-clone", "router2"}},
+				{Transform: "scale", Args: []string{"-instance-group", "router2", "-instances", "3"}},
+				{Transform: "add-vm-extension", Args: []string{"-instance-group", "router2", "-name", "public-lbs"}},
+				{Transform: "change-network", Args: []string{"-instance-group", "router2", "-network", "public", "-static-ips", "10.0.16.10"}},
+			}}
+			_, err := p.Run(manifest, RunOptions{})
+			Ω(err).ShouldNot(HaveOccurred())
+			_, err = p.Run(manifest, RunOptions{})
+			Ω(err).ShouldNot(HaveOccurred())
+
+			clone := manifest.GetInstanceGroupByName("router2")
+			Ω(clone.Instances).Should(Equal(3))
+			Ω(clone.VMExtensions).Should(ConsistOf("public-lbs"))
+		})
+
+		It("refuses to clone over a group whose jobs have changed since", func() {
+			p := &Pipeline{Steps: []Step{
+				{Transform: "clone", Args: []string{"-instance-group", "router", "-clone", "router2"}},
+			}}
+			_, err := p.Run(manifest, RunOptions{})
+			Ω(err).ShouldNot(HaveOccurred())
+			manifest.GetInstanceGroupByName("router2").Jobs[0].Release = "other"
+			_, err = p.Run(manifest, RunOptions{})
+			Ω(err).Should(MatchError("step 1 (clone): instance group router2 already exists"))
+			Ω(err.(*StepError).Err).Should(BeAssignableToTypeOf(&errs.PreconditionError{}))
+		})
+
+		It("still refuses to clone into a different instance group", func() {
+			p := &Pipeline{Steps: []Step{
+				{Transform: "clone", Args: []string{"-instance-group", "router", "-clone", "nats"}},
+			}}
+			_, err := p.Run(manifest, RunOptions{})
+			Ω(err).Should(MatchError("step 1 (clone): instance group nats already exists"))
+		})
+
+		table.DescribeTable("checks that idempotent steps are idempotent",
+			func(transform string, args ...string) {
+				p := &Pipeline{Steps: []Step{{Transform: transform, Args: args}}}
+				log, err := p.Run(manifest, RunOptions{CheckIdempotency: true})
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(log.Entries[0].Idempotent).Should(BeTrue())
+			},
+			table.Entry("scale", "scale", "-instance-group", "router", "-instances", "3"),
+			table.Entry("clone", "clone", "-instance-group", "router", "-clone", "router2"),
+			table.Entry("change-az", "change-az", "-instance-group", "diego_cell", "-az", "us-west-1b,us-west-1c", "-rebalance"),
+			table.Entry("change-az with a cloud config", "change-az", "-instance-group", "router", "-az", "us-west-1b,us-west-1c", "-rebalance", "-cloud-config", "fixtures/cloud-config-aws.yml"),
+			table.Entry("change-network", "change-network", "-instance-group", "router", "-network", "public", "-static-ips", "10.0.16.10"),
+			table.Entry("make-ha", "make-ha", "-az", "us-west-1b,us-west-1c", "-cloud-config", "fixtures/cloud-config-aws.yml"),
+			table.Entry("add-tags", "add-tags", "-instance-group", "router", "owner=ops"),
+			table.Entry("remove-tags", "remove-tags", "owner"),
+			table.Entry("add-vm-extension", "add-vm-extension", "-instance-group", "nats", "-name", "test,public-lbs"),
+			table.Entry("remove-vm-extension", "remove-vm-extension", "-instance-group", "nats", "-name", "test"),
+			table.Entry("change-lifecycle", "change-lifecycle", "-instance-group", "router", "-lifecycle", "errand"),
+			table.Entry("merge", "merge", "-manifest", "fixtures/rotate-certs.yml"),
+		)
+
+		It("fails when a step isn't idempotent", func() {
+			saved := transformations
+			defer func() { transformations = saved }()
+			RegisterTransformation(TransformationInfo{
+				Info: registry.Info{
+					Name: "append-tag",
+				},
+				Builder: func([]string) (Transformation, error) { return appendTag{}, nil },
+			})
+
+			p := &Pipeline{Steps: []Step{{Transform: "append-tag"}}}
+			_, err := p.Run(manifest, RunOptions{CheckIdempotency: true})
+			Ω(err).Should(HaveOccurred())
+
+			var notIdempotent *ErrNotIdempotent
+			Ω(errors.As(err, &notIdempotent)).Should(BeTrue())
+			Ω(notIdempotent.Changes).Should(Equal([]string{`~ tags.runs: "x" -> "xx"`}))
+		})
+	})
+
+	Context("when undoing a pipeline", func() {
+		table.DescribeTable("restores the original manifest",
+			func(transform string, args ...string) {
+				args = append([]string(nil), args...)
+				for i := range args {
+					if args[i] == "$DIR" {
+						args[i] = filepath.Join(dir, "split.yml")
+					}
+				}
+				p := &Pipeline{Steps: []Step{{Transform: transform, Args: args}}}
+				log, err := p.Run(manifest, RunOptions{})
+				Ω(err).ShouldNot(HaveOccurred())
+
+				By("writing and reading the log")
+				path := filepath.Join(dir, "transforms.log")
+				Ω(log.WriteFile(path)).Should(Succeed())
+				log, err = ReadTransformLog(path)
+				Ω(err).ShouldNot(HaveOccurred())
+
+				Ω(Undo(manifest, log)).Should(Succeed())
+				undone, err := yaml.Marshal(manifest)
+				Ω(err).ShouldNot(HaveOccurred())
+				changes, err := golden.Diff(original, undone)
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(changes).Should(BeEmpty())
+			},
+			table.Entry("scale", "scale", "-instance-group", "router", "-instances", "3"),
+			table.Entry("clone", "clone", "-instance-group", "router", "-clone", "router2"),
+			table.Entry("change-az", "change-az", "-instance-group", "router", "-az", "az1,az2"),
+			table.Entry("change-az with rebalancing", "change-az", "-instance-group", "diego_cell", "-az", "us-west-1b,us-west-1c", "-rebalance"),
+			table.Entry("change-az with static IPs", "change-az", "-instance-group", "router", "-az", "us-west-1c", "-cloud-config", "fixtures/cloud-config-aws.yml"),
+			table.Entry("make-ha", "make-ha", "-az", "us-west-1b,us-west-1c", "-cloud-config", "fixtures/cloud-config-aws.yml"),
+			table.Entry("change-network", "change-network", "-instance-group", "mysql_proxy", "-network", "public"),
+			table.Entry("change-network with static IPs", "change-network", "-instance-group", "mysql_proxy", "-network", "public", "-static-ips", "10.0.16.10"),
+			table.Entry("add-tags", "add-tags", "owner=ops"),
+			table.Entry("add-tags to an instance group", "add-tags", "-instance-group", "router", "owner=ops"),
+			table.Entry("add-vm-extension", "add-vm-extension", "-instance-group", "nats", "-name", "test,public-lbs"),
+			table.Entry("remove-vm-extension", "remove-vm-extension", "-instance-group", "nats", "-name", "test"),
+			table.Entry("change-lifecycle", "change-lifecycle", "-instance-group", "router", "-lifecycle", "errand"),
+			table.Entry("add-errand", "add-errand", "-job", "rotate_certs", "-release", "cf"),
+			table.Entry("split", "split", "-instance-group", "router,tcp_router", "-deployment", "routing", "-output", "$DIR"),
+		)
+
+		It("undoes the steps in reverse order", func() {
+			p := &Pipeline{Steps: []Step{
+				{Transform: "add-tags", Args: []string{"owner=ops"}},
+				{Transform: "add-tags", Args: []string{"owner=dev"}},
+				{Transform: "remove-tags", Args: []string{"owner"}},
+			}}
+			log, err := p.Run(manifest, RunOptions{})
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(Undo(manifest, log)).Should(Succeed())
+			Ω(manifest.Tags).Should(BeEmpty())
+		})
+
+		It("leaves the manifest untouched if a step fails to undo", func() {
+			log := &TransformLog{Entries: []LogEntry{
+				{
+					Step: Step{Transform: "clone", Args: []string{"-instance-group", "router", "-clone", "gone"}},
+					Undo: []Step{{Transform: "remove-instance-group", Args: []string{"-instance-group", "gone"}}},
+				},
+				{
+					Step: Step{Transform: "scale", Args: []string{"-instance-group", "router", "-instances", "3"}},
+					Undo: []Step{{Transform: "scale", Args: []string{"-instance-group", "router", "-instances", "1"}}},
+				},
+			}}
+			manifest.GetInstanceGroupByName("router").Instances = 3
+
+			err := Undo(manifest, log)
+			Ω(err).Should(MatchError(HavePrefix("step 1 (clone): undo remove-instance-group")))
+			Ω(manifest.GetInstanceGroupByName("router").Instances).Should(Equal(3))
+		})
+
+		It("refuses to undo steps that can't be undone", func() {
+			p := &Pipeline{Steps: []Step{
+				{Transform: "merge", Args: []string{"-manifest", "fixtures/rotate-certs.yml"}},
+				{Transform: "scale", Args: []string{"-instance-group", "router", "-instances", "3"}},
+			}}
+			log, err := p.Run(manifest, RunOptions{})
+			Ω(err).ShouldNot(HaveOccurred())
+
+			err = Undo(manifest, log)
+			Ω(err).Should(MatchError("step 1 (merge): can't be undone: merge doesn't support undo"))
+			Ω(manifest.GetInstanceGroupByName("router").Instances).Should(Equal(3))
+		})
+	})
+})
@@ -0,0 +1,285 @@
+package manifest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/enaml-ops/enaml"
+	"github.com/enaml-ops/omg-transform/errs"
+	"github.com/enaml-ops/omg-transform/registry"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// PluginPrefix is the prefix of the names of plugin executables.  A
+// plugin named omg-transform-<name> provides the <name> transformation.
+const PluginPrefix = "omg-transform-"
+
+// PluginProtocolVersion is the version of the JSON protocol spoken with
+// plugins.  Plugins must reply with the version they were sent.
+const PluginProtocolVersion = 1
+
+// PluginPathEnv is the environment variable listing the directories
+// searched for plugins before PATH.
+const PluginPathEnv = "OMG_TRANSFORM_PLUGIN_PATH"
+
+// The time a plugin is given to describe itself, and to apply its
+// transformation, before it is killed.
+var (
+	PluginDescribeTimeout = 5 * time.Second
+	PluginApplyTimeout    = 5 * time.Minute
+)
+
+// pluginDirs are the directories searched for the plugins that
+// LookupTransformation finds by name, set by UsePlugins.
+var pluginDirs []string
+
+// reservedPluginNames are the names of the other omg-transform
+// executables, and of the release binaries, which aren't plugins.
+var reservedPluginNames = []string{"cloudconfig", "runtimeconfig", "osx", "linux"}
+
+// PluginRequest is written to a plugin's stdin.  The describe command
+// asks the plugin to describe itself, the apply command to apply the
+// transformation to Manifest with Args.
+type PluginRequest struct {
+	Protocol int      `json:"protocol"`
+	Command  string   `json:"command"`
+	Args     []string `json:"args,omitempty"`
+	Manifest string   `json:"manifest,omitempty"` // the manifest, as YAML
+}
+
+// PluginResponse is read from a plugin's stdout.  Description, Usage and
+// Examples answer the describe command, Manifest the apply command.
+// Error is set if the command failed.
+type PluginResponse struct {
+	Protocol    int          `json:"protocol"`
+	Description string       `json:"description,omitempty"`
+	Usage       string       `json:"usage,omitempty"`
+	Examples    []string     `json:"examples,omitempty"`
+	Manifest    string       `json:"manifest,omitempty"`
+	Error       *PluginError `json:"error,omitempty"`
+}
+
+// PluginError is an error reported by a plugin, or an error running
+// one.  Kind is one of the error kinds, and defaults to errs.KindFailure.
+type PluginError struct {
+	Plugin  string `json:"plugin"`
+	Message string `json:"message"`
+	Kind    string `json:"kind,omitempty"`
+}
+
+func (e *PluginError) Error() string { return e.Message }
+
+func (e *PluginError) ErrorKind() string {
+	switch e.Kind {
+	case errs.KindNotFound, errs.KindArgument, errs.KindPrecondition:
+		return e.Kind
+	}
+	return errs.KindFailure
+}
+
+// Plugin is an executable that provides a transformation.
+type Plugin struct {
+	Name string // the transformation's name
+	Path string
+}
+
+// PluginDirs returns the directories searched for plugins: those in
+// $OMG_TRANSFORM_PLUGIN_PATH followed by those in $PATH.
+func PluginDirs() []string {
+	var dirs []string
+	for _, env := range []string{PluginPathEnv, "PATH"} {
+		for _, dir := range filepath.SplitList(os.Getenv(env)) {
+			if dir != "" {
+				dirs = append(dirs, dir)
+			}
+		}
+	}
+	return dirs
+}
+
+// FindPlugins returns the plugins in dirs, sorted by name.  If several
+// directories have a plugin with the same name, the first one wins.
+func FindPlugins(dirs []string) []Plugin {
+	found := map[string]string{}
+	for _, dir := range dirs {
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, f := range files {
+			name := strings.TrimPrefix(f.Name(), PluginPrefix)
+			if name == f.Name() || name == "" || isReservedPluginName(name) {
+				continue
+			}
+			if f.IsDir() || f.Mode()&0111 == 0 {
+				continue
+			}
+			if _, ok := found[name]; !ok {
+				found[name] = filepath.Join(dir, f.Name())
+			}
+		}
+	}
+
+	var plugins []Plugin
+	for name, path := range found {
+		plugins = append(plugins, Plugin{Name: name, Path: path})
+	}
+	sort.Slice(plugins, func(i, j int) bool { return plugins[i].Name < plugins[j].Name })
+	return plugins
+}
+
+func isReservedPluginName(name string) bool {
+	for _, r := range reservedPluginNames {
+		if name == r || strings.HasPrefix(name, r+"-") {
+			return true
+		}
+	}
+	return false
+}
+
+// RegisterPlugins registers the plugins in dirs as transformations,
+// asking each of them to describe itself, so that they can be listed.
+// Plugins that have the same name as a registered transformation are
+// ignored.  A plugin that can't describe itself is still registered,
+// and the failure is shown in its description.
+func RegisterPlugins(dirs []string) {
+	for _, p := range FindPlugins(dirs) {
+		if _, ok := transformations.Lookup(p.Name); ok {
+			continue
+		}
+		RegisterTransformation(p.info(true))
+	}
+}
+
+// UsePlugins makes LookupTransformation find the plugins in dirs by
+// name when no transformation with that name is registered.  Plugins
+// found this way aren't run until they are applied, so they have no
+// description.
+func UsePlugins(dirs []string) {
+	pluginDirs = dirs
+}
+
+// lookupPlugin registers the plugin with the specified name in the
+// directories given to UsePlugins.
+func lookupPlugin(name string) (TransformationInfo, bool) {
+	if name == "" || strings.ContainsAny(name, `/\`) || isReservedPluginName(name) {
+		return TransformationInfo{}, false
+	}
+	for _, dir := range pluginDirs {
+		path := filepath.Join(dir, PluginPrefix+name)
+		if f, err := os.Stat(path); err != nil || f.IsDir() || f.Mode()&0111 == 0 {
+			continue
+		}
+		info := Plugin{Name: name, Path: path}.info(false)
+		RegisterTransformation(info)
+		return info, true
+	}
+	return TransformationInfo{}, false
+}
+
+func (p Plugin) info(describe bool) TransformationInfo {
+	info := TransformationInfo{
+		Info: registry.Info{
+			Name: p.Name,
+		},
+		Builder: func(args []string) (Transformation, error) {
+			return &PluginTransformation{Plugin: p, Args: args}, nil
+		},
+	}
+	if !describe {
+		return info
+	}
+	resp, err := p.call(PluginRequest{Command: "describe"}, PluginDescribeTimeout)
+	if err != nil {
+		info.Description = fmt.Sprintf("plugin %s, which failed to describe itself: %v", p.Path, err)
+		return info
+	}
+	info.Description = resp.Description
+	info.Usage = resp.Usage
+	info.Examples = resp.Examples
+	return info
+}
+
+// PluginTransformation applies a plugin to a manifest.
+type PluginTransformation struct {
+	Plugin Plugin
+	Args   []string
+}
+
+func (t *PluginTransformation) Apply(dm *enaml.DeploymentManifest) error {
+	b, err := yaml.Marshal(dm)
+	if err != nil {
+		return err
+	}
+	resp, err := t.Plugin.call(PluginRequest{Command: "apply", Args: t.Args, Manifest: string(b)}, PluginApplyTimeout)
+	if err != nil {
+		return err
+	}
+	out := enaml.NewDeploymentManifest([]byte(resp.Manifest))
+	if out == nil || resp.Manifest == "" {
+		return t.Plugin.errorf("plugin %s returned an invalid manifest", t.Plugin.Name)
+	}
+	*dm = *out
+	return nil
+}
+
+// call sends a request to the plugin and reads its response.  The
+// plugin is killed if it doesn't exit within timeout.
+func (p Plugin) call(req PluginRequest, timeout time.Duration) (*PluginResponse, error) {
+	req.Protocol = PluginProtocolVersion
+	in, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, p.Path)
+	cmd.Stdin = bytes.NewReader(in)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	runErr := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return nil, p.errorf("plugin %s didn't %s within %v", p.Name, req.Command, timeout)
+	}
+
+	resp := &PluginResponse{}
+	if err = json.Unmarshal(stdout.Bytes(), resp); err != nil {
+		if runErr != nil {
+			return nil, p.errorf("plugin %s failed: %v%s", p.Name, runErr, stderrSuffix(stderr.Bytes()))
+		}
+		return nil, p.errorf("plugin %s wrote an invalid response: %v", p.Name, err)
+	}
+	if resp.Protocol != PluginProtocolVersion {
+		return nil, p.errorf("plugin %s speaks protocol version %d, omg-transform speaks version %d", p.Name, resp.Protocol, PluginProtocolVersion)
+	}
+	if resp.Error != nil {
+		resp.Error.Plugin = p.Name
+		return nil, resp.Error
+	}
+	if runErr != nil {
+		return nil, p.errorf("plugin %s failed: %v%s", p.Name, runErr, stderrSuffix(stderr.Bytes()))
+	}
+	return resp, nil
+}
+
+func (p Plugin) errorf(format string, args ...interface{}) error {
+	return &PluginError{Plugin: p.Name, Message: fmt.Sprintf(format, args...)}
+}
+
+func stderrSuffix(stderr []byte) string {
+	if s := strings.TrimSpace(string(stderr)); s != "" {
+		return ": " + s
+	}
+	return ""
+}
@@ -0,0 +1,164 @@
+package manifest
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/enaml-ops/enaml"
+	"github.com/enaml-ops/omg-transform/errs"
+	"github.com/enaml-ops/omg-transform/registry"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// renamePlugin describes itself, and renames the deployment to its
+// argument if it is passed "prod".
+const renamePlugin = `#!/bin/sh
+req=$(cat)
+case "$req" in
+*'"command":"describe"'*)
+	echo '{"protocol":1,"description":"rename the deployment","usage":"<name>","examples":["rename prod"]}' ;;
+*'"args":["prod"]'*'"manifest":"name: rotate-certs'*)
+	printf '%s\n' '{"protocol":1,"manifest":"name: prod\nreleases:\n- name: cf\n  version: \"1\"\n"}' ;;
+*)
+	echo '{"protocol":1,"error":{"message":"unexpected request","kind":"argument"}}'
+	exit 1 ;;
+esac
+`
+
+var _ = Describe("plugins", func() {
+	var (
+		dir, other string
+		saved      registry.Registry
+		dm         *enaml.DeploymentManifest
+	)
+
+	writePlugin := func(dir, name, script string) string {
+		path := filepath.Join(dir, PluginPrefix+name)
+		Ω(ioutil.WriteFile(path, []byte(script), 0755)).Should(Succeed())
+		return path
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "omg-transform")
+		Ω(err).ShouldNot(HaveOccurred())
+		other, err = ioutil.TempDir("", "omg-transform")
+		Ω(err).ShouldNot(HaveOccurred())
+
+		saved = transformations
+
+		b, err := ioutil.ReadFile("fixtures/rotate-certs.yml")
+		Ω(err).ShouldNot(HaveOccurred())
+		dm = enaml.NewDeploymentManifest(b)
+	})
+
+	AfterEach(func() {
+		transformations = saved
+		pluginDirs = nil
+		os.RemoveAll(dir)
+		os.RemoveAll(other)
+	})
+
+	It("finds executables named omg-transform-<name>", func() {
+		rename := writePlugin(dir, "rename", renamePlugin)
+		writePlugin(other, "rename", "#!/bin/sh\nexit 1\n")
+		other := writePlugin(other, "other", renamePlugin)
+		writePlugin(dir, "cloudconfig", renamePlugin)
+		writePlugin(dir, "runtimeconfig-linux", renamePlugin)
+		Ω(ioutil.WriteFile(filepath.Join(dir, PluginPrefix+"readme"), nil, 0644)).Should(Succeed())
+		Ω(os.Mkdir(filepath.Join(dir, PluginPrefix+"dir"), 0755)).Should(Succeed())
+
+		Ω(FindPlugins([]string{dir, "/does/not/exist", filepath.Dir(other)})).Should(Equal([]Plugin{
+			{Name: "other", Path: other},
+			{Name: "rename", Path: rename},
+		}))
+	})
+
+	It("registers plugins as transformations", func() {
+		writePlugin(dir, "rename", renamePlugin)
+		writePlugin(dir, "scale", renamePlugin)
+		RegisterPlugins([]string{dir})
+
+		info, ok := LookupTransformation("rename")
+		Ω(ok).Should(BeTrue())
+		Ω(info.Description).Should(Equal("rename the deployment"))
+		Ω(info.Usage).Should(Equal("<name>"))
+		Ω(info.Examples).Should(Equal([]string{"rename prod"}))
+
+		info, _ = LookupTransformation("scale")
+		Ω(info.Description).ShouldNot(Equal("rename the deployment"))
+
+		t, err := Step{Transform: "rename", Args: []string{"prod"}}.Build()
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(t.Apply(dm)).Should(Succeed())
+		Ω(dm.Name).Should(Equal("prod"))
+		Ω(dm.InstanceGroups).Should(BeEmpty())
+	})
+
+	It("finds plugins by name without running them", func() {
+		writePlugin(dir, "rename", "#!/bin/sh\nexit 1\n")
+		writePlugin(other, "rename", renamePlugin)
+		writePlugin(other, "cloudconfig", renamePlugin)
+		UsePlugins([]string{dir, other})
+
+		info, ok := LookupTransformation("rename")
+		Ω(ok).Should(BeTrue())
+		Ω(info.Description).Should(BeEmpty())
+		t, err := info.Builder(nil)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(t.(*PluginTransformation).Plugin.Path).Should(Equal(filepath.Join(dir, PluginPrefix+"rename")))
+
+		for _, name := range []string{"missing", "cloudconfig", "../" + filepath.Base(other) + "/omg-transform-rename"} {
+			_, ok = LookupTransformation(name)
+			Ω(ok).Should(BeFalse(), name)
+		}
+	})
+
+	It("kills plugins that take too long", func() {
+		defer func(d time.Duration) { PluginDescribeTimeout = d }(PluginDescribeTimeout)
+		PluginDescribeTimeout = 100 * time.Millisecond
+		writePlugin(dir, "slow", "#!/bin/sh\nexec sleep 10\n")
+
+		start := time.Now()
+		RegisterPlugins([]string{dir})
+		Ω(time.Since(start)).Should(BeNumerically("<", 5*time.Second))
+		info, ok := LookupTransformation("slow")
+		Ω(ok).Should(BeTrue())
+		Ω(info.Description).Should(ContainSubstring("plugin slow didn't describe within 100ms"))
+	})
+
+	It("returns the errors reported by plugins with their kind", func() {
+		path := writePlugin(dir, "rename", renamePlugin)
+		t := &PluginTransformation{Plugin: Plugin{Name: "rename", Path: path}, Args: []string{"staging"}}
+		err := t.Apply(dm)
+		Ω(err).Should(MatchError("unexpected request"))
+		Ω(err.(*PluginError).Plugin).Should(Equal("rename"))
+		Ω(err.(*PluginError).ErrorKind()).Should(Equal(errs.KindArgument))
+		Ω(dm.Name).Should(Equal("rotate-certs"))
+	})
+
+	It("returns an error with the plugin's stderr if it fails", func() {
+		path := writePlugin(dir, "crash", "#!/bin/sh\necho oops >&2\nexit 3\n")
+		t := &PluginTransformation{Plugin: Plugin{Name: "crash", Path: path}}
+		err := t.Apply(dm)
+		Ω(err).Should(MatchError("plugin crash failed: exit status 3: oops"))
+		Ω(err.(*PluginError).ErrorKind()).Should(Equal(errs.KindFailure))
+	})
+
+	It("refuses plugins that speak another protocol version", func() {
+		path := writePlugin(dir, "future", "#!/bin/sh\necho '{\"protocol\":2}'\n")
+		t := &PluginTransformation{Plugin: Plugin{Name: "future", Path: path}}
+		Ω(t.Apply(dm)).Should(MatchError("plugin future speaks protocol version 2, omg-transform speaks version 1"))
+	})
+
+	It("registers plugins that fail to describe themselves", func() {
+		writePlugin(dir, "broken", "#!/bin/sh\necho nope\n")
+		RegisterPlugins([]string{dir})
+		info, ok := LookupTransformation("broken")
+		Ω(ok).Should(BeTrue())
+		Ω(info.Description).Should(ContainSubstring("failed to describe itself: plugin broken wrote an invalid response"))
+	})
+})
@@ -0,0 +1,248 @@
+package manifest
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/enaml-ops/enaml"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Query returns the values in a deployment manifest that match a
+// JSONPath-like expression.  Keys are the YAML keys of the manifest.
+//
+//	$.name                                    the deployment name
+//	.instance_groups[0].name                  the first instance group's name
+//	.instance_groups[*].name                  every instance group's name
+//	.instance_groups[name=router].azs         the router's AZs
+//	.instance_groups[?(@.lifecycle=="errand")].name
+//	..static_ips                              static IPs anywhere in the manifest
+//
+// The leading '$' is optional.  Filters compare the string form of a
+// key with '=' (or '==') and '!='.  Maps are returned with string keys,
+// so that the results can be encoded as JSON.
+func Query(dm *enaml.DeploymentManifest, expr string) ([]interface{}, error) {
+	steps, err := parseQuery(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := yaml.Marshal(dm)
+	if err != nil {
+		return nil, err
+	}
+	var root interface{}
+	if err = yaml.Unmarshal(b, &root); err != nil {
+		return nil, err
+	}
+
+	values := []interface{}{normalize(root)}
+	for _, s := range steps {
+		var next []interface{}
+		for _, v := range values {
+			next = s.apply(v, next)
+		}
+		values = next
+	}
+	return values, nil
+}
+
+type queryStep struct {
+	key       string // a map key, or "*" for every element
+	index     int    // an index into a list, if isIndex is set
+	isIndex   bool
+	recursive bool // match key at any depth
+
+	// a filter on the elements of a list
+	filterKey   string
+	filterValue string
+	filterNot   bool
+	isFilter    bool
+}
+
+func (s queryStep) apply(v interface{}, result []interface{}) []interface{} {
+	if s.recursive {
+		return descend(v, s.key, result)
+	}
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if s.key == "*" {
+			for _, k := range sortedMapKeys(v) {
+				result = append(result, v[k])
+			}
+		} else if s.key != "" {
+			if child, ok := v[s.key]; ok {
+				result = append(result, child)
+			}
+		}
+	case []interface{}:
+		switch {
+		case s.isIndex:
+			i := s.index
+			if i < 0 {
+				i += len(v)
+			}
+			if i >= 0 && i < len(v) {
+				result = append(result, v[i])
+			}
+		case s.isFilter:
+			for _, e := range v {
+				m, ok := e.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				value, ok := m[s.filterKey]
+				match := ok && fmt.Sprint(value) == s.filterValue
+				if match != s.filterNot {
+					result = append(result, e)
+				}
+			}
+		case s.key == "*":
+			result = append(result, v...)
+		}
+	}
+	return result
+}
+
+// descend appends every value stored under key at any depth of v.
+func descend(v interface{}, key string, result []interface{}) []interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for _, k := range sortedMapKeys(v) {
+			if k == key || key == "*" {
+				result = append(result, v[k])
+			}
+			result = descend(v[k], key, result)
+		}
+	case []interface{}:
+		for _, e := range v {
+			result = descend(e, key, result)
+		}
+	}
+	return result
+}
+
+// parseQuery parses a query expression into steps.
+func parseQuery(expr string) ([]queryStep, error) {
+	s := strings.TrimSpace(expr)
+	s = strings.TrimPrefix(s, "$")
+	if s == "" {
+		return nil, nil
+	}
+
+	var steps []queryStep
+	for len(s) > 0 {
+		switch {
+		case strings.HasPrefix(s, ".."):
+			name, rest := queryName(s[2:])
+			if name == "" {
+				return nil, fmt.Errorf("invalid query %q: expected a key after '..'", expr)
+			}
+			steps = append(steps, queryStep{key: name, recursive: true})
+			s = rest
+		case s[0] == '.':
+			name, rest := queryName(s[1:])
+			if name == "" {
+				return nil, fmt.Errorf("invalid query %q: expected a key after '.'", expr)
+			}
+			steps = append(steps, queryStep{key: name})
+			s = rest
+		case s[0] == '[':
+			end := strings.Index(s, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("invalid query %q: missing ']'", expr)
+			}
+			step, err := parseSubscript(s[1:end])
+			if err != nil {
+				return nil, fmt.Errorf("invalid query %q: %v", expr, err)
+			}
+			steps = append(steps, step)
+			s = s[end+1:]
+		default:
+			if len(steps) > 0 {
+				return nil, fmt.Errorf("invalid query %q: unexpected %q", expr, s)
+			}
+			// allow the leading '.' to be omitted
+			s = "." + s
+		}
+	}
+	return steps, nil
+}
+
+// queryName returns the key at the start of s and the rest of s.
+func queryName(s string) (string, string) {
+	i := strings.IndexAny(s, ".[")
+	if i < 0 {
+		return s, ""
+	}
+	return s[:i], s[i:]
+}
+
+// parseSubscript parses the contents of a [...] subscript.
+func parseSubscript(sub string) (queryStep, error) {
+	sub = strings.TrimSpace(sub)
+	if sub == "*" {
+		return queryStep{key: "*"}, nil
+	}
+	if i, err := strconv.Atoi(sub); err == nil {
+		return queryStep{index: i, isIndex: true}, nil
+	}
+	if len(sub) > 1 && (sub[0] == '\'' || sub[0] == '"') && sub[len(sub)-1] == sub[0] {
+		return queryStep{key: sub[1 : len(sub)-1]}, nil
+	}
+
+	// filters may be written as [key=value] or [?(@.key=="value")]
+	if strings.HasPrefix(sub, "?(") && strings.HasSuffix(sub, ")") {
+		sub = strings.TrimPrefix(sub[2:len(sub)-1], "@.")
+	}
+	step := queryStep{isFilter: true}
+	var parts []string
+	switch {
+	case strings.Contains(sub, "!="):
+		parts = strings.SplitN(sub, "!=", 2)
+		step.filterNot = true
+	case strings.Contains(sub, "=="):
+		parts = strings.SplitN(sub, "==", 2)
+	case strings.Contains(sub, "="):
+		parts = strings.SplitN(sub, "=", 2)
+	default:
+		return step, fmt.Errorf("invalid subscript [%s]", sub)
+	}
+	step.filterKey = strings.TrimSpace(parts[0])
+	step.filterValue = strings.Trim(strings.TrimSpace(parts[1]), `"'`)
+	if step.filterKey == "" {
+		return step, fmt.Errorf("invalid filter [%s]", sub)
+	}
+	return step, nil
+}
+
+// normalize converts the maps produced by the yaml package into maps
+// with string keys.
+func normalize(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[fmt.Sprint(k)] = normalize(e)
+		}
+		return m
+	case []interface{}:
+		for i := range v {
+			v[i] = normalize(v[i])
+		}
+		return v
+	}
+	return v
+}
+
+func sortedMapKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
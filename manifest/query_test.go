@@ -0,0 +1,92 @@
+package manifest
+
+import (
+	"os"
+
+	"github.com/enaml-ops/enaml"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Query", func() {
+	var manifest *enaml.DeploymentManifest
+
+	BeforeEach(func() {
+		f, err := os.Open("fixtures/pcf-aws-1.8.00-build.373.yml")
+		Ω(err).ShouldNot(HaveOccurred())
+		defer f.Close()
+		manifest = enaml.NewDeploymentManifestFromFile(f)
+	})
+
+	It("returns a top level key", func() {
+		values, err := Query(manifest, "$.name")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(values).Should(Equal([]interface{}{"cf-d05e4cdd400adc2c32ce"}))
+
+		values, err = Query(manifest, "director_uuid")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(values).Should(Equal([]interface{}{"ignore"}))
+	})
+
+	It("indexes lists", func() {
+		values, err := Query(manifest, ".instance_groups[0].name")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(values).Should(Equal([]interface{}{"consul_server"}))
+
+		values, err = Query(manifest, ".instance_groups[-1].name")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(values).Should(Equal([]interface{}{"push-pivotal-account"}))
+	})
+
+	It("returns every element with a wildcard", func() {
+		values, err := Query(manifest, ".instance_groups[*].name")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(values).Should(HaveLen(len(manifest.InstanceGroups)))
+		Ω(values).Should(ContainElement("diego_cell"))
+	})
+
+	It("filters lists", func() {
+		values, err := Query(manifest, ".instance_groups[name=nats].networks[0].static_ips")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(values).Should(Equal([]interface{}{[]interface{}{"10.0.0.8"}}))
+
+		values, err = Query(manifest, `.instance_groups[?(@.name=="nats")].instances`)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(values).Should(Equal([]interface{}{1}))
+
+		errands, err := Query(manifest, `.instance_groups[lifecycle=errand].name`)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(errands).Should(ContainElement("smoke-tests"))
+		Ω(errands).ShouldNot(ContainElement("nats"))
+
+		services, err := Query(manifest, `.instance_groups[lifecycle!=errand].name`)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(len(errands) + len(services)).Should(Equal(len(manifest.InstanceGroups)))
+	})
+
+	It("finds keys at any depth", func() {
+		values, err := Query(manifest, "..static_ips")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(values).Should(ContainElement([]interface{}{"10.0.0.8"}))
+	})
+
+	It("returns maps with string keys", func() {
+		values, err := Query(manifest, ".stemcells[0]")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(values).Should(HaveLen(1))
+		Ω(values[0]).Should(HaveKeyWithValue("os", "ubuntu-trusty"))
+	})
+
+	It("returns nothing when there is no match", func() {
+		values, err := Query(manifest, ".instance_groups[name=missing].name")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(values).Should(BeEmpty())
+	})
+
+	It("returns an error for invalid expressions", func() {
+		for _, expr := range []string{".instance_groups[0", ".instance_groups[bogus]", "name.", "a..", ".[x=]"} {
+			_, err := Query(manifest, expr)
+			Ω(err).Should(HaveOccurred(), expr)
+		}
+	})
+})
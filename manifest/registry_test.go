@@ -0,0 +1,16 @@
+package manifest
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("transformation registry", func() {
+	It("looks up builders of manifest transformations", func() {
+		info, ok := LookupTransformation("scale")
+		Ω(ok).Should(BeTrue())
+		t, err := info.Builder([]string{"-instance-group", "router", "-instances", "3"})
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(t).Should(Equal(&ScaleInstance{InstanceGroup: "router", Scale: 3}))
+	})
+})
@@ -0,0 +1,59 @@
+package manifest
+
+import (
+	"flag"
+
+	"github.com/enaml-ops/enaml"
+	"github.com/enaml-ops/omg-transform/errs"
+	"github.com/enaml-ops/omg-transform/registry"
+)
+
+// InstanceGroupRemover is a transformation that removes instance groups
+// from a deployment.
+type InstanceGroupRemover struct {
+	InstanceGroups []string
+	igsFlag        string
+}
+
+func (r *InstanceGroupRemover) Apply(dm *enaml.DeploymentManifest) error {
+	for _, name := range r.InstanceGroups {
+		if dm.GetInstanceGroupByName(name) == nil {
+			return instanceGroupNotFound(dm, name)
+		}
+	}
+
+	var remaining []*enaml.InstanceGroup
+	for _, ig := range dm.InstanceGroups {
+		if !contains(r.InstanceGroups, ig.Name) {
+			remaining = append(remaining, ig)
+		}
+	}
+	dm.InstanceGroups = remaining
+	return nil
+}
+
+func (r *InstanceGroupRemover) flagSet() *flag.FlagSet {
+	fs := registry.NewFlagSet("remove-instance-group")
+	fs.StringVar(&r.igsFlag, "instance-group", "", "comma-separated list of instance groups to remove")
+	return fs
+}
+
+// RemoveInstanceGroupTransformation is a TransformationBuilder that builds
+// the 'remove-instance-group' transformation.
+func RemoveInstanceGroupTransformation(args []string) (Transformation, error) {
+	r := &InstanceGroupRemover{}
+	fs := r.flagSet()
+	err := fs.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.igsFlag == "" {
+		return nil, errs.MissingFlag("instance-group")
+	}
+	r.InstanceGroups = registry.Split(r.igsFlag, ",")
+	if len(r.InstanceGroups) == 0 {
+		return nil, errs.InvalidFlag("instance-group", "invalid format for instance groups, must be comma-separated")
+	}
+	return r, nil
+}
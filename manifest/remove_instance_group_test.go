@@ -0,0 +1,54 @@
+package manifest
+
+import (
+	"os"
+
+	"github.com/enaml-ops/enaml"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("remove instance group", func() {
+	Context("when creating the transformation", func() {
+		It("returns an error if no arguments are provided", func() {
+			_, err := RemoveInstanceGroupTransformation(nil)
+			Ω(err).Should(HaveOccurred())
+		})
+
+		It("returns an error if the instance-group argument is malformed", func() {
+			_, err := RemoveInstanceGroupTransformation([]string{"-instance-group", ",,"})
+			Ω(err).Should(HaveOccurred())
+		})
+
+		It("returns a transformation when given valid args", func() {
+			t, err := RemoveInstanceGroupTransformation([]string{"-instance-group", "router,tcp_router"})
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(t.(*InstanceGroupRemover).InstanceGroups).Should(Equal([]string{"router", "tcp_router"}))
+		})
+	})
+
+	Context("PCF 1.8 AWS manifest", func() {
+		var manifest *enaml.DeploymentManifest
+
+		BeforeEach(func() {
+			f, err := os.Open("fixtures/pcf-aws-1.8.00-build.373.yml")
+			Ω(err).ShouldNot(HaveOccurred())
+			manifest = enaml.NewDeploymentManifestFromFile(f)
+		})
+
+		It("removes the instance groups", func() {
+			expectGolden(goldenCase{
+				Transform: "remove-instance-group",
+				Args:      []string{"-instance-group", "router,tcp_router"},
+				Golden:    "remove-instance-group-routers.diff",
+			})
+		})
+
+		It("returns an error without removing anything when given an invalid instance group", func() {
+			count := len(manifest.InstanceGroups)
+			r := InstanceGroupRemover{InstanceGroups: []string{"router", "foobar"}}
+			Ω(r.Apply(manifest)).Should(BeAssignableToTypeOf(&ErrInstanceGroupNotFound{}))
+			Ω(manifest.InstanceGroups).Should(HaveLen(count))
+		})
+	})
+})
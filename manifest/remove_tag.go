@@ -0,0 +1,77 @@
+package manifest
+
+import (
+	"flag"
+
+	"github.com/enaml-ops/enaml"
+	"github.com/enaml-ops/omg-transform/errs"
+	"github.com/enaml-ops/omg-transform/registry"
+)
+
+// TagRemover is a transformation that removes VM tags.  Tags are removed
+// from the deployment, or from a single instance group's bosh env if
+// InstanceGroup is set.
+type TagRemover struct {
+	Keys          []string
+	InstanceGroup string
+}
+
+func (t *TagRemover) Apply(dm *enaml.DeploymentManifest) error {
+	if t.InstanceGroup == "" {
+		for _, key := range t.Keys {
+			delete(dm.Tags, key)
+		}
+		return nil
+	}
+
+	ig := dm.GetInstanceGroupByName(t.InstanceGroup)
+	if ig == nil {
+		return instanceGroupNotFound(dm, t.InstanceGroup)
+	}
+	tags := instanceGroupTags(ig)
+	for _, key := range t.Keys {
+		delete(tags, key)
+	}
+	setInstanceGroupTags(ig, tags)
+	return nil
+}
+
+func (t *TagRemover) Idempotent() bool { return true }
+
+// Inverse adds back the tags that are removed.
+func (t *TagRemover) Inverse(dm *enaml.DeploymentManifest) ([]Step, error) {
+	existing, err := currentTags(dm, t.InstanceGroup)
+	if err != nil {
+		return nil, err
+	}
+	var removed []string
+	for _, key := range t.Keys {
+		if v, ok := existing[key]; ok && !contains(removed, key+"="+v) {
+			removed = append(removed, key+"="+v)
+		}
+	}
+	return tagSteps(t.InstanceGroup, nil, removed)
+}
+
+func (t *TagRemover) flagSet() *flag.FlagSet {
+	fs := registry.NewFlagSet("remove-tags")
+	fs.StringVar(&t.InstanceGroup, "instance-group", "", "remove the tags from this instance group's bosh env instead of the deployment")
+	return fs
+}
+
+// RemoveTagsTransformation is a TransformationBuilder that builds the
+// 'remove-tags' transformation.
+func RemoveTagsTransformation(args []string) (Transformation, error) {
+	t := &TagRemover{}
+	fs := t.flagSet()
+	err := fs.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+
+	t.Keys = fs.Args()
+	if len(t.Keys) == 0 {
+		return nil, errs.InvalidArgs("missing tag name(s)")
+	}
+	return t, nil
+}
@@ -0,0 +1,84 @@
+package manifest
+
+import (
+	"os"
+
+	"github.com/enaml-ops/enaml"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("remove tags transformation", func() {
+	Context("when creating the transformation", func() {
+		It("returns an error if no arguments are provided", func() {
+			_, err := RemoveTagsTransformation(nil)
+			Ω(err).Should(HaveOccurred())
+		})
+
+		It("returns a transformation when given valid args", func() {
+			t, err := RemoveTagsTransformation([]string{"-instance-group", "router", "key1", "key2"})
+			Ω(err).ShouldNot(HaveOccurred())
+
+			rt := t.(*TagRemover)
+			Ω(rt.InstanceGroup).Should(Equal("router"))
+			Ω(rt.Keys).Should(Equal([]string{"key1", "key2"}))
+		})
+	})
+
+	Context("PCF 1.8 AWS manifest", func() {
+		var manifest *enaml.DeploymentManifest
+
+		BeforeEach(func() {
+			f, err := os.Open("fixtures/pcf-aws-1.8.00-build.373.yml")
+			Ω(err).ShouldNot(HaveOccurred())
+			manifest = enaml.NewDeploymentManifestFromFile(f)
+			manifest.AddTag("key1", "value1")
+			manifest.AddTag("key2", "value2")
+		})
+
+		It("removes tags from the deployment", func() {
+			t := &TagRemover{Keys: []string{"key1", "missing"}}
+			Ω(t.Apply(manifest)).Should(Succeed())
+			Ω(manifest.Tags).Should(HaveLen(1))
+			Ω(manifest.Tag("key2")).Should(Equal("value2"))
+		})
+
+		It("removes tags from another manifest", func() {
+			expectGolden(goldenCase{
+				Input:     "fixtures/rotate-certs.yml",
+				Transform: "remove-tags",
+				Args:      []string{"owner"},
+				Golden:    "remove-tags-owner.diff",
+			})
+		})
+
+		It("removes tags from an instance group", func() {
+			add := &TagAdder{Args: []string{"key1=value1", "key2=value2"}, InstanceGroup: "router"}
+			Ω(add.Apply(manifest)).Should(Succeed())
+
+			t := &TagRemover{Keys: []string{"key1"}, InstanceGroup: "router"}
+			Ω(t.Apply(manifest)).Should(Succeed())
+
+			ig := manifest.GetInstanceGroupByName("router")
+			Ω(instanceGroupTags(ig)).Should(Equal(map[string]string{"key2": "value2"}))
+			Ω(manifest.Tags).Should(HaveLen(2))
+		})
+
+		It("removes the tags section when the last tag is removed", func() {
+			add := &TagAdder{Args: []string{"key1=value1"}, InstanceGroup: "router"}
+			Ω(add.Apply(manifest)).Should(Succeed())
+
+			t := &TagRemover{Keys: []string{"key1"}, InstanceGroup: "router"}
+			Ω(t.Apply(manifest)).Should(Succeed())
+
+			bosh, _ := stringMap(manifest.GetInstanceGroupByName("router").Env["bosh"])
+			Ω(bosh).ShouldNot(HaveKey("tags"))
+			Ω(bosh).Should(HaveKey("password"))
+		})
+
+		It("returns an error when given an invalid instance group", func() {
+			t := &TagRemover{Keys: []string{"key1"}, InstanceGroup: "foobar"}
+			Ω(t.Apply(manifest)).ShouldNot(Succeed())
+		})
+	})
+})
@@ -0,0 +1,96 @@
+package manifest
+
+import (
+	"flag"
+	"strings"
+
+	"github.com/enaml-ops/enaml"
+	"github.com/enaml-ops/omg-transform/errs"
+	"github.com/enaml-ops/omg-transform/registry"
+)
+
+// VMExtensionRemover is a transformation that removes vm extensions
+// from the given instance group.
+type VMExtensionRemover struct {
+	Name          string
+	InstanceGroup string
+	Extensions    []string
+}
+
+func (ve *VMExtensionRemover) Apply(dm *enaml.DeploymentManifest) error {
+	ig := dm.GetInstanceGroupByName(ve.InstanceGroup)
+	if ig == nil {
+		return instanceGroupNotFound(dm, ve.InstanceGroup)
+	}
+
+	var remaining []string
+	for _, ext := range ig.VMExtensions {
+		if !contains(ve.Extensions, ext) {
+			remaining = append(remaining, ext)
+		}
+	}
+	ig.VMExtensions = remaining
+	return nil
+}
+
+func (ve *VMExtensionRemover) Idempotent() bool { return true }
+
+// Inverse adds back the extensions that the instance group has.  They
+// are added after its remaining extensions.
+func (ve *VMExtensionRemover) Inverse(dm *enaml.DeploymentManifest) ([]Step, error) {
+	ig := dm.GetInstanceGroupByName(ve.InstanceGroup)
+	if ig == nil {
+		return nil, instanceGroupNotFound(dm, ve.InstanceGroup)
+	}
+	var removed []string
+	for _, ext := range ig.VMExtensions {
+		if contains(ve.Extensions, ext) {
+			removed = append(removed, ext)
+		}
+	}
+	if len(removed) == 0 {
+		return nil, nil
+	}
+	return []Step{{Transform: "add-vm-extension", Args: []string{"-instance-group", ig.Name, "-name", strings.Join(removed, ",")}}}, nil
+}
+
+// contains returns true if s is an element of list.
+func contains(list []string, s string) bool {
+	for i := range list {
+		if list[i] == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (ve *VMExtensionRemover) flagSet() *flag.FlagSet {
+	fs := registry.NewFlagSet("remove-vm-extension")
+	fs.StringVar(&ve.InstanceGroup, "instance-group", "", "Name of the instance group")
+	fs.StringVar(&ve.Name, "name", "", "Name(s) of the vm extension [If multiple, comma separate values]")
+	return fs
+}
+
+// RemoveVMExtensionTransformation is a TransformationBuilder that builds the
+// 'remove-vm-extension' transformation.
+func RemoveVMExtensionTransformation(args []string) (Transformation, error) {
+	ve := &VMExtensionRemover{}
+	fs := ve.flagSet()
+	err := fs.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+
+	if ve.InstanceGroup == "" {
+		return nil, errs.MissingFlag("instance-group")
+	}
+	if ve.Name == "" {
+		return nil, errs.MissingFlag("name")
+	}
+	ve.Extensions = registry.Split(ve.Name, ",")
+	if len(ve.Extensions) == 0 {
+		return nil, errs.InvalidFlag("name", "invalid format for extension names, must be comma-separated")
+	}
+
+	return ve, nil
+}
@@ -0,0 +1,60 @@
+package manifest
+
+import (
+	"os"
+
+	"github.com/enaml-ops/enaml"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("remove vm extension", func() {
+	Context("when creating the transformation", func() {
+		It("returns an error if no arguments are provided", func() {
+			_, err := RemoveVMExtensionTransformation(nil)
+			Ω(err).Should(HaveOccurred())
+		})
+
+		It("returns an error if the instance-group argument is missing", func() {
+			_, err := RemoveVMExtensionTransformation([]string{"-name", "public-lbs"})
+			Ω(err).Should(HaveOccurred())
+		})
+
+		It("returns an error if the extension name is invalid", func() {
+			_, err := RemoveVMExtensionTransformation([]string{"-instance-group", "foo", "-name", ",,"})
+			Ω(err).Should(HaveOccurred())
+		})
+
+		It("returns a transformation when given valid args", func() {
+			t, err := RemoveVMExtensionTransformation([]string{"-instance-group", "foo", "-name", "public-lbs1,public-lbs2"})
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(t.(*VMExtensionRemover).Extensions).Should(Equal([]string{"public-lbs1", "public-lbs2"}))
+		})
+	})
+
+	Context("PCF 1.8 AWS manifest", func() {
+		var manifest *enaml.DeploymentManifest
+
+		BeforeEach(func() {
+			f, err := os.Open("fixtures/pcf-aws-1.8.00-build.373.yml")
+			Ω(err).ShouldNot(HaveOccurred())
+			manifest = enaml.NewDeploymentManifestFromFile(f)
+		})
+
+		It("removes vm extensions from an instance group", func() {
+			expectGolden(goldenCase{
+				Transform: "remove-vm-extension",
+				Args:      []string{"-instance-group", "nats", "-name", "test,not-present"},
+				Golden:    "remove-vm-extension-nats.diff",
+			})
+		})
+
+		It("returns an error when given an invalid instance group", func() {
+			ve := VMExtensionRemover{
+				InstanceGroup: "blahblah",
+				Extensions:    []string{"test"},
+			}
+			Ω(ve.Apply(manifest)).ShouldNot(Succeed())
+		})
+	})
+})
@@ -0,0 +1,149 @@
+package manifest
+
+import (
+	"strings"
+
+	"github.com/enaml-ops/enaml"
+	"github.com/enaml-ops/omg-transform/cloudconfig"
+	"github.com/enaml-ops/omg-transform/errs"
+)
+
+// The kinds of cloud config entity that RenameReference renames.
+const (
+	ReferenceAZ          = "az"
+	ReferenceNetwork     = "network"
+	ReferenceVMType      = "vm-type"
+	ReferenceVMExtension = "vm-extension"
+	ReferenceDiskType    = "disk-type"
+)
+
+// ReferenceKinds are the kinds of cloud config entity that can be
+// renamed.
+var ReferenceKinds = []string{ReferenceAZ, ReferenceNetwork, ReferenceVMType, ReferenceVMExtension, ReferenceDiskType}
+
+// Renamed is the number of references RenameReference changed in the
+// cloud config and in each manifest.
+type Renamed struct {
+	CloudConfig int
+	Manifests   []int
+}
+
+// RenameReference renames an AZ, network, vm type, vm extension or disk
+// type in a cloud config, and every reference to it in the cloud config
+// and in the deployment manifests that use it, including the AZs of
+// migrated_from entries, so that they stay consistent.  Nothing is
+// changed if an error is returned.
+func RenameReference(cc *enaml.CloudConfigManifest, dms []*enaml.DeploymentManifest, kind, from, to string) (*Renamed, error) {
+	check, ok := referenceChecks[kind]
+	if !ok {
+		return nil, errs.InvalidFlag("kind", "unknown kind %q, must be one of %s", kind, strings.Join(ReferenceKinds, ", "))
+	}
+	if from == to {
+		return nil, errs.InvalidFlag("to", "the new name is the same as the old name")
+	}
+	if err := check(cc, from); err != nil {
+		return nil, err
+	}
+	if check(cc, to) == nil {
+		return nil, errs.Preconditionf("%s %s is already defined in the cloud config", strings.Replace(kind, "-", " ", -1), to)
+	}
+
+	// converting the networks is the only step that can fail, so it
+	// happens before anything is renamed
+	networks, err := cloudconfig.Networks(cc)
+	if err != nil {
+		return nil, err
+	}
+	r := &Renamed{}
+	rename := func(s *string) int {
+		if *s != from {
+			return 0
+		}
+		*s = to
+		return 1
+	}
+	renameAll := func(list []string) int {
+		n := 0
+		for i := range list {
+			n += rename(&list[i])
+		}
+		return n
+	}
+
+	switch kind {
+	case ReferenceAZ:
+		for i := range cc.AZs {
+			r.CloudConfig += rename(&cc.AZs[i].Name)
+		}
+		for i := range networks {
+			for j := range networks[i].Subnets {
+				s := &networks[i].Subnets[j]
+				r.CloudConfig += rename(&s.AZ) + renameAll(s.AZs)
+			}
+		}
+	case ReferenceNetwork:
+		for i := range networks {
+			r.CloudConfig += rename(&networks[i].Name)
+		}
+	case ReferenceVMType:
+		for i := range cc.VMTypes {
+			r.CloudConfig += rename(&cc.VMTypes[i].Name)
+		}
+	case ReferenceVMExtension:
+		for i := range cc.VMExtensions {
+			r.CloudConfig += rename(&cc.VMExtensions[i].Name)
+		}
+	case ReferenceDiskType:
+		for i := range cc.DiskTypes {
+			r.CloudConfig += rename(&cc.DiskTypes[i].Name)
+		}
+	}
+	if kind == ReferenceAZ || kind == ReferenceNetwork {
+		cloudconfig.SetNetworks(cc, networks)
+	}
+	if c := cc.Compilation; c != nil {
+		switch kind {
+		case ReferenceAZ:
+			r.CloudConfig += rename(&c.AZ)
+		case ReferenceNetwork:
+			r.CloudConfig += rename(&c.Network)
+		case ReferenceVMType:
+			r.CloudConfig += rename(&c.VMType)
+		}
+	}
+
+	for _, dm := range dms {
+		n := 0
+		for _, ig := range dm.InstanceGroups {
+			switch kind {
+			case ReferenceAZ:
+				n += renameAll(ig.AZs)
+				for i := range ig.MigratedFrom {
+					n += rename(&ig.MigratedFrom[i].AZ)
+				}
+			case ReferenceNetwork:
+				for i := range ig.Networks {
+					n += rename(&ig.Networks[i].Name)
+				}
+			case ReferenceVMType:
+				n += rename(&ig.VMType)
+			case ReferenceVMExtension:
+				n += renameAll(ig.VMExtensions)
+			case ReferenceDiskType:
+				n += rename(&ig.PersistentDiskType)
+			}
+		}
+		r.Manifests = append(r.Manifests, n)
+	}
+	return r, nil
+}
+
+// referenceChecks return an error if the named entity of each kind isn't
+// defined in a cloud config.
+var referenceChecks = map[string]func(*enaml.CloudConfigManifest, string) error{
+	ReferenceAZ:          cloudconfig.CheckAZ,
+	ReferenceNetwork:     cloudconfig.CheckNetwork,
+	ReferenceVMType:      cloudconfig.CheckVMType,
+	ReferenceVMExtension: cloudconfig.CheckVMExtension,
+	ReferenceDiskType:    cloudconfig.CheckDiskType,
+}
@@ -0,0 +1,120 @@
+package manifest
+
+import (
+	"io/ioutil"
+
+	"github.com/enaml-ops/enaml"
+	"github.com/enaml-ops/omg-transform/cloudconfig"
+	"github.com/enaml-ops/omg-transform/errs"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	yaml "gopkg.in/yaml.v2"
+)
+
+var _ = Describe("renaming references", func() {
+	var (
+		cc  *enaml.CloudConfigManifest
+		dms []*enaml.DeploymentManifest
+	)
+
+	BeforeEach(func() {
+		var err error
+		cc, err = readCloudConfig("fixtures/cloud-config-aws.yml")
+		Ω(err).ShouldNot(HaveOccurred())
+
+		b, err := ioutil.ReadFile("fixtures/pcf-aws-1.8.00-build.373.yml")
+		Ω(err).ShouldNot(HaveOccurred())
+		dms = []*enaml.DeploymentManifest{
+			enaml.NewDeploymentManifest(b),
+			enaml.NewDeploymentManifest([]byte(`name: other
+instance_groups:
+- name: web
+  azs: [us-west-1c, us-west-1b]
+  vm_type: m3.large
+  vm_extensions: [public-lbs]
+  persistent_disk_type: "1024"
+  networks:
+  - name: cf
+`)),
+		}
+	})
+
+	marshal := func(v interface{}) string {
+		b, err := yaml.Marshal(v)
+		Ω(err).ShouldNot(HaveOccurred())
+		return string(b)
+	}
+
+	It("renames an AZ", func() {
+		renamed, err := RenameReference(cc, dms, ReferenceAZ, "us-west-1b", "z1")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(renamed.CloudConfig).Should(Equal(3))
+		Ω(renamed.Manifests).Should(HaveLen(2))
+		Ω(renamed.Manifests[0]).Should(BeNumerically(">", 0))
+		Ω(renamed.Manifests[1]).Should(Equal(1))
+
+		Ω(cc.AZs[0].Name).Should(Equal("z1"))
+		// the IaaS's name for the AZ is kept
+		Ω(marshal(cc.AZs[0].CloudProperties)).Should(Equal("availability_zone: us-west-1b\n"))
+		Ω(cc.Compilation.AZ).Should(Equal("z1"))
+		networks, err := cloudconfig.Networks(cc)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(networks[0].Subnets[0].AZ).Should(Equal("z1"))
+		Ω(networks[0].Subnets[1].AZ).Should(Equal("us-west-1c"))
+		Ω(dms[1].InstanceGroups[0].AZs).Should(Equal([]string{"us-west-1c", "z1"}))
+		for _, v := range []interface{}{dms[0], dms[1]} {
+			Ω(marshal(v)).ShouldNot(ContainSubstring("us-west-1b"))
+		}
+	})
+
+	It("renames a network", func() {
+		renamed, err := RenameReference(cc, dms, ReferenceNetwork, "cf", "default")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(renamed.CloudConfig).Should(Equal(2))
+		Ω(renamed.Manifests[1]).Should(Equal(1))
+
+		Ω(cloudconfig.CheckNetwork(cc, "default")).Should(Succeed())
+		Ω(cc.Compilation.Network).Should(Equal("default"))
+		for _, ig := range dms[0].InstanceGroups {
+			for _, n := range ig.Networks {
+				Ω(n.Name).ShouldNot(Equal("cf"))
+			}
+		}
+	})
+
+	It("renames vm types and disk types", func() {
+		renamed, err := RenameReference(cc, dms, ReferenceVMType, "t2.small", "small")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(renamed.CloudConfig).Should(Equal(2))
+		Ω(renamed.Manifests[0]).Should(BeNumerically(">", 0))
+		Ω(renamed.Manifests[1]).Should(Equal(0))
+		Ω(cc.Compilation.VMType).Should(Equal("small"))
+
+		renamed, err = RenameReference(cc, dms, ReferenceDiskType, "1024", "small")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(renamed.CloudConfig).Should(Equal(1))
+		Ω(renamed.Manifests[1]).Should(Equal(1))
+		Ω(dms[1].InstanceGroups[0].PersistentDiskType).Should(Equal("small"))
+	})
+
+	It("returns an error if the entity isn't defined", func() {
+		_, err := RenameReference(cc, dms, ReferenceAZ, "us-west-1d", "z1")
+		Ω(err).Should(MatchError(HavePrefix("az us-west-1d is not defined in the cloud config")))
+
+		_, err = RenameReference(cc, dms, ReferenceVMExtension, "public-lbs", "lbs")
+		Ω(err).Should(MatchError("vm extension public-lbs is not defined in the cloud config"))
+		Ω(err.(*cloudconfig.ErrVMExtensionNotFound).ErrorKind()).Should(Equal(errs.KindNotFound))
+	})
+
+	It("returns an error if the new name is taken", func() {
+		_, err := RenameReference(cc, dms, ReferenceVMType, "t2.small", "m3.large")
+		Ω(err).Should(MatchError("vm type m3.large is already defined in the cloud config"))
+		Ω(err.(*errs.PreconditionError).ErrorKind()).Should(Equal(errs.KindPrecondition))
+		Ω(cc.VMTypes[1].Name).Should(Equal("t2.small"))
+	})
+
+	It("returns an error for unknown kinds", func() {
+		_, err := RenameReference(cc, dms, "stemcell", "a", "b")
+		Ω(err).Should(MatchError("unknown kind \"stemcell\", must be one of az, network, vm-type, vm-extension, disk-type"))
+	})
+})
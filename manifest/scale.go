@@ -1,11 +1,12 @@
 package manifest
 
 import (
-	"errors"
 	"flag"
-	"fmt"
+	"strconv"
 
 	"github.com/enaml-ops/enaml"
+	"github.com/enaml-ops/omg-transform/errs"
+	"github.com/enaml-ops/omg-transform/registry"
 )
 
 //ScaleInstance Scale instance type stores what instance group and how much to scale it
@@ -18,7 +19,7 @@ type ScaleInstance struct {
 func (s *ScaleInstance) Apply(dm *enaml.DeploymentManifest) error {
 	ig := dm.GetInstanceGroupByName(s.InstanceGroup)
 	if ig == nil {
-		return fmt.Errorf("couldn't find instance group %s", s.InstanceGroup)
+		return instanceGroupNotFound(dm, s.InstanceGroup)
 	}
 
 	ig.Instances = s.Scale
@@ -26,8 +27,23 @@ func (s *ScaleInstance) Apply(dm *enaml.DeploymentManifest) error {
 	return nil
 }
 
+func (s *ScaleInstance) Idempotent() bool { return true }
+
+// Inverse restores the number of instances.
+func (s *ScaleInstance) Inverse(dm *enaml.DeploymentManifest) ([]Step, error) {
+	ig := dm.GetInstanceGroupByName(s.InstanceGroup)
+	if ig == nil {
+		return nil, instanceGroupNotFound(dm, s.InstanceGroup)
+	}
+	return []Step{scaleStep(ig)}, nil
+}
+
+func scaleStep(ig *enaml.InstanceGroup) Step {
+	return Step{Transform: "scale", Args: []string{"-instance-group", ig.Name, "-instances", strconv.Itoa(ig.Instances)}}
+}
+
 func (s *ScaleInstance) flagSet() *flag.FlagSet {
-	fs := flag.NewFlagSet("scale", flag.ContinueOnError)
+	fs := registry.NewFlagSet("scale")
 	fs.StringVar(&s.InstanceGroup, "instance-group", "", "name of the instance group")
 	fs.IntVar(&s.Scale, "instances", -1, "number of instances")
 
@@ -45,17 +61,17 @@ func ScaleInstanceTransform(args []string) (Transformation, error) {
 	}
 
 	if s.InstanceGroup == "" {
-		return nil, errors.New("Missing required flag -instance-group")
+		return nil, errs.MissingFlag("instance-group")
 	}
 
 	if s.Scale < 0 {
-		return nil, errors.New("Missing required flag -scale or invalid value")
+		return nil, errs.InvalidFlag("instances", "missing required flag -instances or invalid value")
 	}
 
 	if s.InstanceGroup == "clock_global" {
 
 		if s.Scale > 1 {
-			return nil, errors.New("Singleton Instance cannot be scaled higher than 1")
+			return nil, errs.InvalidFlag("instances", "Singleton Instance cannot be scaled higher than 1")
 		}
 	}
 
@@ -63,14 +63,11 @@ var _ = Describe("Scale instances", func() {
 		})
 
 		It("changes the number of instances for a instance", func() {
-			const newInstanceCount = 3
-			s := ScaleInstance{
-				InstanceGroup: "router",
-				Scale:         newInstanceCount,
-			}
-			Ω(s.Apply(manifest)).Should(Succeed())
-			ig := manifest.GetInstanceGroupByName("router")
-			Ω(ig.Instances).Should(Equal(3))
+			expectGolden(goldenCase{
+				Transform: "scale",
+				Args:      []string{"-instance-group", "router", "-instances", "3"},
+				Golden:    "scale-router.diff",
+			})
 		})
 
 		It("returns an error when given an invalid instance group", func() {
@@ -0,0 +1,99 @@
+package manifest
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+
+	"github.com/enaml-ops/enaml"
+	"github.com/enaml-ops/omg-transform/errs"
+	"github.com/enaml-ops/omg-transform/registry"
+	"github.com/enaml-ops/omg-transform/script"
+	"go.starlark.net/starlark"
+)
+
+// scriptGlobals are the values that manifest scripts can use, besides
+// the Starlark built-ins.
+//
+//	manifest             the manifest, as a dict
+//	instance_group(name) the instance group dict with that name
+var scriptGlobals = []string{"manifest", "instance_group"}
+
+// Script is a transformation that runs a Starlark script, which edits
+// the manifest in place.
+type Script struct {
+	File     string // the script file
+	Source   string // the script, if File isn't set
+	MaxSteps uint64 // the execution steps after which the script is stopped
+	prog     *script.Program
+}
+
+func (s *Script) Apply(dm *enaml.DeploymentManifest) error {
+	data, err := script.Data(dm)
+	if err != nil {
+		return err
+	}
+	err = s.prog.Run(starlark.StringDict{
+		"manifest": data,
+		"instance_group": script.Lookup("instance_group", data, "instance_groups", func(name string) error {
+			return instanceGroupNotFound(dm, name)
+		}),
+	}, script.Options{MaxSteps: s.MaxSteps, Print: os.Stderr})
+	if err != nil {
+		return err
+	}
+
+	b, err := script.Marshal(data)
+	if err != nil {
+		return errs.Preconditionf("the script left an invalid manifest: %v", err)
+	}
+	out := enaml.NewDeploymentManifest(b)
+	if out == nil {
+		return errs.Preconditionf("the script left an invalid manifest")
+	}
+	*dm = *out
+	return nil
+}
+
+func (s *Script) flagSet() *flag.FlagSet {
+	fs := registry.NewFlagSet("script")
+	fs.StringVar(&s.File, "file", "", "the Starlark script to run")
+	fs.StringVar(&s.Source, "e", "", "the script to run, instead of a file")
+	fs.Uint64Var(&s.MaxSteps, "max-steps", script.DefaultMaxSteps, "stop the script after this many execution steps")
+	return fs
+}
+
+func ScriptTransformation(args []string) (Transformation, error) {
+	s := &Script{}
+	fs := s.flagSet()
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, errs.InvalidArgs("unexpected arguments %v", fs.Args())
+	}
+
+	src, filename, err := scriptSource(s.File, s.Source)
+	if err != nil {
+		return nil, err
+	}
+	if s.prog, err = script.Compile(filename, src, scriptGlobals); err != nil {
+		return nil, errs.InvalidArgs("%v", err)
+	}
+	return s, nil
+}
+
+// scriptSource returns the script given with -file or -e, and the name
+// used for it in errors.
+func scriptSource(file, source string) ([]byte, string, error) {
+	switch {
+	case file != "" && source != "":
+		return nil, "", errs.InvalidArgs("only one of -file and -e can be given")
+	case file != "":
+		src, err := ioutil.ReadFile(file)
+		return src, file, err
+	case source != "":
+		return []byte(source), "<script>", nil
+	}
+	return nil, "", errs.InvalidFlag("file", "missing required flag -file or -e")
+}
@@ -0,0 +1,81 @@
+package manifest
+
+import (
+	"errors"
+	"io/ioutil"
+
+	"github.com/enaml-ops/enaml"
+	"github.com/enaml-ops/omg-transform/errs"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("script", func() {
+	Context("when creating the transform", func() {
+		It("requires a script", func() {
+			_, err := ScriptTransformation(nil)
+			Ω(err).Should(MatchError("missing required flag -file or -e"))
+		})
+
+		It("doesn't accept both -file and -e", func() {
+			_, err := ScriptTransformation([]string{"-file", "fixtures/scripts/scale-routers.star", "-e", "x = 1"})
+			Ω(err).Should(MatchError("only one of -file and -e can be given"))
+		})
+
+		It("reports syntax errors with their line number", func() {
+			_, err := ScriptTransformation([]string{"-e", "x = 1\nmanifst[\"name\"] = \"cf\""})
+			Ω(err).Should(MatchError(ContainSubstring("<script>:2:1: undefined: manifst")))
+			var argErr *errs.ArgumentError
+			Ω(errors.As(err, &argErr)).Should(BeTrue())
+		})
+	})
+
+	Context("when applying the transform", func() {
+		var dm *enaml.DeploymentManifest
+
+		BeforeEach(func() {
+			b, err := ioutil.ReadFile(pcfManifest)
+			Ω(err).ShouldNot(HaveOccurred())
+			dm = enaml.NewDeploymentManifest(b)
+		})
+
+		apply := func(args ...string) error {
+			t, err := ScriptTransformation(args)
+			Ω(err).ShouldNot(HaveOccurred())
+			return t.Apply(dm)
+		}
+
+		It("edits the manifest", func() {
+			expectGolden(goldenCase{
+				Transform: "script",
+				Args:      []string{"-file", "fixtures/scripts/scale-routers.star"},
+				Golden:    "script-scale-routers.diff",
+			})
+		})
+
+		It("runs inline scripts", func() {
+			Ω(apply("-e", `manifest["tags"] = {"owner": "ops"}`)).Should(Succeed())
+			Ω(dm.Tag("owner")).Should(Equal("ops"))
+		})
+
+		It("reports unknown instance groups at the line that looked them up", func() {
+			err := apply("-e", "x = 1\ninstance_group(\"routr\")[\"instances\"] = 3")
+			Ω(err).Should(MatchError("<script>:2:15: couldn't find instance group routr, did you mean router?"))
+			var notFound *ErrInstanceGroupNotFound
+			Ω(errors.As(err, &notFound)).Should(BeTrue())
+			Ω(dm.GetInstanceGroupByName("router").Instances).Should(Equal(1))
+		})
+
+		It("stops scripts that take too many steps", func() {
+			err := apply("-max-steps", "100", "-e", "for i in range(1000):\n    manifest[\"name\"] = str(i)")
+			Ω(err).Should(MatchError(ContainSubstring("too many steps")))
+		})
+
+		It("returns an error if the script leaves values that aren't YAML", func() {
+			err := apply("-e", `manifest["name"] = len`)
+			Ω(err).Should(MatchError(ContainSubstring("the script left an invalid manifest")))
+			_, ok := err.(*errs.PreconditionError)
+			Ω(ok).Should(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,215 @@
+package manifest
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/enaml-ops/enaml"
+	"github.com/enaml-ops/omg-transform/errs"
+	"github.com/enaml-ops/omg-transform/registry"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Splitter is a transformation that moves instance groups out of a
+// deployment and into a new deployment manifest.
+type Splitter struct {
+	InstanceGroups []string // IGs to move
+	Deployment     string   // name for the new deployment
+	Output         string   // file the new deployment manifest is written to
+	igsFlag        string
+}
+
+func (s *Splitter) Apply(dm *enaml.DeploymentManifest) error {
+	split, err := Split(dm, s.Deployment, s.InstanceGroups)
+	if err != nil {
+		return err
+	}
+
+	b, err := yaml.Marshal(split)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(s.Output, b, 0644)
+}
+
+// Inverse merges the new deployment manifest back in.  The instance
+// groups are restored after the ones that remain.
+func (s *Splitter) Inverse(dm *enaml.DeploymentManifest) ([]Step, error) {
+	output, err := filepath.Abs(s.Output)
+	if err != nil {
+		return nil, err
+	}
+	return []Step{{Transform: "merge", Args: []string{"-manifest", output}}}, nil
+}
+
+// Split moves the named instance groups out of dm and into a new
+// deployment manifest with the specified name.
+//
+// The new manifest shares the update block of the original and only
+// contains the releases, stemcells and global properties that the
+// moved instance groups use.  A global property is considered used if
+// its name matches a job or a property namespace configured by one of
+// the moved instance groups.
+//
+// Declared links that end up crossing the two deployments are rewritten
+// as cross-deployment links, and the providing job is marked as shared.
+func Split(dm *enaml.DeploymentManifest, name string, instanceGroups []string) (*enaml.DeploymentManifest, error) {
+	if name == dm.Name {
+		return nil, errs.InvalidFlag("deployment", "new deployment must have a different name than %s", dm.Name)
+	}
+
+	moved := make(map[string]bool, len(instanceGroups))
+	for _, ig := range instanceGroups {
+		if dm.GetInstanceGroupByName(ig) == nil {
+			return nil, instanceGroupNotFound(dm, ig)
+		}
+		moved[ig] = true
+	}
+
+	providers := linkProviders(dm)
+	split := &enaml.DeploymentManifest{
+		Name:         name,
+		DirectorUUID: dm.DirectorUUID,
+		Update:       dm.Update,
+	}
+
+	releases := make(map[string]bool)
+	stemcells := make(map[string]bool)
+	namespaces := make(map[string]bool)
+
+	var remaining []*enaml.InstanceGroup
+	for _, ig := range dm.InstanceGroups {
+		if !moved[ig.Name] {
+			remaining = append(remaining, ig)
+			continue
+		}
+		split.InstanceGroups = append(split.InstanceGroups, ig)
+		stemcells[ig.Stemcell] = true
+		for k := range ig.Properties {
+			namespaces[k] = true
+		}
+		for _, job := range ig.Jobs {
+			releases[job.Release] = true
+			namespaces[job.Name] = true
+			if props, ok := stringMap(job.Properties); ok {
+				for k := range props {
+					namespaces[k] = true
+				}
+			}
+		}
+	}
+	dm.InstanceGroups = remaining
+
+	for _, r := range dm.Releases {
+		if releases[r.Name] {
+			split.Releases = append(split.Releases, r)
+		}
+	}
+	for _, s := range dm.Stemcells {
+		if stemcells[s.Alias] {
+			split.Stemcells = append(split.Stemcells, s)
+		}
+	}
+	for k, v := range dm.Properties {
+		if namespaces[k] {
+			if split.Properties == nil {
+				split.Properties = make(enaml.Properties)
+			}
+			split.Properties[k] = v
+		}
+	}
+
+	linkAcrossDeployments(split, dm, providers)
+	linkAcrossDeployments(dm, split, providers)
+	return split, nil
+}
+
+// linkAcrossDeployments points the declared links consumed in consumer
+// at provider when the instance group providing them lives there.
+func linkAcrossDeployments(consumer, provider *enaml.DeploymentManifest, providers map[string]string) {
+	for _, ig := range consumer.InstanceGroups {
+		for i := range ig.Jobs {
+			job := &ig.Jobs[i]
+			consumes := linkEntries(job.Consumes)
+			changed := false
+			for link, opts := range consumes {
+				if opts == nil {
+					// the link has been explicitly disabled
+					continue
+				}
+				from := link
+				if f, ok := opts["from"].(string); ok && f != "" {
+					from = f
+				}
+				providerIG, ok := providers[from]
+				if !ok || provider.GetInstanceGroupByName(providerIG) == nil {
+					continue
+				}
+				opts["from"] = from
+				opts["deployment"] = provider.Name
+				changed = true
+				shareLink(provider.GetInstanceGroupByName(providerIG), from)
+			}
+			if changed {
+				job.Consumes = linkBlock(consumes)
+			}
+		}
+	}
+}
+
+// shareLink marks the link with the specified name as shared so that it
+// can be consumed from other deployments.
+func shareLink(ig *enaml.InstanceGroup, name string) {
+	for i := range ig.Jobs {
+		job := &ig.Jobs[i]
+		provides := linkEntries(job.Provides)
+		for link, opts := range provides {
+			as, _ := opts["as"].(string)
+			if link != name && as != name {
+				continue
+			}
+			if opts == nil {
+				opts = make(map[string]interface{})
+				provides[link] = opts
+			}
+			opts["shared"] = true
+			job.Provides = linkBlock(provides)
+			return
+		}
+	}
+}
+
+func (s *Splitter) flagSet() *flag.FlagSet {
+	fs := registry.NewFlagSet("split")
+	fs.StringVar(&s.igsFlag, "instance-group", "", "comma-separated list of instance groups to move")
+	fs.StringVar(&s.Deployment, "deployment", "", "the name of the new deployment")
+	fs.StringVar(&s.Output, "output", "", "file to write the new deployment manifest to")
+	return fs
+}
+
+// SplitTransformation is a TransformationBuilder that builds the
+// 'split' transformation.
+func SplitTransformation(args []string) (Transformation, error) {
+	s := &Splitter{}
+	fs := s.flagSet()
+	err := fs.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.igsFlag == "" {
+		return nil, errs.MissingFlag("instance-group")
+	}
+	if s.Deployment == "" {
+		return nil, errs.MissingFlag("deployment")
+	}
+	if s.Output == "" {
+		return nil, errs.MissingFlag("output")
+	}
+	s.InstanceGroups = registry.Split(s.igsFlag, ",")
+	if len(s.InstanceGroups) == 0 {
+		return nil, errs.InvalidFlag("instance-group", "invalid format for instance-group, must be comma-separated")
+	}
+	return s, nil
+}
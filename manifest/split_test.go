@@ -0,0 +1,165 @@
+package manifest
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/enaml-ops/enaml"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("split transformation", func() {
+	Context("when creating the transformation", func() {
+		It("returns an error if no arguments are provided", func() {
+			_, err := SplitTransformation(nil)
+			Ω(err).Should(HaveOccurred())
+		})
+
+		It("returns an error if the instance-group argument is missing", func() {
+			_, err := SplitTransformation([]string{"-deployment", "diego", "-output", "diego.yml"})
+			Ω(err).Should(HaveOccurred())
+		})
+
+		It("returns an error if the deployment argument is missing", func() {
+			_, err := SplitTransformation([]string{"-instance-group", "diego_cell", "-output", "diego.yml"})
+			Ω(err).Should(HaveOccurred())
+		})
+
+		It("returns an error if the output argument is missing", func() {
+			_, err := SplitTransformation([]string{"-instance-group", "diego_cell", "-deployment", "diego"})
+			Ω(err).Should(HaveOccurred())
+		})
+
+		It("returns an error if the instance-group argument is malformed", func() {
+			_, err := SplitTransformation([]string{"-instance-group", ",,", "-deployment", "diego", "-output", "diego.yml"})
+			Ω(err).Should(HaveOccurred())
+		})
+
+		It("returns a transformation when given valid args", func() {
+			t, err := SplitTransformation([]string{"-instance-group", "diego_cell,diego_brain", "-deployment", "diego", "-output", "diego.yml"})
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(t).ShouldNot(BeNil())
+
+			s := t.(*Splitter)
+			Ω(s.InstanceGroups).Should(ConsistOf("diego_cell", "diego_brain"))
+		})
+	})
+
+	Context("PCF 1.8 AWS manifest", func() {
+		var manifest *enaml.DeploymentManifest
+
+		BeforeEach(func() {
+			f, err := os.Open("fixtures/pcf-aws-1.8.00-build.373.yml")
+			Ω(err).ShouldNot(HaveOccurred())
+			manifest = enaml.NewDeploymentManifestFromFile(f)
+		})
+
+		It("returns an error when given an invalid instance group", func() {
+			_, err := Split(manifest, "diego", []string{"diego-cell"})
+			Ω(err).Should(HaveOccurred())
+			Ω(manifest.GetInstanceGroupByName("diego_cell")).ShouldNot(BeNil())
+		})
+
+		It("returns an error when the new deployment has the same name", func() {
+			_, err := Split(manifest, manifest.Name, []string{"diego_cell"})
+			Ω(err).Should(HaveOccurred())
+		})
+
+		It("moves the instance groups into a new manifest", func() {
+			count := len(manifest.InstanceGroups)
+			diego, err := Split(manifest, "diego", []string{"diego_cell"})
+			Ω(err).ShouldNot(HaveOccurred())
+
+			Ω(manifest.InstanceGroups).Should(HaveLen(count - 1))
+			Ω(manifest.GetInstanceGroupByName("diego_cell")).Should(BeNil())
+
+			Ω(diego.Name).Should(Equal("diego"))
+			Ω(diego.DirectorUUID).Should(Equal(manifest.DirectorUUID))
+			Ω(diego.Update).Should(Equal(manifest.Update))
+			Ω(diego.InstanceGroups).Should(HaveLen(1))
+			Ω(diego.GetInstanceGroupByName("diego_cell")).ShouldNot(BeNil())
+		})
+
+		It("only copies the releases and stemcells that are used", func() {
+			diego, err := Split(manifest, "diego", []string{"diego_cell"})
+			Ω(err).ShouldNot(HaveOccurred())
+
+			var releases []string
+			for _, r := range diego.Releases {
+				releases = append(releases, r.Name)
+			}
+			Ω(releases).Should(ConsistOf("consul", "diego", "garden-linux", "cflinuxfs2-rootfs", "cf"))
+			Ω(diego.Stemcells).Should(HaveLen(1))
+			Ω(diego.Stemcells[0].Alias).Should(Equal("bosh-aws-xen-hvm-ubuntu-trusty-go_agent"))
+		})
+
+		It("removes the instance groups from the manifest", func() {
+			dir, err := ioutil.TempDir("", "omg-transform")
+			Ω(err).ShouldNot(HaveOccurred())
+			defer os.RemoveAll(dir)
+
+			expectGolden(goldenCase{
+				Transform: "split",
+				Args:      []string{"-instance-group", "router,tcp_router", "-deployment", "routing", "-output", filepath.Join(dir, "routing.yml")},
+				Golden:    "split-routing.diff",
+			})
+		})
+
+		It("writes the new manifest to the output file", func() {
+			dir, err := ioutil.TempDir("", "omg-transform")
+			Ω(err).ShouldNot(HaveOccurred())
+			defer os.RemoveAll(dir)
+
+			s := &Splitter{
+				InstanceGroups: []string{"router", "tcp_router"},
+				Deployment:     "routing",
+				Output:         filepath.Join(dir, "routing.yml"),
+			}
+			Ω(s.Apply(manifest)).Should(Succeed())
+
+			b, err := ioutil.ReadFile(s.Output)
+			Ω(err).ShouldNot(HaveOccurred())
+			routing := enaml.NewDeploymentManifest(b)
+			Ω(routing).ShouldNot(BeNil())
+			Ω(routing.InstanceGroups).Should(HaveLen(2))
+		})
+	})
+
+	Context("when links cross deployments", func() {
+		var manifest *enaml.DeploymentManifest
+
+		BeforeEach(func() {
+			manifest = enaml.NewDeploymentManifest([]byte(`
+name: cf
+instance_groups:
+- name: database
+  jobs:
+  - name: postgres
+    release: postgres
+    provides:
+      db: {as: cc_db}
+- name: api
+  jobs:
+  - name: cloud_controller
+    release: cf
+    consumes:
+      db: {from: cc_db}
+`))
+			Ω(manifest).ShouldNot(BeNil())
+		})
+
+		It("consumes the link from the other deployment", func() {
+			api, err := Split(manifest, "api", []string{"api"})
+			Ω(err).ShouldNot(HaveOccurred())
+
+			consumes := linkEntries(api.InstanceGroups[0].Jobs[0].Consumes)
+			Ω(consumes["db"]).Should(HaveKeyWithValue("from", "cc_db"))
+			Ω(consumes["db"]).Should(HaveKeyWithValue("deployment", "cf"))
+
+			provides := linkEntries(manifest.GetInstanceGroupByName("database").Jobs[0].Provides)
+			Ω(provides["db"]).Should(HaveKeyWithValue("shared", true))
+		})
+	})
+})
@@ -10,3 +10,23 @@ type Transformation interface {
 // TransformationBuilder is a function that builds a transformation from
 // a CLI context.
 type TransformationBuilder func(args []string) (Transformation, error)
+
+// Idempotent is implemented by transformations that know whether
+// applying them a second time leaves the manifest unchanged.
+type Idempotent interface {
+	Idempotent() bool
+}
+
+// IsIdempotent returns true if t declares itself idempotent.
+func IsIdempotent(t Transformation) bool {
+	i, ok := t.(Idempotent)
+	return ok && i.Idempotent()
+}
+
+// Invertible is implemented by transformations that can be undone.
+type Invertible interface {
+	// Inverse returns the steps that undo the transformation.  It is
+	// called with the manifest the transformation is about to be applied
+	// to, so that the steps can restore the values it replaces.
+	Inverse(dm *enaml.DeploymentManifest) ([]Step, error)
+}
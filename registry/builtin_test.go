@@ -0,0 +1,42 @@
+package registry_test
+
+import (
+	"github.com/enaml-ops/omg-transform/cloudconfig"
+	"github.com/enaml-ops/omg-transform/manifest"
+	"github.com/enaml-ops/omg-transform/registry"
+	"github.com/enaml-ops/omg-transform/runtimeconfig"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("built-in transformations", func() {
+	expectMetadata := func(info registry.Info, hasBuilder bool) {
+		Ω(info.Description).ShouldNot(BeEmpty(), info.Name)
+		Ω(info.Usage).ShouldNot(BeEmpty(), info.Name)
+		Ω(info.Examples).ShouldNot(BeEmpty(), info.Name)
+		Ω(info.Flags).ShouldNot(BeNil(), info.Name)
+		Ω(info.Flags()).ShouldNot(BeNil(), info.Name)
+		Ω(hasBuilder).Should(BeTrue(), info.Name)
+	}
+
+	It("registers every manifest transformation with its metadata", func() {
+		Ω(manifest.Transformations()).ShouldNot(BeEmpty())
+		for _, t := range manifest.Transformations() {
+			expectMetadata(t.Info, t.Builder != nil)
+		}
+	})
+
+	It("registers every cloud config transformation with its metadata", func() {
+		Ω(cloudconfig.Transformations()).ShouldNot(BeEmpty())
+		for _, t := range cloudconfig.Transformations() {
+			expectMetadata(t.Info, t.Builder != nil)
+		}
+	})
+
+	It("registers every runtime config transformation with its metadata", func() {
+		Ω(runtimeconfig.Transformations()).ShouldNot(BeEmpty())
+		for _, t := range runtimeconfig.Transformations() {
+			expectMetadata(t.Info, t.Builder != nil)
+		}
+	})
+})
@@ -0,0 +1,30 @@
+package registry
+
+import (
+	"flag"
+	"io/ioutil"
+	"strings"
+)
+
+// NewFlagSet creates the FlagSet used to parse a transformation's
+// arguments.  Errors are returned to the caller rather than printed,
+// help is provided by the CLI from the transformation's registration.
+func NewFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	return fs
+}
+
+// Split is like strings.Split but does not return empty elements.  It
+// is used to parse the comma separated lists transformations take as
+// arguments.
+func Split(str, sep string) []string {
+	orig := strings.Split(str, sep)
+	var result []string
+	for i := range orig {
+		if orig[i] != "" {
+			result = append(result, orig[i])
+		}
+	}
+	return result
+}
@@ -0,0 +1,19 @@
+package registry
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("argument parsing", func() {
+	It("returns flag errors instead of printing them", func() {
+		fs := NewFlagSet("transform")
+		fs.String("name", "", "a name")
+		Ω(fs.Parse([]string{"-unknown"})).Should(MatchError("flag provided but not defined: -unknown"))
+	})
+
+	It("splits lists without empty elements", func() {
+		Ω(Split("a,,b,", ",")).Should(Equal([]string{"a", "b"}))
+		Ω(Split("", ",")).Should(BeEmpty())
+	})
+})
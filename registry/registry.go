@@ -0,0 +1,76 @@
+// Package registry holds the transformations of each kind of document
+// by name.  The manifest, cloudconfig and runtimeconfig packages each
+// keep a Registry of their own transformations, whose builders differ
+// only in the type of document they apply to, and parse their
+// arguments with the helpers in this package.
+package registry
+
+import (
+	"flag"
+	"fmt"
+)
+
+// Info describes a transformation that can be looked up by name.
+type Info struct {
+	Name        string   // the command used to invoke the transformation
+	Description string   // a one line summary
+	Usage       string   // a synopsis of the arguments
+	Examples    []string // example invocations, without the program name
+
+	// Flags returns the transformation's flags with their defaults
+	// and usage, or nil if the transformation takes no flags.
+	Flags func() *flag.FlagSet
+
+	// Complete maps flag names to the kind of value they take, so that
+	// shell completion can suggest values from the user's manifest and
+	// cloud config.  The kinds are "instance-group", "network", "az",
+	// "vm-type" and "vm-extension".
+	Complete map[string]string
+}
+
+// Entry is a registered transformation and the function that builds it
+// from its arguments.
+type Entry struct {
+	Info
+	Builder interface{}
+}
+
+// Registry is a list of transformations in the order they were
+// registered.  The zero value is an empty registry.
+type Registry struct {
+	entries []Entry
+}
+
+// Register makes a transformation available by name.  It panics if a
+// transformation with the same name is already registered.
+func (r *Registry) Register(e Entry) {
+	if _, ok := r.Lookup(e.Name); ok {
+		panic(fmt.Errorf("duplicate transformation %q\n\nThis is a development error and should be reported at https://github.com/enaml-ops/omg-transform/issues", e.Name))
+	}
+	r.entries = append(r.entries, e)
+}
+
+// Lookup returns the transformation registered with the specified name.
+func (r *Registry) Lookup(name string) (Entry, bool) {
+	for _, e := range r.entries {
+		if e.Name == name {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
+
+// Entries returns every registered transformation in the order they
+// were registered.
+func (r *Registry) Entries() []Entry {
+	return append([]Entry(nil), r.entries...)
+}
+
+// Names returns the names of the registered transformations.
+func (r *Registry) Names() []string {
+	names := make([]string, len(r.entries))
+	for i, e := range r.entries {
+		names[i] = e.Name
+	}
+	return names
+}
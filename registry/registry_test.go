@@ -0,0 +1,47 @@
+package registry
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Registry", func() {
+	var r Registry
+
+	BeforeEach(func() {
+		r = Registry{}
+	})
+
+	It("panics if multiple transformations share the same name", func() {
+		r.Register(Entry{Info: Info{Name: "transform"}})
+		Ω(func() {
+			r.Register(Entry{Info: Info{Name: "transform"}})
+		}).Should(Panic())
+	})
+
+	It("looks up transformations by name", func() {
+		r.Register(Entry{Info: Info{Name: "transform", Description: "a transform"}, Builder: 1})
+		e, ok := r.Lookup("transform")
+		Ω(ok).Should(BeTrue())
+		Ω(e.Description).Should(Equal("a transform"))
+		Ω(e.Builder).Should(Equal(1))
+
+		_, ok = r.Lookup("missing")
+		Ω(ok).Should(BeFalse())
+	})
+
+	It("lists transformations in the order they were registered", func() {
+		for _, name := range []string{"c", "a", "b"} {
+			r.Register(Entry{Info: Info{Name: name}})
+		}
+		Ω(r.Names()).Should(Equal([]string{"c", "a", "b"}))
+		Ω(r.Entries()).Should(HaveLen(3))
+	})
+
+	It("doesn't share entries with copies registered after", func() {
+		r.Register(Entry{Info: Info{Name: "a"}})
+		saved := r
+		r.Register(Entry{Info: Info{Name: "b"}})
+		Ω(saved.Names()).Should(Equal([]string{"a"}))
+	})
+})
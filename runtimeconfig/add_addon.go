@@ -0,0 +1,95 @@
+package runtimeconfig
+
+import (
+	"flag"
+	"io/ioutil"
+
+	"github.com/enaml-ops/enaml"
+	"github.com/enaml-ops/omg-transform/errs"
+	"github.com/enaml-ops/omg-transform/registry"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// AddonAdder is a transformation that adds an addon to a runtime config.
+type AddonAdder struct {
+	Addon Addon
+
+	// Release is added to the runtime config if it doesn't already
+	// contain a release with the same name.
+	Release enaml.Release
+
+	jobsFlag       string
+	propertiesFlag string
+}
+
+func (a *AddonAdder) Apply(rc *RuntimeConfig) error {
+	if rc.GetAddonByName(a.Addon.Name) != nil {
+		return errs.Preconditionf("addon %s already exists", a.Addon.Name)
+	}
+
+	if rc.GetReleaseByName(a.Release.Name) == nil {
+		if a.Release.Version == "" {
+			return errs.InvalidFlag("release-version", "release %s is not in the runtime config and no version was provided", a.Release.Name)
+		}
+		rc.Releases = append(rc.Releases, a.Release)
+	}
+
+	rc.Addons = append(rc.Addons, a.Addon)
+	return nil
+}
+
+func (a *AddonAdder) flagSet() *flag.FlagSet {
+	fs := registry.NewFlagSet("add-addon")
+	fs.StringVar(&a.Addon.Name, "name", "", "name of the addon")
+	fs.StringVar(&a.jobsFlag, "job", "", "comma-separated list of jobs in the addon")
+	fs.StringVar(&a.Release.Name, "release", "", "the release that provides the jobs")
+	fs.StringVar(&a.Release.Version, "release-version", "", "version of the release, if it isn't already in the runtime config")
+	fs.StringVar(&a.Release.URL, "release-url", "", "URL of the release, if it isn't already in the runtime config")
+	fs.StringVar(&a.Release.SHA1, "release-sha1", "", "SHA1 of the release, if it isn't already in the runtime config")
+	fs.StringVar(&a.propertiesFlag, "properties", "", "path to a YAML file with properties for the jobs")
+	return fs
+}
+
+// AddAddonTransformation is a TransformationBuilder that builds the
+// 'add-addon' transformation.
+func AddAddonTransformation(args []string) (Transformation, error) {
+	a := &AddonAdder{}
+	fs := a.flagSet()
+	err := fs.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+
+	if a.Addon.Name == "" {
+		return nil, errs.MissingFlag("name")
+	}
+	if a.jobsFlag == "" {
+		return nil, errs.MissingFlag("job")
+	}
+	if a.Release.Name == "" {
+		return nil, errs.MissingFlag("release")
+	}
+
+	var properties map[string]interface{}
+	if a.propertiesFlag != "" {
+		b, err := ioutil.ReadFile(a.propertiesFlag)
+		if err != nil {
+			return nil, err
+		}
+		if err = yaml.Unmarshal(b, &properties); err != nil {
+			return nil, errs.InvalidFlag("properties", "invalid properties file %s: %v", a.propertiesFlag, err)
+		}
+	}
+
+	for _, job := range registry.Split(a.jobsFlag, ",") {
+		j := Job{Name: job, Release: a.Release.Name}
+		if properties != nil {
+			j.Properties = properties
+		}
+		a.Addon.Jobs = append(a.Addon.Jobs, j)
+	}
+	if len(a.Addon.Jobs) == 0 {
+		return nil, errs.InvalidFlag("job", "invalid format for job, must be comma-separated")
+	}
+	return a, nil
+}
@@ -0,0 +1,85 @@
+package runtimeconfig
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("add addon transformation", func() {
+	Context("when creating the transformation", func() {
+		It("returns an error if no arguments are provided", func() {
+			_, err := AddAddonTransformation(nil)
+			Ω(err).Should(HaveOccurred())
+		})
+
+		It("returns an error if the job argument is missing", func() {
+			_, err := AddAddonTransformation([]string{"-name", "dns", "-release", "bosh-dns"})
+			Ω(err).Should(HaveOccurred())
+		})
+
+		It("returns an error if the release argument is missing", func() {
+			_, err := AddAddonTransformation([]string{"-name", "dns", "-job", "bosh-dns"})
+			Ω(err).Should(HaveOccurred())
+		})
+
+		It("returns an error if the properties file doesn't exist", func() {
+			_, err := AddAddonTransformation([]string{"-name", "dns", "-job", "bosh-dns", "-release", "bosh-dns", "-properties", "fixtures/does-not-exist.yml"})
+			Ω(err).Should(HaveOccurred())
+		})
+
+		It("returns a transformation when given valid args", func() {
+			t, err := AddAddonTransformation([]string{"-name", "dns", "-job", "bosh-dns,bosh-dns-healthcheck", "-release", "bosh-dns", "-release-version", "0.2.0"})
+			Ω(err).ShouldNot(HaveOccurred())
+
+			a := t.(*AddonAdder)
+			Ω(a.Addon.Jobs).Should(HaveLen(2))
+			Ω(a.Addon.Jobs[1]).Should(Equal(Job{Name: "bosh-dns-healthcheck", Release: "bosh-dns"}))
+			Ω(a.Release.Version).Should(Equal("0.2.0"))
+		})
+	})
+
+	Context("when applying the transformation", func() {
+		var rc *RuntimeConfig
+
+		BeforeEach(func() {
+			rc = loadFixture()
+		})
+
+		It("adds the addon and its release", func() {
+			a := &AddonAdder{
+				Addon: Addon{Name: "dns", Jobs: []Job{{Name: "bosh-dns", Release: "bosh-dns"}}},
+			}
+			a.Release.Name = "bosh-dns"
+			a.Release.Version = "0.2.0"
+			Ω(a.Apply(rc)).Should(Succeed())
+
+			Ω(rc.GetAddonByName("dns")).ShouldNot(BeNil())
+			Ω(rc.GetReleaseByName("bosh-dns").Version).Should(Equal("0.2.0"))
+		})
+
+		It("uses a release that is already in the runtime config", func() {
+			a := &AddonAdder{
+				Addon: Addon{Name: "syslog_storer", Jobs: []Job{{Name: "syslog_storer", Release: "syslog"}}},
+			}
+			a.Release.Name = "syslog"
+			Ω(a.Apply(rc)).Should(Succeed())
+			Ω(rc.Releases).Should(HaveLen(3))
+		})
+
+		It("returns an error if the release is missing and has no version", func() {
+			a := &AddonAdder{
+				Addon: Addon{Name: "dns", Jobs: []Job{{Name: "bosh-dns", Release: "bosh-dns"}}},
+			}
+			a.Release.Name = "bosh-dns"
+			Ω(a.Apply(rc)).ShouldNot(Succeed())
+		})
+
+		It("returns an error if the addon already exists", func() {
+			a := &AddonAdder{
+				Addon: Addon{Name: "security", Jobs: []Job{{Name: "security_agent", Release: "security-agent"}}},
+			}
+			a.Release.Name = "security-agent"
+			Ω(a.Apply(rc)).ShouldNot(Succeed())
+		})
+	})
+})
@@ -0,0 +1,60 @@
+package runtimeconfig
+
+import (
+	"flag"
+
+	"github.com/enaml-ops/omg-transform/registry"
+)
+
+func init() {
+	RegisterTransformation(TransformationInfo{
+		Info: registry.Info{
+			Name:        "add-addon",
+			Description: "add an addon, and its release if necessary",
+			Usage:       "-name name -job jobs -release name [-release-version version] [-release-url url] [-release-sha1 sha1] [-properties file]",
+			Examples: []string{
+				"add-addon -name syslog_forwarder -job syslog_forwarder -release syslog -release-version 11 -properties syslog.yml",
+			},
+			Flags: func() *flag.FlagSet { return new(AddonAdder).flagSet() },
+		},
+		Builder: AddAddonTransformation,
+	})
+	RegisterTransformation(TransformationInfo{
+		Info: registry.Info{
+			Name:        "remove-addon",
+			Description: "remove an addon and the releases only it uses",
+			Usage:       "-name name",
+			Examples: []string{
+				"remove-addon -name syslog_forwarder",
+			},
+			Flags: func() *flag.FlagSet { return new(AddonRemover).flagSet() },
+		},
+		Builder: RemoveAddonTransformation,
+	})
+	RegisterTransformation(TransformationInfo{
+		Info: registry.Info{
+			Name:        "set-addon-placement",
+			Description: "set an addon's include and exclude rules",
+			Usage:       "-name name [-include kind=values]... [-exclude kind=values]... [-clear]",
+			Examples: []string{
+				"set-addon-placement -name security -include deployments=cf,cf-diego -exclude jobs=smoke_tests:cf",
+				"set-addon-placement -name syslog_forwarder -include stemcell=ubuntu-trusty",
+			},
+			Flags: func() *flag.FlagSet { return new(PlacementChanger).flagSet() },
+		},
+		Builder: SetAddonPlacementTransformation,
+	})
+	RegisterTransformation(TransformationInfo{
+		Info: registry.Info{
+			Name:        "pin-release",
+			Description: "pin a release to a specific version",
+			Usage:       "-release name -version version [-url url] [-sha1 sha1]",
+			Examples: []string{
+				"pin-release -release os-conf -version 12",
+				"pin-release -release syslog -version 12 -url https://bosh.io/d/github.com/cloudfoundry/syslog-release?v=12 -sha1 <sha1>",
+			},
+			Flags: func() *flag.FlagSet { return new(ReleasePinner).flagSet() },
+		},
+		Builder: PinReleaseTransformation,
+	})
+}
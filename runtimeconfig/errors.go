@@ -0,0 +1,31 @@
+package runtimeconfig
+
+import (
+	"fmt"
+
+	"github.com/enaml-ops/omg-transform/errs"
+)
+
+// ErrAddonNotFound is returned when a transformation refers to an addon
+// that isn't in the runtime config.
+type ErrAddonNotFound struct {
+	Name string `json:"name"`
+}
+
+func (e *ErrAddonNotFound) Error() string {
+	return fmt.Sprintf("couldn't find addon %s", e.Name)
+}
+
+func (e *ErrAddonNotFound) ErrorKind() string { return errs.KindNotFound }
+
+// ErrReleaseNotFound is returned when a transformation refers to a
+// release that isn't in the runtime config.
+type ErrReleaseNotFound struct {
+	Name string `json:"name"`
+}
+
+func (e *ErrReleaseNotFound) Error() string {
+	return fmt.Sprintf("couldn't find release %s", e.Name)
+}
+
+func (e *ErrReleaseNotFound) ErrorKind() string { return errs.KindNotFound }
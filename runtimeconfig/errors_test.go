@@ -0,0 +1,34 @@
+package runtimeconfig
+
+import (
+	"github.com/enaml-ops/omg-transform/errs"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("errors", func() {
+	var rc *RuntimeConfig
+
+	BeforeEach(func() {
+		rc = loadFixture()
+	})
+
+	It("reports missing addons by name", func() {
+		err := (&AddonRemover{Name: "missing"}).Apply(rc)
+		Ω(err).Should(Equal(&ErrAddonNotFound{Name: "missing"}))
+		Ω(err.(*ErrAddonNotFound).ErrorKind()).Should(Equal(errs.KindNotFound))
+	})
+
+	It("reports missing releases by name", func() {
+		r := &ReleasePinner{}
+		r.Release.Name = "missing"
+		r.Release.Version = "1"
+		Ω(r.Apply(rc)).Should(Equal(&ErrReleaseNotFound{Name: "missing"}))
+	})
+
+	It("reports the flag at fault for invalid arguments", func() {
+		_, err := SetAddonPlacementTransformation([]string{"-name", "dns", "-include", "bogus"})
+		Ω(err).Should(BeAssignableToTypeOf(&errs.ArgumentError{}))
+		Ω(err.(*errs.ArgumentError).Flag).Should(Equal("include"))
+	})
+})
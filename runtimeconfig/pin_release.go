@@ -0,0 +1,69 @@
+package runtimeconfig
+
+import (
+	"flag"
+
+	"github.com/enaml-ops/enaml"
+	"github.com/enaml-ops/omg-transform/errs"
+	"github.com/enaml-ops/omg-transform/registry"
+)
+
+// ReleasePinner is a transformation that pins an addon release
+// to a specific version.  A release that is downloaded from a URL
+// must be given the URL and SHA1 of the pinned version.
+type ReleasePinner struct {
+	Release enaml.Release
+}
+
+func (r *ReleasePinner) Apply(rc *RuntimeConfig) error {
+	release := rc.GetReleaseByName(r.Release.Name)
+	if release == nil {
+		return &ErrReleaseNotFound{Name: r.Release.Name}
+	}
+
+	// the old url and sha1 are for the old version
+	if release.URL != "" && r.Release.URL == "" {
+		return errs.Preconditionf("release %s is downloaded from a URL, -url is required to pin it", release.Name)
+	}
+	if release.SHA1 != "" && r.Release.SHA1 == "" {
+		return errs.Preconditionf("release %s has a SHA1, -sha1 is required to pin it", release.Name)
+	}
+
+	release.Version = r.Release.Version
+	if r.Release.URL != "" {
+		release.URL = r.Release.URL
+		release.SHA1 = r.Release.SHA1
+	}
+	return nil
+}
+
+func (r *ReleasePinner) flagSet() *flag.FlagSet {
+	fs := registry.NewFlagSet("pin-release")
+	fs.StringVar(&r.Release.Name, "release", "", "name of the release")
+	fs.StringVar(&r.Release.Version, "version", "", "the version to pin the release to")
+	fs.StringVar(&r.Release.URL, "url", "", "URL to download the pinned version from")
+	fs.StringVar(&r.Release.SHA1, "sha1", "", "SHA1 of the pinned version")
+	return fs
+}
+
+// PinReleaseTransformation is a TransformationBuilder that builds the
+// 'pin-release' transformation.
+func PinReleaseTransformation(args []string) (Transformation, error) {
+	r := &ReleasePinner{}
+	fs := r.flagSet()
+	err := fs.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.Release.Name == "" {
+		return nil, errs.MissingFlag("release")
+	}
+	if r.Release.Version == "" || r.Release.Version == "latest" {
+		return nil, errs.InvalidFlag("version", "missing required flag -version, must be a specific version")
+	}
+	if r.Release.SHA1 != "" && r.Release.URL == "" {
+		return nil, errs.InvalidFlag("sha1", "flag -sha1 requires -url")
+	}
+	return r, nil
+}
@@ -0,0 +1,87 @@
+package runtimeconfig
+
+import (
+	"github.com/enaml-ops/omg-transform/errs"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("pin release transformation", func() {
+	Context("when creating the transformation", func() {
+		It("returns an error if no arguments are provided", func() {
+			_, err := PinReleaseTransformation(nil)
+			Ω(err).Should(HaveOccurred())
+		})
+
+		It("returns an error if the version is missing or latest", func() {
+			_, err := PinReleaseTransformation([]string{"-release", "os-conf"})
+			Ω(err).Should(HaveOccurred())
+
+			_, err = PinReleaseTransformation([]string{"-release", "os-conf", "-version", "latest"})
+			Ω(err).Should(HaveOccurred())
+		})
+
+		It("returns an error if a sha1 is given without a url", func() {
+			_, err := PinReleaseTransformation([]string{"-release", "os-conf", "-version", "12", "-sha1", "abc"})
+			Ω(err).Should(HaveOccurred())
+		})
+
+		It("returns a transformation when given valid args", func() {
+			t, err := PinReleaseTransformation([]string{"-release", "os-conf", "-version", "12"})
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(t).ShouldNot(BeNil())
+		})
+	})
+
+	Context("when applying the transformation", func() {
+		var rc *RuntimeConfig
+
+		BeforeEach(func() {
+			rc = loadFixture()
+		})
+
+		It("pins the release version", func() {
+			r := &ReleasePinner{}
+			r.Release.Name = "os-conf"
+			r.Release.Version = "12"
+			Ω(r.Apply(rc)).Should(Succeed())
+			Ω(rc.GetReleaseByName("os-conf").Version).Should(Equal("12"))
+		})
+
+		It("replaces the url and sha1 when a url is given", func() {
+			r := &ReleasePinner{}
+			r.Release.Name = "syslog"
+			r.Release.Version = "12"
+			r.Release.URL = "https://example.com/syslog-12.tgz"
+			r.Release.SHA1 = "abc"
+			Ω(r.Apply(rc)).Should(Succeed())
+
+			release := rc.GetReleaseByName("syslog")
+			Ω(release.URL).Should(Equal("https://example.com/syslog-12.tgz"))
+			Ω(release.SHA1).Should(Equal("abc"))
+		})
+
+		It("requires a url and sha1 for a release downloaded from a url", func() {
+			r := &ReleasePinner{}
+			r.Release.Name = "syslog"
+			r.Release.Version = "12"
+			err := r.Apply(rc)
+			Ω(err).Should(MatchError("release syslog is downloaded from a URL, -url is required to pin it"))
+			Ω(err).Should(BeAssignableToTypeOf(&errs.PreconditionError{}))
+
+			r.Release.URL = "https://example.com/syslog-12.tgz"
+			Ω(r.Apply(rc)).Should(MatchError("release syslog has a SHA1, -sha1 is required to pin it"))
+
+			release := rc.GetReleaseByName("syslog")
+			Ω(release.Version).Should(Equal("11"))
+			Ω(release.URL).Should(Equal("https://bosh.io/d/github.com/cloudfoundry/syslog-release?v=11"))
+		})
+
+		It("returns an error when given an invalid release", func() {
+			r := &ReleasePinner{}
+			r.Release.Name = "foobar"
+			r.Release.Version = "1"
+			Ω(r.Apply(rc)).ShouldNot(Succeed())
+		})
+	})
+})
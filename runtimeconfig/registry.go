@@ -0,0 +1,43 @@
+package runtimeconfig
+
+import "github.com/enaml-ops/omg-transform/registry"
+
+// TransformationInfo describes a runtime config transformation that can be
+// looked up by name.
+type TransformationInfo struct {
+	registry.Info
+	Builder TransformationBuilder
+}
+
+var transformations registry.Registry
+
+// RegisterTransformation makes a transformation available by name.
+// It panics if a transformation with the same name is already registered.
+func RegisterTransformation(info TransformationInfo) {
+	transformations.Register(registry.Entry{Info: info.Info, Builder: info.Builder})
+}
+
+// LookupTransformation returns the transformation registered with
+// the specified name.
+func LookupTransformation(name string) (TransformationInfo, bool) {
+	e, ok := transformations.Lookup(name)
+	if !ok {
+		return TransformationInfo{}, false
+	}
+	return transformationInfo(e), true
+}
+
+// Transformations returns every registered transformation in the order
+// they were registered.
+func Transformations() []TransformationInfo {
+	var infos []TransformationInfo
+	for _, e := range transformations.Entries() {
+		infos = append(infos, transformationInfo(e))
+	}
+	return infos
+}
+
+func transformationInfo(e registry.Entry) TransformationInfo {
+	builder, _ := e.Builder.(TransformationBuilder)
+	return TransformationInfo{Info: e.Info, Builder: builder}
+}
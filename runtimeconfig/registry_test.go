@@ -0,0 +1,16 @@
+package runtimeconfig
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("transformation registry", func() {
+	It("looks up builders of runtime config transformations", func() {
+		info, ok := LookupTransformation("remove-addon")
+		Ω(ok).Should(BeTrue())
+		t, err := info.Builder([]string{"-name", "os-conf"})
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(t).Should(Equal(&AddonRemover{Name: "os-conf"}))
+	})
+})
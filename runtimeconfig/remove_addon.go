@@ -0,0 +1,77 @@
+package runtimeconfig
+
+import (
+	"flag"
+
+	"github.com/enaml-ops/enaml"
+	"github.com/enaml-ops/omg-transform/errs"
+	"github.com/enaml-ops/omg-transform/registry"
+)
+
+// AddonRemover is a transformation that removes an addon from a runtime
+// config, along with any releases that no other addon uses.
+type AddonRemover struct {
+	Name string
+}
+
+func (a *AddonRemover) Apply(rc *RuntimeConfig) error {
+	removed := rc.GetAddonByName(a.Name)
+	if removed == nil {
+		return &ErrAddonNotFound{Name: a.Name}
+	}
+
+	var addons []Addon
+	used := make(map[string]bool)
+	for _, addon := range rc.Addons {
+		if addon.Name == a.Name {
+			continue
+		}
+		addons = append(addons, addon)
+		for _, job := range addon.Jobs {
+			used[job.Release] = true
+		}
+	}
+
+	var releases []enaml.Release
+	for _, r := range rc.Releases {
+		if used[r.Name] || !usedBy(removed, r.Name) {
+			releases = append(releases, r)
+		}
+	}
+
+	rc.Addons = addons
+	rc.Releases = releases
+	return nil
+}
+
+// usedBy returns true if any of the addon's jobs come from the release.
+func usedBy(addon *Addon, release string) bool {
+	for _, job := range addon.Jobs {
+		if job.Release == release {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *AddonRemover) flagSet() *flag.FlagSet {
+	fs := registry.NewFlagSet("remove-addon")
+	fs.StringVar(&a.Name, "name", "", "name of the addon")
+	return fs
+}
+
+// RemoveAddonTransformation is a TransformationBuilder that builds the
+// 'remove-addon' transformation.
+func RemoveAddonTransformation(args []string) (Transformation, error) {
+	a := &AddonRemover{}
+	fs := a.flagSet()
+	err := fs.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+
+	if a.Name == "" {
+		return nil, errs.MissingFlag("name")
+	}
+	return a, nil
+}
@@ -0,0 +1,42 @@
+package runtimeconfig
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("remove addon transformation", func() {
+	It("returns an error if no arguments are provided", func() {
+		_, err := RemoveAddonTransformation(nil)
+		Ω(err).Should(HaveOccurred())
+	})
+
+	Context("when applying the transformation", func() {
+		var rc *RuntimeConfig
+
+		BeforeEach(func() {
+			rc = loadFixture()
+		})
+
+		It("removes the addon and the releases only it uses", func() {
+			a := &AddonRemover{Name: "security"}
+			Ω(a.Apply(rc)).Should(Succeed())
+			Ω(rc.GetAddonByName("security")).Should(BeNil())
+			Ω(rc.Addons).Should(HaveLen(2))
+			Ω(rc.GetReleaseByName("security-agent")).Should(BeNil())
+			Ω(rc.Releases).Should(HaveLen(2))
+		})
+
+		It("keeps releases that other addons use", func() {
+			rc.Addons = append(rc.Addons, Addon{Name: "syslog_storer", Jobs: []Job{{Name: "syslog_storer", Release: "syslog"}}})
+			a := &AddonRemover{Name: "syslog_forwarder"}
+			Ω(a.Apply(rc)).Should(Succeed())
+			Ω(rc.GetReleaseByName("syslog")).ShouldNot(BeNil())
+		})
+
+		It("returns an error when given an invalid addon", func() {
+			a := &AddonRemover{Name: "foobar"}
+			Ω(a.Apply(rc)).ShouldNot(Succeed())
+		})
+	})
+})
@@ -0,0 +1,91 @@
+// Package runtimeconfig contains transformations for bosh runtime configs.
+package runtimeconfig
+
+import (
+	"github.com/enaml-ops/enaml"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// RuntimeConfig is a bosh runtime config.
+//
+// RuntimeConfig, Addon, Job and Placement keep the keys they don't
+// model, such as variables or a job's links, in Extra, so that
+// transformations don't drop them.
+type RuntimeConfig struct {
+	Releases []enaml.Release        `yaml:"releases,omitempty"`
+	Addons   []Addon                `yaml:"addons,omitempty"`
+	Tags     map[string]string      `yaml:"tags,omitempty"`
+	Extra    map[string]interface{} `yaml:",inline"`
+}
+
+// Addon is a set of jobs that bosh colocates on the VMs
+// matched by its placement rules.
+type Addon struct {
+	Name    string                 `yaml:"name"`
+	Jobs    []Job                  `yaml:"jobs,omitempty"`
+	Include *Placement             `yaml:"include,omitempty"`
+	Exclude *Placement             `yaml:"exclude,omitempty"`
+	Extra   map[string]interface{} `yaml:",inline"`
+}
+
+// Job is a job that is part of an addon.
+type Job struct {
+	Name       string                 `yaml:"name"`
+	Release    string                 `yaml:"release"`
+	Properties interface{}            `yaml:"properties,omitempty"`
+	Extra      map[string]interface{} `yaml:",inline"`
+}
+
+// Placement is a set of include or exclude rules for an addon.
+type Placement struct {
+	Stemcells      []StemcellRule         `yaml:"stemcell,omitempty"`
+	Deployments    []string               `yaml:"deployments,omitempty"`
+	Jobs           []JobRule              `yaml:"jobs,omitempty"`
+	InstanceGroups []string               `yaml:"instance_groups,omitempty"`
+	Networks       []string               `yaml:"networks,omitempty"`
+	Teams          []string               `yaml:"teams,omitempty"`
+	Extra          map[string]interface{} `yaml:",inline"`
+}
+
+// StemcellRule matches VMs by stemcell operating system.
+type StemcellRule struct {
+	OS string `yaml:"os"`
+}
+
+// JobRule matches VMs that run a job from a release.
+type JobRule struct {
+	Name    string `yaml:"name"`
+	Release string `yaml:"release"`
+}
+
+// NewRuntimeConfig parses a runtime config, returning nil if
+// it is invalid.
+func NewRuntimeConfig(b []byte) *RuntimeConfig {
+	rc := new(RuntimeConfig)
+	if err := yaml.Unmarshal(b, rc); err != nil {
+		return nil
+	}
+	return rc
+}
+
+// GetAddonByName returns the addon with the specified name,
+// or nil if there is no such addon.
+func (rc *RuntimeConfig) GetAddonByName(name string) *Addon {
+	for i := range rc.Addons {
+		if rc.Addons[i].Name == name {
+			return &rc.Addons[i]
+		}
+	}
+	return nil
+}
+
+// GetReleaseByName returns the release with the specified name,
+// or nil if there is no such release.
+func (rc *RuntimeConfig) GetReleaseByName(name string) *enaml.Release {
+	for i := range rc.Releases {
+		if rc.Releases[i].Name == name {
+			return &rc.Releases[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package runtimeconfig
+
+import (
+	"io/ioutil"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// loadFixture reads the runtime config used by the tests.
+func loadFixture() *RuntimeConfig {
+	b, err := ioutil.ReadFile("fixtures/runtime-config.yml")
+	Ω(err).ShouldNot(HaveOccurred())
+	rc := NewRuntimeConfig(b)
+	Ω(rc).ShouldNot(BeNil())
+	return rc
+}
+
+var _ = Describe("runtime config", func() {
+	It("returns nil for an invalid runtime config", func() {
+		Ω(NewRuntimeConfig([]byte("addons: {"))).Should(BeNil())
+	})
+
+	It("parses addons and their placement rules", func() {
+		rc := loadFixture()
+		Ω(rc.Releases).Should(HaveLen(3))
+		Ω(rc.Addons).Should(HaveLen(3))
+		Ω(rc.Tags).Should(HaveKeyWithValue("owner", "cloudops"))
+
+		syslog := rc.GetAddonByName("syslog_forwarder")
+		Ω(syslog).ShouldNot(BeNil())
+		Ω(syslog.Include.Stemcells).Should(Equal([]StemcellRule{{OS: "ubuntu-trusty"}}))
+
+		security := rc.GetAddonByName("security")
+		Ω(security.Exclude.Deployments).Should(Equal([]string{"concourse"}))
+		Ω(security.Exclude.Jobs).Should(Equal([]JobRule{{Name: "smoke_tests", Release: "cf"}}))
+	})
+
+	It("looks up releases by name", func() {
+		rc := loadFixture()
+		Ω(rc.GetReleaseByName("os-conf").Version).Should(Equal("latest"))
+		Ω(rc.GetReleaseByName("missing")).Should(BeNil())
+	})
+
+	It("keeps the keys it doesn't model", func() {
+		b, err := ioutil.ReadFile("fixtures/runtime-config-links.yml")
+		Ω(err).ShouldNot(HaveOccurred())
+		rc := NewRuntimeConfig(b)
+		Ω(rc).ShouldNot(BeNil())
+		Ω(rc.Addons[0].Include.Networks).Should(Equal([]string{"default"}))
+
+		out, err := yaml.Marshal(rc)
+		Ω(err).ShouldNot(HaveOccurred())
+		var want, got interface{}
+		Ω(yaml.Unmarshal(b, &want)).Should(Succeed())
+		Ω(yaml.Unmarshal(out, &got)).Should(Succeed())
+		Ω(got).Should(Equal(want))
+	})
+})
@@ -0,0 +1,136 @@
+package runtimeconfig
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/enaml-ops/omg-transform/errs"
+	"github.com/enaml-ops/omg-transform/registry"
+)
+
+// PlacementChanger is a transformation that sets the include and
+// exclude rules of an addon.  A nil Include or Exclude leaves the
+// existing rules in place.
+type PlacementChanger struct {
+	Name    string
+	Include *Placement
+	Exclude *Placement
+
+	includeFlag rules
+	excludeFlag rules
+	clear       bool
+}
+
+func (p *PlacementChanger) Apply(rc *RuntimeConfig) error {
+	addon := rc.GetAddonByName(p.Name)
+	if addon == nil {
+		return &ErrAddonNotFound{Name: p.Name}
+	}
+
+	if p.Include != nil {
+		addon.Include = emptyToNil(p.Include)
+	}
+	if p.Exclude != nil {
+		addon.Exclude = emptyToNil(p.Exclude)
+	}
+	return nil
+}
+
+func emptyToNil(p *Placement) *Placement {
+	if len(p.Stemcells) == 0 && len(p.Deployments) == 0 && len(p.Jobs) == 0 &&
+		len(p.InstanceGroups) == 0 && len(p.Networks) == 0 && len(p.Teams) == 0 {
+		return nil
+	}
+	return p
+}
+
+// rules is a flag.Value that collects placement rules.
+type rules []string
+
+func (r *rules) String() string {
+	return strings.Join(*r, " ")
+}
+
+func (r *rules) Set(value string) error {
+	*r = append(*r, value)
+	return nil
+}
+
+// parsePlacement parses rules of the form kind=value,value.  Supported
+// kinds are stemcell (operating systems), deployments, jobs (in
+// name:release format), instance_groups, networks and teams.
+func parsePlacement(rules []string) (*Placement, error) {
+	p := &Placement{}
+	for _, rule := range rules {
+		parts := strings.SplitN(rule, "=", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			return nil, fmt.Errorf("invalid placement rule %q, expected format kind=value,value", rule)
+		}
+		values := registry.Split(parts[1], ",")
+		switch parts[0] {
+		case "stemcell":
+			for _, os := range values {
+				p.Stemcells = append(p.Stemcells, StemcellRule{OS: os})
+			}
+		case "deployments":
+			p.Deployments = append(p.Deployments, values...)
+		case "jobs":
+			for _, v := range values {
+				job := strings.Split(v, ":")
+				if len(job) != 2 || job[0] == "" || job[1] == "" {
+					return nil, fmt.Errorf("invalid job rule %q, expected format name:release", v)
+				}
+				p.Jobs = append(p.Jobs, JobRule{Name: job[0], Release: job[1]})
+			}
+		case "instance_groups":
+			p.InstanceGroups = append(p.InstanceGroups, values...)
+		case "networks":
+			p.Networks = append(p.Networks, values...)
+		case "teams":
+			p.Teams = append(p.Teams, values...)
+		default:
+			return nil, fmt.Errorf("invalid placement rule %q, unknown kind %q", rule, parts[0])
+		}
+	}
+	return p, nil
+}
+
+func (p *PlacementChanger) flagSet() *flag.FlagSet {
+	fs := registry.NewFlagSet("set-addon-placement")
+	fs.StringVar(&p.Name, "name", "", "name of the addon")
+	fs.Var(&p.includeFlag, "include", "an include rule in kind=value,value format (may be repeated)")
+	fs.Var(&p.excludeFlag, "exclude", "an exclude rule in kind=value,value format (may be repeated)")
+	fs.BoolVar(&p.clear, "clear", false, "remove the existing rules that aren't replaced")
+	return fs
+}
+
+// SetAddonPlacementTransformation is a TransformationBuilder that builds the
+// 'set-addon-placement' transformation.
+func SetAddonPlacementTransformation(args []string) (Transformation, error) {
+	p := &PlacementChanger{}
+	fs := p.flagSet()
+	err := fs.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.Name == "" {
+		return nil, errs.MissingFlag("name")
+	}
+	if len(p.includeFlag) == 0 && len(p.excludeFlag) == 0 && !p.clear {
+		return nil, errs.InvalidArgs("missing placement rules, provide -include, -exclude or -clear")
+	}
+
+	if len(p.includeFlag) > 0 || p.clear {
+		if p.Include, err = parsePlacement(p.includeFlag); err != nil {
+			return nil, errs.InvalidFlag("include", "%v", err)
+		}
+	}
+	if len(p.excludeFlag) > 0 || p.clear {
+		if p.Exclude, err = parsePlacement(p.excludeFlag); err != nil {
+			return nil, errs.InvalidFlag("exclude", "%v", err)
+		}
+	}
+	return p, nil
+}
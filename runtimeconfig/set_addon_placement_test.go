@@ -0,0 +1,77 @@
+package runtimeconfig
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("set addon placement transformation", func() {
+	Context("when creating the transformation", func() {
+		It("returns an error if no arguments are provided", func() {
+			_, err := SetAddonPlacementTransformation(nil)
+			Ω(err).Should(HaveOccurred())
+		})
+
+		It("returns an error if no rules are provided", func() {
+			_, err := SetAddonPlacementTransformation([]string{"-name", "security"})
+			Ω(err).Should(HaveOccurred())
+		})
+
+		It("returns an error for malformed rules", func() {
+			_, err := SetAddonPlacementTransformation([]string{"-name", "security", "-include", "deployments"})
+			Ω(err).Should(HaveOccurred())
+
+			_, err = SetAddonPlacementTransformation([]string{"-name", "security", "-include", "azs=z1"})
+			Ω(err).Should(HaveOccurred())
+
+			_, err = SetAddonPlacementTransformation([]string{"-name", "security", "-exclude", "jobs=smoke_tests"})
+			Ω(err).Should(HaveOccurred())
+		})
+
+		It("parses repeated rules", func() {
+			t, err := SetAddonPlacementTransformation([]string{"-name", "security",
+				"-include", "deployments=cf,diego",
+				"-include", "stemcell=ubuntu-trusty",
+				"-exclude", "jobs=smoke_tests:cf,acceptance_tests:cf",
+			})
+			Ω(err).ShouldNot(HaveOccurred())
+
+			p := t.(*PlacementChanger)
+			Ω(p.Include.Deployments).Should(Equal([]string{"cf", "diego"}))
+			Ω(p.Include.Stemcells).Should(Equal([]StemcellRule{{OS: "ubuntu-trusty"}}))
+			Ω(p.Exclude.Jobs).Should(HaveLen(2))
+		})
+	})
+
+	Context("when applying the transformation", func() {
+		var rc *RuntimeConfig
+
+		BeforeEach(func() {
+			rc = loadFixture()
+		})
+
+		It("replaces only the rules that are provided", func() {
+			p := &PlacementChanger{Name: "security", Include: &Placement{Deployments: []string{"cf"}}}
+			Ω(p.Apply(rc)).Should(Succeed())
+
+			addon := rc.GetAddonByName("security")
+			Ω(addon.Include.Deployments).Should(Equal([]string{"cf"}))
+			Ω(addon.Exclude.Deployments).Should(Equal([]string{"concourse"}))
+		})
+
+		It("clears rules", func() {
+			t, err := SetAddonPlacementTransformation([]string{"-name", "security", "-clear"})
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(t.Apply(rc)).Should(Succeed())
+
+			addon := rc.GetAddonByName("security")
+			Ω(addon.Include).Should(BeNil())
+			Ω(addon.Exclude).Should(BeNil())
+		})
+
+		It("returns an error when given an invalid addon", func() {
+			p := &PlacementChanger{Name: "foobar", Include: &Placement{Deployments: []string{"cf"}}}
+			Ω(p.Apply(rc)).ShouldNot(Succeed())
+		})
+	})
+})
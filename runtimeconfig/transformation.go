@@ -0,0 +1,10 @@
+package runtimeconfig
+
+// Transformation is an action applied to a runtime config.
+type Transformation interface {
+	Apply(*RuntimeConfig) error
+}
+
+// TransformationBuilder is a function that builds a transformation from
+// a CLI context.
+type TransformationBuilder func(args []string) (Transformation, error)
@@ -0,0 +1,278 @@
+// Package script runs Starlark scripts that edit manifests and cloud
+// configs.
+//
+// Scripts run in a sandbox: they can't load other files and have no
+// access to the filesystem or the network, and they are stopped after a
+// number of execution steps.  The data they edit is exposed as plain
+// Starlark dicts and lists, which are converted back to YAML once the
+// script has finished.
+package script
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"go.starlark.net/resolve"
+	"go.starlark.net/starlark"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// DefaultMaxSteps is the number of execution steps a script may take
+// when Options.MaxSteps isn't set.
+const DefaultMaxSteps = 1000000
+
+func init() {
+	// allow if, for and while statements at the top level of a script
+	// and recursion; runaway scripts are stopped by the step limit
+	resolve.AllowGlobalReassign = true
+	resolve.AllowRecursion = true
+}
+
+// Error is an error in a script.  Pos is the position in the script
+// where it occurred, as file:line:col.
+type Error struct {
+	Pos     string
+	Message string
+	Err     error // the error returned by a helper, if any
+}
+
+func (e *Error) Error() string {
+	if e.Pos == "" {
+		return e.Message
+	}
+	return e.Pos + ": " + e.Message
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Program is a compiled script.
+type Program struct {
+	filename string
+	prog     *starlark.Program
+}
+
+// Compile parses a script.  predeclared lists the names of the values
+// that will be passed to Run.  Syntax errors and references to undefined
+// names are reported with their line numbers.
+func Compile(filename string, src []byte, predeclared []string) (*Program, error) {
+	names := map[string]bool{}
+	for _, n := range predeclared {
+		names[n] = true
+	}
+	_, prog, err := starlark.SourceProgram(filename, src, func(name string) bool { return names[name] })
+	if err != nil {
+		return nil, &Error{Message: err.Error()}
+	}
+	return &Program{filename: filename, prog: prog}, nil
+}
+
+// Options control how a script is run.
+type Options struct {
+	// MaxSteps is the number of execution steps after which the script
+	// is stopped, and defaults to DefaultMaxSteps.
+	MaxSteps uint64
+
+	// Print receives the output of the script's print calls, and
+	// defaults to discarding it.
+	Print io.Writer
+}
+
+// Run runs the program with the predeclared values.
+func (p *Program) Run(predeclared starlark.StringDict, opts Options) error {
+	if opts.MaxSteps == 0 {
+		opts.MaxSteps = DefaultMaxSteps
+	}
+	if opts.Print == nil {
+		opts.Print = ioutil.Discard
+	}
+
+	thread := &starlark.Thread{
+		Name:  p.filename,
+		Print: func(_ *starlark.Thread, msg string) { fmt.Fprintln(opts.Print, msg) },
+	}
+	thread.SetMaxExecutionSteps(opts.MaxSteps)
+
+	_, err := p.prog.Init(thread, predeclared)
+	if evalErr, ok := err.(*starlark.EvalError); ok {
+		return &Error{Pos: p.position(evalErr), Message: evalErr.Msg, Err: evalErr.Unwrap()}
+	}
+	if err != nil {
+		return &Error{Message: err.Error()}
+	}
+	return nil
+}
+
+// position returns the innermost position in the script of an error,
+// ignoring the frames of built-in functions.
+func (p *Program) position(err *starlark.EvalError) string {
+	for i := len(err.CallStack) - 1; i >= 0; i-- {
+		if pos := err.CallStack[i].Pos; pos.Filename() == p.filename {
+			return pos.String()
+		}
+	}
+	return ""
+}
+
+// Data returns v, which must marshal to a YAML mapping, as a Starlark
+// dict.
+func Data(v interface{}) (*starlark.Dict, error) {
+	b, err := yaml.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var data map[interface{}]interface{}
+	if err = yaml.Unmarshal(b, &data); err != nil {
+		return nil, err
+	}
+	sv, err := ToValue(data)
+	if err != nil {
+		return nil, err
+	}
+	return sv.(*starlark.Dict), nil
+}
+
+// Marshal returns a Starlark value as YAML.
+func Marshal(v starlark.Value) ([]byte, error) {
+	gv, err := FromValue(v)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(gv)
+}
+
+// ToValue converts a value decoded by the yaml package to Starlark.
+func ToValue(v interface{}) (starlark.Value, error) {
+	switch v := v.(type) {
+	case nil:
+		return starlark.None, nil
+	case bool:
+		return starlark.Bool(v), nil
+	case int:
+		return starlark.MakeInt(v), nil
+	case int64:
+		return starlark.MakeInt64(v), nil
+	case uint64:
+		return starlark.MakeUint64(v), nil
+	case float64:
+		return starlark.Float(v), nil
+	case string:
+		return starlark.String(v), nil
+	case []interface{}:
+		elems := make([]starlark.Value, len(v))
+		for i := range v {
+			e, err := ToValue(v[i])
+			if err != nil {
+				return nil, err
+			}
+			elems[i] = e
+		}
+		return starlark.NewList(elems), nil
+	case map[interface{}]interface{}:
+		d := starlark.NewDict(len(v))
+		for k, e := range v {
+			sv, err := ToValue(e)
+			if err != nil {
+				return nil, err
+			}
+			if err = d.SetKey(starlark.String(fmt.Sprint(k)), sv); err != nil {
+				return nil, err
+			}
+		}
+		return d, nil
+	}
+	return nil, fmt.Errorf("can't convert %T to a script value", v)
+}
+
+// FromValue converts a Starlark value to a value that can be marshaled
+// as YAML.  Dicts must have string keys.
+func FromValue(v starlark.Value) (interface{}, error) {
+	switch v := v.(type) {
+	case starlark.NoneType:
+		return nil, nil
+	case starlark.Bool:
+		return bool(v), nil
+	case starlark.Int:
+		if i, ok := v.Int64(); ok {
+			return i, nil
+		}
+		return nil, fmt.Errorf("integer %s is too large", v)
+	case starlark.Float:
+		return float64(v), nil
+	case starlark.String:
+		return string(v), nil
+	case *starlark.List:
+		return fromIterable(v, v.Len())
+	case starlark.Tuple:
+		return fromIterable(v, v.Len())
+	case *starlark.Dict:
+		m := make(map[string]interface{}, v.Len())
+		for _, item := range v.Items() {
+			k, ok := item[0].(starlark.String)
+			if !ok {
+				return nil, fmt.Errorf("dict keys must be strings, not %s", item[0].Type())
+			}
+			e, err := FromValue(item[1])
+			if err != nil {
+				return nil, err
+			}
+			m[string(k)] = e
+		}
+		return m, nil
+	}
+	return nil, fmt.Errorf("can't convert a %s to YAML", v.Type())
+}
+
+func fromIterable(v starlark.Iterable, n int) (interface{}, error) {
+	elems := make([]interface{}, 0, n)
+	it := v.Iterate()
+	defer it.Done()
+	var e starlark.Value
+	for it.Next(&e) {
+		ge, err := FromValue(e)
+		if err != nil {
+			return nil, err
+		}
+		elems = append(elems, ge)
+	}
+	return elems, nil
+}
+
+// Find returns the dict in data[list] whose name is name, or nil if
+// there isn't one.
+func Find(data *starlark.Dict, list, name string) *starlark.Dict {
+	v, ok, _ := data.Get(starlark.String(list))
+	if !ok {
+		return nil
+	}
+	l, ok := v.(*starlark.List)
+	if !ok {
+		return nil
+	}
+	for i := 0; i < l.Len(); i++ {
+		d, ok := l.Index(i).(*starlark.Dict)
+		if !ok {
+			continue
+		}
+		if n, ok, _ := d.Get(starlark.String("name")); ok && n == starlark.String(name) {
+			return d
+		}
+	}
+	return nil
+}
+
+// Lookup returns a built-in function, called name, that takes a name
+// and returns the dict with that name in data[list].  notFound returns
+// the error for names that aren't found.
+func Lookup(name string, data *starlark.Dict, list string, notFound func(string) error) *starlark.Builtin {
+	return starlark.NewBuiltin(name, func(_ *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var n string
+		if err := starlark.UnpackPositionalArgs(fn.Name(), args, kwargs, 1, &n); err != nil {
+			return nil, err
+		}
+		if d := Find(data, list, n); d != nil {
+			return d, nil
+		}
+		return nil, notFound(n)
+	})
+}
@@ -0,0 +1,119 @@
+package script
+
+import (
+	"bytes"
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.starlark.net/starlark"
+	yaml "gopkg.in/yaml.v2"
+)
+
+var _ = Describe("scripts", func() {
+	var data *starlark.Dict
+
+	BeforeEach(func() {
+		var err error
+		data, err = Data(map[string]interface{}{
+			"name": "cf",
+			"instance_groups": []map[string]interface{}{
+				{"name": "router", "instances": 2, "azs": []string{"z1"}},
+				{"name": "nats", "instances": 1, "lifecycle": nil},
+			},
+			"ratio":  0.5,
+			"public": true,
+		})
+		Ω(err).ShouldNot(HaveOccurred())
+	})
+
+	run := func(src string, opts Options) error {
+		p, err := Compile("test.star", []byte(src), []string{"data", "group"})
+		if err != nil {
+			return err
+		}
+		return p.Run(starlark.StringDict{
+			"data": data,
+			"group": Lookup("group", data, "instance_groups", func(name string) error {
+				return errors.New("no group " + name)
+			}),
+		}, opts)
+	}
+
+	It("exposes data that the script can edit", func() {
+		Ω(run(`
+for ig in data["instance_groups"]:
+    ig["instances"] += 1
+group("nats")["lifecycle"] = "service"
+data["tags"] = {"owner": "ops"}
+`, Options{})).Should(Succeed())
+
+		b, err := Marshal(data)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(string(b)).Should(MatchYAML(`
+name: cf
+instance_groups:
+- {name: router, instances: 3, azs: [z1]}
+- {name: nats, instances: 2, lifecycle: service}
+ratio: 0.5
+public: true
+tags: {owner: ops}
+`))
+	})
+
+	It("reports syntax errors and undefined names with their position", func() {
+		Ω(run("x = 1\ny = 1 +* 2\n", Options{})).Should(MatchError(MatchRegexp(`^test.star:2:\d+: `)))
+		Ω(run("data = 1\nmanifest[\"name\"] = 2\n", Options{})).Should(MatchError("test.star:2:1: undefined: manifest"))
+	})
+
+	It("reports runtime errors with their position", func() {
+		err := run("x = 1\ndata[\"nope\"]\n", Options{})
+		Ω(err).Should(MatchError(`test.star:2:5: key "nope" not in dict`))
+	})
+
+	It("reports errors from helpers at the line that called them", func() {
+		err := run("def f():\n    return group(\"routr\")\nf()\n", Options{})
+		Ω(err).Should(MatchError("test.star:2:17: no group routr"))
+		Ω(errors.Unwrap(err)).Should(MatchError("no group routr"))
+	})
+
+	It("stops scripts that take too many steps", func() {
+		err := run("x = 0\nwhile True:\n    x += 1\n", Options{MaxSteps: 1000})
+		Ω(err).Should(MatchError(ContainSubstring("too many steps")))
+	})
+
+	It("can't load other files", func() {
+		Ω(run(`load("other.star", "x")`, Options{})).Should(MatchError(ContainSubstring("load not implemented")))
+	})
+
+	It("writes the output of print", func() {
+		var out bytes.Buffer
+		Ω(run(`print(data["name"])`, Options{Print: &out})).Should(Succeed())
+		Ω(out.String()).Should(Equal("cf\n"))
+	})
+
+	It("refuses values that can't be written as YAML", func() {
+		Ω(run(`data["f"] = len`, Options{})).Should(Succeed())
+		_, err := Marshal(data)
+		Ω(err).Should(MatchError("can't convert a builtin_function_or_method to YAML"))
+
+		_, err = FromValue(starlark.NewDict(1))
+		Ω(err).ShouldNot(HaveOccurred())
+		d := starlark.NewDict(1)
+		Ω(d.SetKey(starlark.MakeInt(1), starlark.None)).Should(Succeed())
+		_, err = FromValue(d)
+		Ω(err).Should(MatchError("dict keys must be strings, not int"))
+	})
+
+	It("converts YAML values", func() {
+		var v interface{}
+		Ω(yaml.Unmarshal([]byte("[1, 2.5, x, true, null, {a: [b]}]"), &v)).Should(Succeed())
+		sv, err := ToValue(v)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(sv.String()).Should(Equal(`[1, 2.5, "x", True, None, {"a": ["b"]}]`))
+
+		back, err := FromValue(sv)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(back).Should(Equal([]interface{}{int64(1), 2.5, "x", true, nil, map[string]interface{}{"a": []interface{}{"b"}}}))
+	})
+})
@@ -0,0 +1,373 @@
+// Package server exposes the omg-transform transformations over HTTP.
+//
+//	GET  /transforms                 lists the registered transformations
+//	POST /manifest/{transform}       transforms the deployment manifest in the body
+//	POST /cloudconfig/{transform}    transforms the cloud config in the body
+//
+// The transformation's arguments are passed as repeated arg query
+// parameters, for example
+//
+//	POST /manifest/scale?arg=-instance-group&arg=router&arg=-instances&arg=3
+//
+// The transformed YAML is returned with a 200 status.  Errors are
+// returned as a JSON cli.ErrorReport with a status that depends on the
+// kind of error.
+//
+// Only the built-in transformations that work on the request alone are
+// served.  Flags that read or write the server's files or environment,
+// such as -cloud-config and -file, are rejected, and so are the
+// transformations that need them, such as split and merge, and plugins.
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/enaml-ops/enaml"
+	"github.com/enaml-ops/omg-transform/cli"
+	"github.com/enaml-ops/omg-transform/cloudconfig"
+	"github.com/enaml-ops/omg-transform/errs"
+	"github.com/enaml-ops/omg-transform/manifest"
+	"github.com/enaml-ops/omg-transform/script"
+	"github.com/enaml-ops/omg-transform/suggest"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// DefaultMaxBodySize is the largest request body accepted when
+// Options.MaxBodySize isn't set.
+const DefaultMaxBodySize = 10 << 20
+
+// The transformations the server applies, with the flags of each that
+// it rejects because they read the server's files or environment or
+// write to its files or standard error.
+var (
+	manifestTransforms = map[string][]string{
+		"change-network":        {"cloud-config"},
+		"clone":                 nil,
+		"remove-instance-group": nil,
+		"change-az":             {"cloud-config", "plan"},
+		"make-ha":               {"cloud-config", "plan"},
+		"scale":                 nil,
+		"add-tags":              {"file", "env-prefix"},
+		"remove-tags":           nil,
+		"add-vm-extension":      nil,
+		"remove-vm-extension":   nil,
+		"change-lifecycle":      nil,
+		"add-errand":            {"cloud-config"},
+		"script":                {"file"},
+	}
+	cloudConfigTransforms = map[string][]string{
+		"add-subnet":    nil,
+		"resize-static": nil,
+		"script":        {"file"},
+	}
+)
+
+// Options configure a Server.
+type Options struct {
+	// MaxBodySize is the largest request body accepted, in bytes.
+	// Larger requests fail with 413 Request Entity Too Large.
+	MaxBodySize int64
+
+	// MaxConcurrent is the number of transformations applied at once,
+	// and defaults to the number of CPUs.  Other requests wait for one
+	// of them to finish.
+	MaxConcurrent int
+
+	// MaxScriptSteps is the largest -max-steps accepted by the script
+	// transformations, and defaults to script.DefaultMaxSteps.
+	MaxScriptSteps uint64
+}
+
+// Server is an http.Handler that applies transformations.
+type Server struct {
+	maxBodySize    int64
+	maxScriptSteps uint64
+	sem            chan struct{}
+	mux            *http.ServeMux
+}
+
+// New returns a server with the specified options.
+func New(opts Options) *Server {
+	if opts.MaxBodySize <= 0 {
+		opts.MaxBodySize = DefaultMaxBodySize
+	}
+	if opts.MaxConcurrent <= 0 {
+		opts.MaxConcurrent = runtime.NumCPU()
+	}
+	if opts.MaxScriptSteps == 0 {
+		opts.MaxScriptSteps = script.DefaultMaxSteps
+	}
+	s := &Server{
+		maxBodySize:    opts.MaxBodySize,
+		maxScriptSteps: opts.MaxScriptSteps,
+		sem:            make(chan struct{}, opts.MaxConcurrent),
+		mux:            http.NewServeMux(),
+	}
+	s.mux.HandleFunc("/transforms", s.handleTransforms)
+	s.mux.HandleFunc("/manifest/", s.handleTransform("/manifest/", s.applyManifest))
+	s.mux.HandleFunc("/cloudconfig/", s.handleTransform("/cloudconfig/", s.applyCloudConfig))
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Listing is the response to GET /transforms, which lists the
+// transformations the server applies.
+type Listing struct {
+	Manifest    []TransformationDoc `json:"manifest"`
+	CloudConfig []TransformationDoc `json:"cloudconfig"`
+}
+
+// TransformationDoc describes a registered transformation.
+type TransformationDoc struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Usage       string    `json:"usage"`
+	Examples    []string  `json:"examples,omitempty"`
+	Flags       []FlagDoc `json:"flags,omitempty"`
+}
+
+// FlagDoc describes a flag of a transformation.
+type FlagDoc struct {
+	Name    string `json:"name"`
+	Usage   string `json:"usage"`
+	Default string `json:"default,omitempty"`
+}
+
+func (s *Server) handleTransforms(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	var l Listing
+	for _, t := range manifest.Transformations() {
+		if rejected, ok := manifestTransforms[t.Name]; ok {
+			l.Manifest = append(l.Manifest, transformationDoc(t.Name, t.Description, t.Usage, t.Examples, t.Flags, rejected))
+		}
+	}
+	for _, t := range cloudconfig.Transformations() {
+		if rejected, ok := cloudConfigTransforms[t.Name]; ok {
+			l.CloudConfig = append(l.CloudConfig, transformationDoc(t.Name, t.Description, t.Usage, t.Examples, t.Flags, rejected))
+		}
+	}
+	writeJSON(w, http.StatusOK, l)
+}
+
+// transformationDoc describes a transformation without the flags the
+// server rejects.
+func transformationDoc(name, description, usage string, examples []string, flags func() *flag.FlagSet, rejected []string) TransformationDoc {
+	doc := TransformationDoc{Name: name, Description: description, Usage: usage, Examples: examples}
+	if flags != nil {
+		flags().VisitAll(func(f *flag.Flag) {
+			if !contains(rejected, f.Name) {
+				doc.Flags = append(doc.Flags, FlagDoc{Name: f.Name, Usage: f.Usage, Default: f.DefValue})
+			}
+		})
+	}
+	return doc
+}
+
+// applyFunc applies the named transformation to a manifest or cloud
+// config and returns the result.
+type applyFunc func(name string, args []string, body []byte) ([]byte, error)
+
+func (s *Server) handleTransform(prefix string, apply applyFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, prefix)
+		if name == "" || strings.Contains(name, "/") {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method != http.MethodPost {
+			methodNotAllowed(w, http.MethodPost)
+			return
+		}
+
+		body, err := ioutil.ReadAll(io.LimitReader(r.Body, s.maxBodySize+1))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, name, err)
+			return
+		}
+		if int64(len(body)) > s.maxBodySize {
+			writeError(w, http.StatusRequestEntityTooLarge, name, fmt.Errorf("request body is larger than %d bytes", s.maxBodySize))
+			return
+		}
+
+		select {
+		case s.sem <- struct{}{}:
+			defer func() { <-s.sem }()
+		case <-r.Context().Done():
+			writeError(w, http.StatusServiceUnavailable, name, r.Context().Err())
+			return
+		}
+
+		out, err := apply(name, r.URL.Query()["arg"], body)
+		if err != nil {
+			writeError(w, statusCode(err), name, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-yaml")
+		w.Write(out)
+	}
+}
+
+func (s *Server) applyManifest(name string, args []string, body []byte) ([]byte, error) {
+	info, ok := manifest.LookupTransformation(name)
+	if err := checkArgs(name, args, manifestTransforms, ok); err != nil {
+		return nil, err
+	}
+	t, err := info.Builder(args)
+	if err != nil {
+		return nil, cli.ArgumentError(err)
+	}
+	if sc, ok := t.(*manifest.Script); ok {
+		if err = s.checkScriptSteps(sc.MaxSteps); err != nil {
+			return nil, err
+		}
+	}
+	dm := enaml.NewDeploymentManifest(body)
+	if dm == nil {
+		return nil, &cli.UsageError{Message: "invalid input manifest"}
+	}
+	if err = t.Apply(dm); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(dm)
+}
+
+func (s *Server) applyCloudConfig(name string, args []string, body []byte) ([]byte, error) {
+	info, ok := cloudconfig.LookupTransformation(name)
+	if err := checkArgs(name, args, cloudConfigTransforms, ok); err != nil {
+		return nil, err
+	}
+	t, err := info.Builder(args)
+	if err != nil {
+		return nil, cli.ArgumentError(err)
+	}
+	if sc, ok := t.(*cloudconfig.Script); ok {
+		if err = s.checkScriptSteps(sc.MaxSteps); err != nil {
+			return nil, err
+		}
+	}
+	cc := enaml.NewCloudConfigManifest(body)
+	if cc == nil {
+		return nil, &cli.UsageError{Message: "invalid input cloud config"}
+	}
+	if err = t.Apply(cc); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(cc)
+}
+
+// checkArgs returns an error if the named transformation isn't
+// registered or served, or if args use a flag it rejects.
+func checkArgs(name string, args []string, served map[string][]string, registered bool) error {
+	rejected, ok := served[name]
+	if !registered || !ok {
+		var names []string
+		for n := range served {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return unknownTransform(name, names)
+	}
+	for _, arg := range args {
+		if f, ok := flagName(arg); ok && contains(rejected, f) {
+			return errs.InvalidFlag(f, "flag -%s isn't allowed by the server", f)
+		}
+	}
+	return nil
+}
+
+// checkScriptSteps returns an error if a script may take more
+// execution steps than the server allows.
+func (s *Server) checkScriptSteps(steps uint64) error {
+	if steps == 0 {
+		steps = script.DefaultMaxSteps
+	}
+	if steps > s.maxScriptSteps {
+		return errs.InvalidFlag("max-steps", "-max-steps can't be more than %d", s.maxScriptSteps)
+	}
+	return nil
+}
+
+// flagName returns the name of the flag in arg, which may be written
+// with one or two dashes and followed by =value.
+func flagName(arg string) (string, bool) {
+	if !strings.HasPrefix(arg, "-") || arg == "-" || arg == "--" {
+		return "", false
+	}
+	name := strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-")
+	if i := strings.Index(name, "="); i >= 0 {
+		name = name[:i]
+	}
+	return name, true
+}
+
+func contains(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+// errUnknownTransform is returned for transforms that aren't
+// registered, and is reported with a 404 status.
+type errUnknownTransform struct {
+	cli.UsageError
+}
+
+func unknownTransform(name string, names []string) error {
+	return &errUnknownTransform{cli.UsageError{
+		Message: fmt.Sprintf("unknown transform %q%s", name, suggest.DidYouMean(suggest.Similar(name, names))),
+	}}
+}
+
+// statusCode returns the HTTP status for an error returned by a
+// transformation.
+func statusCode(err error) int {
+	var unknown *errUnknownTransform
+	if errors.As(err, &unknown) {
+		return http.StatusNotFound
+	}
+	switch cli.ErrorKind(err) {
+	case cli.KindArgument:
+		return http.StatusBadRequest
+	case cli.KindNotFound, cli.KindPrecondition:
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusInternalServerError
+}
+
+func methodNotAllowed(w http.ResponseWriter, allow string) {
+	w.Header().Set("Allow", allow)
+	writeError(w, http.StatusMethodNotAllowed, "", fmt.Errorf("method not allowed, use %s", allow))
+}
+
+func writeError(w http.ResponseWriter, status int, transform string, err error) {
+	writeJSON(w, status, cli.NewErrorReport(transform, err))
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(append(b, '\n'))
+}
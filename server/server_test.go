@@ -0,0 +1,291 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/enaml-ops/enaml"
+	"github.com/enaml-ops/omg-transform/cli"
+	"github.com/enaml-ops/omg-transform/cloudconfig"
+	manifestpkg "github.com/enaml-ops/omg-transform/manifest"
+	"github.com/enaml-ops/omg-transform/registry"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// cloudConfigFunc adapts a function to the cloudconfig.Transformation
+// interface.
+type cloudConfigFunc func(*enaml.CloudConfigManifest) error
+
+func (f cloudConfigFunc) Apply(cc *enaml.CloudConfigManifest) error { return f(cc) }
+
+// started receives a value when the block-test transformation starts,
+// and release is closed to let it finish.
+var (
+	release = make(chan struct{})
+	started = make(chan struct{}, 10)
+)
+
+var _ = BeforeSuite(func() {
+	cloudConfigTransforms["rename-az-test"] = nil
+	cloudConfigTransforms["block-test"] = nil
+	cloudconfig.RegisterTransformation(cloudconfig.TransformationInfo{
+		Info: registry.Info{
+			Name:        "rename-az-test",
+			Description: "rename the first AZ",
+		},
+		Builder: func(args []string) (cloudconfig.Transformation, error) {
+			if len(args) != 1 {
+				return nil, errors.New("rename-az-test takes a name")
+			}
+			return cloudConfigFunc(func(cc *enaml.CloudConfigManifest) error {
+				cc.AZs[0].Name = args[0]
+				return nil
+			}), nil
+		},
+	})
+	cloudconfig.RegisterTransformation(cloudconfig.TransformationInfo{
+		Info: registry.Info{
+			Name:        "block-test",
+			Description: "wait until the test lets it finish",
+		},
+		Builder: func(args []string) (cloudconfig.Transformation, error) {
+			return cloudConfigFunc(func(cc *enaml.CloudConfigManifest) error {
+				started <- struct{}{}
+				<-release
+				return nil
+			}), nil
+		},
+	})
+})
+
+var _ = Describe("Server", func() {
+	var (
+		srv         *httptest.Server
+		manifest    []byte
+		cloudConfig []byte
+	)
+
+	BeforeEach(func() {
+		var err error
+		manifest, err = ioutil.ReadFile("../manifest/fixtures/rotate-certs.yml")
+		Ω(err).ShouldNot(HaveOccurred())
+		cloudConfig, err = ioutil.ReadFile("../cloudconfig/fixtures/cloud-config-aws.yml")
+		Ω(err).ShouldNot(HaveOccurred())
+		srv = httptest.NewServer(New(Options{MaxBodySize: 64 << 10}))
+	})
+
+	AfterEach(func() {
+		srv.Close()
+	})
+
+	post := func(path string, body []byte, args ...string) (*http.Response, []byte) {
+		q := url.Values{"arg": args}
+		resp, err := http.Post(srv.URL+path+"?"+q.Encode(), "application/x-yaml", strings.NewReader(string(body)))
+		Ω(err).ShouldNot(HaveOccurred())
+		defer resp.Body.Close()
+		b, err := ioutil.ReadAll(resp.Body)
+		Ω(err).ShouldNot(HaveOccurred())
+		return resp, b
+	}
+
+	errorReport := func(b []byte) cli.ErrorReport {
+		var report cli.ErrorReport
+		Ω(json.Unmarshal(b, &report)).Should(Succeed())
+		return report
+	}
+
+	It("lists the registered transformations", func() {
+		resp, err := http.Get(srv.URL + "/transforms")
+		Ω(err).ShouldNot(HaveOccurred())
+		defer resp.Body.Close()
+		Ω(resp.StatusCode).Should(Equal(http.StatusOK))
+		Ω(resp.Header.Get("Content-Type")).Should(Equal("application/json"))
+
+		var l Listing
+		Ω(json.NewDecoder(resp.Body).Decode(&l)).Should(Succeed())
+		var scale *TransformationDoc
+		for i := range l.Manifest {
+			if l.Manifest[i].Name == "scale" {
+				scale = &l.Manifest[i]
+			}
+		}
+		Ω(scale).ShouldNot(BeNil())
+		Ω(scale.Flags).Should(ContainElement(FlagDoc{Name: "instances", Usage: "number of instances", Default: "-1"}))
+		Ω(l.CloudConfig).Should(ContainElement(TransformationDoc{Name: "rename-az-test", Description: "rename the first AZ"}))
+	})
+
+	It("transforms manifests", func() {
+		resp, b := post("/manifest/scale", manifest, "-instance-group", "rotate-certs", "-instances", "3")
+		Ω(resp.StatusCode).Should(Equal(http.StatusOK), string(b))
+		Ω(resp.Header.Get("Content-Type")).Should(Equal("application/x-yaml"))
+
+		dm := enaml.NewDeploymentManifest(b)
+		Ω(dm.GetInstanceGroupByName("rotate-certs").Instances).Should(Equal(3))
+	})
+
+	It("transforms cloud configs", func() {
+		resp, b := post("/cloudconfig/rename-az-test", cloudConfig, "us-west-1z")
+		Ω(resp.StatusCode).Should(Equal(http.StatusOK), string(b))
+		Ω(enaml.NewCloudConfigManifest(b).AZs[0].Name).Should(Equal("us-west-1z"))
+	})
+
+	It("handles concurrent requests", func() {
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				resp, b := post("/manifest/add-tags", manifest, "env=prod")
+				Ω(resp.StatusCode).Should(Equal(http.StatusOK), string(b))
+				Ω(enaml.NewDeploymentManifest(b).Tag("env")).Should(Equal("prod"))
+			}()
+		}
+		wg.Wait()
+	})
+
+	It("returns 404 with suggestions for unknown transforms", func() {
+		resp, b := post("/manifest/scael", manifest)
+		Ω(resp.StatusCode).Should(Equal(http.StatusNotFound))
+		report := errorReport(b)
+		Ω(report.Error).Should(ContainSubstring(`unknown transform "scael"`))
+		Ω(report.Error).Should(ContainSubstring("did you mean scale?"))
+		Ω(report.Transform).Should(Equal("scael"))
+	})
+
+	It("returns 400 for invalid arguments", func() {
+		resp, b := post("/manifest/scale", manifest, "-bogus")
+		Ω(resp.StatusCode).Should(Equal(http.StatusBadRequest))
+		Ω(errorReport(b).Kind).Should(Equal(cli.KindArgument))
+
+		resp, b = post("/cloudconfig/rename-az-test", cloudConfig)
+		Ω(resp.StatusCode).Should(Equal(http.StatusBadRequest))
+		Ω(errorReport(b).Error).Should(Equal("rename-az-test takes a name"))
+	})
+
+	It("doesn't serve transformations that use the server's files", func() {
+		for _, name := range []string{"split", "merge"} {
+			resp, b := post("/manifest/"+name, manifest)
+			Ω(resp.StatusCode).Should(Equal(http.StatusNotFound), name)
+			Ω(errorReport(b).Error).Should(ContainSubstring(`unknown transform "` + name + `"`))
+		}
+
+		resp, err := http.Get(srv.URL + "/transforms")
+		Ω(err).ShouldNot(HaveOccurred())
+		defer resp.Body.Close()
+		var l Listing
+		Ω(json.NewDecoder(resp.Body).Decode(&l)).Should(Succeed())
+		for _, t := range l.Manifest {
+			Ω(t.Name).ShouldNot(BeElementOf("split", "merge"))
+			if t.Name == "add-tags" {
+				var flags []string
+				for _, f := range t.Flags {
+					flags = append(flags, f.Name)
+				}
+				Ω(flags).Should(Equal([]string{"instance-group"}))
+			}
+		}
+	})
+
+	It("doesn't serve plugins", func() {
+		manifestpkg.RegisterTransformation(manifestpkg.TransformationInfo{
+			Info: registry.Info{Name: "plugin-test"},
+			Builder: func([]string) (manifestpkg.Transformation, error) {
+				return nil, errors.New("plugin-test was built")
+			},
+		})
+		resp, _ := post("/manifest/plugin-test", manifest)
+		Ω(resp.StatusCode).Should(Equal(http.StatusNotFound))
+	})
+
+	It("rejects flags that read the server's files or environment", func() {
+		for _, args := range [][]string{
+			{"-file", "/etc/passwd"},
+			{"--file=/etc/passwd"},
+			{"env=prod", "-env-prefix", "HOME"},
+			{"-env-prefix=HOME"},
+		} {
+			resp, b := post("/manifest/add-tags", manifest, args...)
+			Ω(resp.StatusCode).Should(Equal(http.StatusBadRequest), strings.Join(args, " "))
+			report := errorReport(b)
+			Ω(report.Kind).Should(Equal(cli.KindArgument))
+			Ω(report.Error).Should(MatchRegexp(`flag -(file|env-prefix) isn't allowed by the server`))
+		}
+
+		resp, b := post("/manifest/change-az", manifest, "-instance-group", "rotate-certs", "-az", "z1", "-cloud-config", "/etc/passwd")
+		Ω(resp.StatusCode).Should(Equal(http.StatusBadRequest))
+		Ω(errorReport(b).Error).Should(Equal("flag -cloud-config isn't allowed by the server"))
+
+		resp, _ = post("/cloudconfig/script", cloudConfig, "-file", "/etc/passwd")
+		Ω(resp.StatusCode).Should(Equal(http.StatusBadRequest))
+	})
+
+	It("limits the steps scripts may take", func() {
+		resp, b := post("/manifest/script", manifest, "-e", "while True: pass", "-max-steps", "18446744073709551615")
+		Ω(resp.StatusCode).Should(Equal(http.StatusBadRequest))
+		Ω(errorReport(b).Error).Should(Equal("-max-steps can't be more than 1000000"))
+
+		resp, b = post("/manifest/script", manifest, "-e", "manifest['name'] = 'scripted'", "-max-steps", "1000")
+		Ω(resp.StatusCode).Should(Equal(http.StatusOK), string(b))
+		Ω(enaml.NewDeploymentManifest(b).Name).Should(Equal("scripted"))
+	})
+
+	It("returns 422 with the error's details when the transformation fails", func() {
+		resp, b := post("/manifest/scale", manifest, "-instance-group", "routr", "-instances", "3")
+		Ω(resp.StatusCode).Should(Equal(http.StatusUnprocessableEntity))
+		report := errorReport(b)
+		Ω(report.Kind).Should(Equal(cli.KindNotFound))
+		Ω(report.ExitCode).Should(Equal(cli.ExitNotFound))
+		Ω(report.Details).ShouldNot(BeNil())
+	})
+
+	It("rejects bodies larger than the limit", func() {
+		resp, b := post("/manifest/add-tags", append(manifest, make([]byte, 64<<10)...), "env=prod")
+		Ω(resp.StatusCode).Should(Equal(http.StatusRequestEntityTooLarge))
+		Ω(errorReport(b).Error).Should(ContainSubstring("larger than 65536 bytes"))
+	})
+
+	It("only accepts the documented methods", func() {
+		resp, err := http.Get(srv.URL + "/manifest/scale")
+		Ω(err).ShouldNot(HaveOccurred())
+		resp.Body.Close()
+		Ω(resp.StatusCode).Should(Equal(http.StatusMethodNotAllowed))
+		Ω(resp.Header.Get("Allow")).Should(Equal("POST"))
+
+		resp, _ = post("/transforms", nil)
+		Ω(resp.StatusCode).Should(Equal(http.StatusMethodNotAllowed))
+
+		resp, _ = post("/manifest/", manifest)
+		Ω(resp.StatusCode).Should(Equal(http.StatusNotFound))
+	})
+
+	It("limits the number of transformations applied at once", func() {
+		s := New(Options{MaxConcurrent: 1})
+
+		done := make(chan *httptest.ResponseRecorder)
+		go func() {
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, httptest.NewRequest("POST", "/cloudconfig/block-test", strings.NewReader(string(cloudConfig))))
+			done <- w
+		}()
+		Eventually(started).Should(Receive())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/cloudconfig/rename-az-test?arg=x", strings.NewReader(string(cloudConfig))).WithContext(ctx)
+		s.ServeHTTP(w, r)
+		Ω(w.Code).Should(Equal(http.StatusServiceUnavailable))
+
+		close(release)
+		Ω((<-done).Code).Should(Equal(http.StatusOK))
+	})
+})
@@ -0,0 +1,123 @@
+// Package suggest finds names similar to one that was mistyped, so
+// that errors can ask "did you mean ...?".
+package suggest
+
+import (
+	"sort"
+	"strings"
+)
+
+// maxSuggestions is the most names Similar returns.
+const maxSuggestions = 3
+
+// Similar returns up to three candidates that look like name, closest
+// first.  Names are compared case insensitively, with hyphens and
+// underscores treated as the same character, so diego-cell matches
+// diego_cell exactly and nothing else is suggested.  Otherwise
+// candidates match if they are within a small edit distance of name,
+// or contain it.
+func Similar(name string, candidates []string) []string {
+	n := normalize(name)
+	if n == "" {
+		return nil
+	}
+	maxDistance := len(n) / 3
+	if maxDistance < 1 {
+		maxDistance = 1
+	}
+
+	type match struct {
+		name     string
+		distance int
+	}
+	var matches []match
+	seen := make(map[string]bool)
+	for _, c := range candidates {
+		if c == name || seen[c] {
+			continue
+		}
+		seen[c] = true
+
+		nc := normalize(c)
+		d := distance(n, nc)
+		if d > maxDistance {
+			if len(n) < 3 || !strings.Contains(nc, n) {
+				continue
+			}
+			// a substring is a weaker match than any within the distance
+			d = maxDistance + 1
+		}
+		matches = append(matches, match{c, d})
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].distance != matches[j].distance {
+			return matches[i].distance < matches[j].distance
+		}
+		return matches[i].name < matches[j].name
+	})
+	var result []string
+	for i := 0; i < len(matches) && i < maxSuggestions; i++ {
+		if matches[i].distance > 0 && len(result) > 0 && matches[0].distance == 0 {
+			// only differs in case or separators, which is surely it
+			break
+		}
+		result = append(result, matches[i].name)
+	}
+	return result
+}
+
+// DidYouMean formats suggestions for the end of an error message,
+// for example ", did you mean diego_cell or diego_brain?".  It returns
+// an empty string if there are no suggestions.
+func DidYouMean(suggestions []string) string {
+	switch len(suggestions) {
+	case 0:
+		return ""
+	case 1:
+		return ", did you mean " + suggestions[0] + "?"
+	}
+	last := len(suggestions) - 1
+	return ", did you mean " + strings.Join(suggestions[:last], ", ") + " or " + suggestions[last] + "?"
+}
+
+func normalize(s string) string {
+	return strings.Replace(strings.ToLower(s), "-", "_", -1)
+}
+
+// distance returns the edit distance between a and b, counting the
+// transposition of two adjacent characters as a single edit.
+func distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(ra)][len(rb)]
+}
+
+func min(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
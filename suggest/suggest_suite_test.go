@@ -0,0 +1,13 @@
+package suggest
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestSuggest(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Suggest Suite")
+}
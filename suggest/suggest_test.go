@@ -0,0 +1,41 @@
+package suggest
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Similar", func() {
+	igs := []string{"diego_cell", "diego_brain", "diego_database", "router", "tcp_router", "nats", "uaa", "uaadb"}
+
+	It("treats hyphens and underscores as the same", func() {
+		Ω(Similar("diego-cell", igs)).Should(Equal([]string{"diego_cell"}))
+		Ω(Similar("Diego_Cell", igs)).Should(Equal([]string{"diego_cell"}))
+		Ω(Similar("us_west_1c", []string{"us-west-1b", "us-west-1c"})).Should(Equal([]string{"us-west-1c"}))
+	})
+
+	It("suggests names within a small edit distance, closest first", func() {
+		Ω(Similar("routr", igs)).Should(Equal([]string{"router"}))
+		Ω(Similar("uab", igs)).Should(Equal([]string{"uaa"}))
+		Ω(Similar("nat", igs)).Should(Equal([]string{"nats"}))
+		Ω(Similar("ruoter", igs)).Should(Equal([]string{"router"}))
+	})
+
+	It("suggests names that contain the name", func() {
+		Ω(Similar("diego", igs)).Should(Equal([]string{"diego_brain", "diego_cell", "diego_database"}))
+		Ω(Similar("rout", igs)).Should(Equal([]string{"router", "tcp_router"}))
+	})
+
+	It("returns nothing when nothing is similar", func() {
+		Ω(Similar("consul_server", igs)).Should(BeEmpty())
+		Ω(Similar("", igs)).Should(BeEmpty())
+	})
+})
+
+var _ = Describe("DidYouMean", func() {
+	It("formats suggestions", func() {
+		Ω(DidYouMean(nil)).Should(Equal(""))
+		Ω(DidYouMean([]string{"a"})).Should(Equal(", did you mean a?"))
+		Ω(DidYouMean([]string{"a", "b", "c"})).Should(Equal(", did you mean a, b or c?"))
+	})
+})